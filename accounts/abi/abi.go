@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -220,6 +221,17 @@ func (abi *ABI) EventByID(topic common.Hash) (*Event, error) {
 	return nil, fmt.Errorf("no event with id: %#x", topic.Hex())
 }
 
+// ErrorByID looks up a custom error by the 4-byte id of its revert data,
+// returns nil if none found.
+func (abi *ABI) ErrorByID(sigdata [4]byte) (*Error, error) {
+	for _, errABI := range abi.Errors {
+		if bytes.Equal(errABI.ID[:4], sigdata[:]) {
+			return &errABI, nil
+		}
+	}
+	return nil, fmt.Errorf("no error with id: %#x", sigdata[:])
+}
+
 // HasFallback returns an indicator whether a fallback function is included.
 func (abi *ABI) HasFallback() bool {
 	return abi.Fallback.Type == Fallback
@@ -252,6 +264,28 @@ func UnpackRevert(data []byte) (string, error) {
 	return unpacked[0].(string), nil
 }
 
+// panicSelector is a special function selector for panic code unpacking.
+var panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+
+// UnpackPanic resolves the abi-encoded panic code. According to the solidity
+// spec https://docs.soliditylang.org/en/latest/control-structures.html#panic-via-assert-and-error-via-require,
+// failing assertions and internal errors revert with the code abi-encoded as if
+// it were a call to a function `Panic(uint256)`.
+func UnpackPanic(data []byte) (*big.Int, error) {
+	if len(data) < 4 {
+		return nil, errors.New("invalid data for unpacking")
+	}
+	if !bytes.Equal(data[:4], panicSelector) {
+		return nil, errors.New("invalid data for unpacking")
+	}
+	typ, _ := NewType("uint256", "", nil)
+	unpacked, err := (Arguments{{Type: typ}}).Unpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	return unpacked[0].(*big.Int), nil
+}
+
 // overloadedName returns the next available name for a given thing.
 // Needed since solidity allows for overloading.
 //
@@ -1055,6 +1055,33 @@ func TestABI_EventById(t *testing.T) {
 	}
 }
 
+func TestABI_ErrorByID(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"inputs":[{"internalType":"uint256","name":"x","type":"uint256"}],"name":"MyError1","type":"error"},
+		{"inputs":[{"internalType":"string","name":"x","type":"string"}],"name":"MyError2","type":"error"},
+		{"inputs":[],"name":"MyError3","type":"error"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, e := range abi.Errors {
+		var id [4]byte
+		copy(id[:], e.ID[:4])
+
+		e2, err := abi.ErrorByID(id)
+		if err != nil {
+			t.Fatalf("Failed to look up ABI error %v: %v", name, err)
+		}
+		if e.Sig != e2.Sig {
+			t.Errorf("Error %v (id %x) not 'findable' by id in ABI", name, id)
+		}
+	}
+	// test unsuccessful lookups
+	if _, err = abi.ErrorByID([4]byte{}); err == nil {
+		t.Error("Expected error: no error with this id")
+	}
+}
+
 // TestDoubleDuplicateMethodNames checks that if transfer0 already exists, there won't be a name
 // conflict and that the second transfer method will be renamed transfer1.
 func TestDoubleDuplicateMethodNames(t *testing.T) {
@@ -1160,3 +1187,35 @@ func TestUnpackRevert(t *testing.T) {
 		})
 	}
 }
+
+func TestUnpackPanic(t *testing.T) {
+	t.Parallel()
+
+	var cases = []struct {
+		input     string
+		expect    *big.Int
+		expectErr error
+	}{
+		{"", nil, errors.New("invalid data for unpacking")},
+		{"4e487b71", nil, errors.New("abi: attempting to unmarshall an empty string while arguments are expected")},
+		{"08c379a00000000000000000000000000000000000000000000000000000000000000011", nil, errors.New("invalid data for unpacking")},
+		{"4e487b710000000000000000000000000000000000000000000000000000000000000011", big.NewInt(0x11), nil},
+	}
+	for index, c := range cases {
+		t.Run(fmt.Sprintf("case %d", index), func(t *testing.T) {
+			got, err := UnpackPanic(common.Hex2Bytes(c.input))
+			if c.expectErr != nil {
+				if err == nil {
+					t.Fatalf("Expected non-nil error")
+				}
+				if err.Error() != c.expectErr.Error() {
+					t.Fatalf("Expected error mismatch, want %v, got %v", c.expectErr, err)
+				}
+				return
+			}
+			if c.expect.Cmp(got) != 0 {
+				t.Fatalf("Output mismatch, want %v, got %v", c.expect, got)
+			}
+		})
+	}
+}
@@ -110,6 +110,8 @@ type BoundContract struct {
 	caller     ContractCaller     // Read interface to interact with the blockchain
 	transactor ContractTransactor // Write interface to interact with the blockchain
 	filterer   ContractFilterer   // Event filtering to interact with the blockchain
+
+	unpackError ErrorUnpacker // Optional decoder of the contract's custom errors
 }
 
 // NewBoundContract creates a low level contract interface through which calls
@@ -124,6 +126,12 @@ func NewBoundContract(address common.Address, abi abi.ABI, caller ContractCaller
 	}
 }
 
+// SetErrorUnpacker sets the decoder converting the revert data of failed calls
+// and gas estimations into the typed custom errors of the contract.
+func (c *BoundContract) SetErrorUnpacker(unpack ErrorUnpacker) {
+	c.unpackError = unpack
+}
+
 // DeployContract deploys a contract onto the Ethereum blockchain and binds the
 // deployment address with a Go wrapper.
 func DeployContract(opts *TransactOpts, abi abi.ABI, bytecode []byte, backend ContractBackend, params ...interface{}) (common.Address, *types.Transaction, *BoundContract, error) {
@@ -172,7 +180,7 @@ func (c *BoundContract) Call(opts *CallOpts, results *[]interface{}, method stri
 		}
 		output, err = pb.PendingCallContract(ctx, msg)
 		if err != nil {
-			return err
+			return UnpackError(err, c.unpackError)
 		}
 		if len(output) == 0 {
 			// Make sure we have a contract to operate on, and bail out otherwise.
@@ -185,7 +193,7 @@ func (c *BoundContract) Call(opts *CallOpts, results *[]interface{}, method stri
 	} else {
 		output, err = c.caller.CallContract(ctx, msg, opts.BlockNumber)
 		if err != nil {
-			return err
+			return UnpackError(err, c.unpackError)
 		}
 		if len(output) == 0 {
 			// Make sure we have a contract to operate on, and bail out otherwise.
@@ -347,7 +355,11 @@ func (c *BoundContract) estimateGasLimit(opts *TransactOpts, contract *common.Ad
 		Value:     value,
 		Data:      input,
 	}
-	return c.transactor.EstimateGas(ensureContext(opts.Context), msg)
+	gas, err := c.transactor.EstimateGas(ensureContext(opts.Context), msg)
+	if err != nil {
+		return 0, UnpackError(err, c.unpackError)
+	}
+	return gas, nil
 }
 
 func (c *BoundContract) getNonce(opts *TransactOpts) (uint64, error) {
@@ -482,6 +494,90 @@ func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]inter
 	return logs, sub, nil
 }
 
+// FollowLogs retrieves the contract logs from the start block of the options up
+// to the chain head, then keeps following the logs of new blocks. Without a start
+// block, it's equivalent to WatchLogs.
+//
+// The new logs arriving while the past ones are delivered are queued in memory
+// until the consumer catches up, instead of overflowing the live subscription.
+func (c *BoundContract) FollowLogs(opts *WatchOpts, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) {
+	// Don't crash on a lazy user
+	if opts == nil {
+		opts = new(WatchOpts)
+	}
+	if opts.Start == nil {
+		return c.WatchLogs(opts, name, query...)
+	}
+	// Append the event selector to the query parameters and construct the topic set
+	query = append([][]interface{}{{c.abi.Events[name].ID}}, query...)
+
+	topics, err := abi.MakeTopics(query...)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := ethereum.FilterQuery{
+		Addresses: []common.Address{c.address},
+		Topics:    topics,
+	}
+	// Subscribe to the new logs before retrieving the past ones, so none are
+	// missed in between. The overlap is deduplicated when forwarding.
+	live := make(chan types.Log, 128)
+	liveSub, err := c.filterer.SubscribeFilterLogs(ensureContext(opts.Context), config, live)
+	if err != nil {
+		return nil, nil, err
+	}
+	config.FromBlock = new(big.Int).SetUint64(*opts.Start)
+	past, err := c.filterer.FilterLogs(ensureContext(opts.Context), config)
+	if err != nil {
+		liveSub.Unsubscribe()
+		return nil, nil, err
+	}
+	logs := make(chan types.Log, 128)
+	return logs, event.NewSubscription(func(quit <-chan struct{}) error {
+		defer liveSub.Unsubscribe()
+
+		var last *types.Log
+		if len(past) > 0 {
+			last = &past[len(past)-1]
+		}
+		// Keep draining the live logs while the queued ones are being delivered,
+		// so a long back-fill can't stall the subscription
+		queue := append([]types.Log{}, past...)
+		for {
+			var (
+				out  chan types.Log
+				next types.Log
+			)
+			if len(queue) > 0 {
+				out, next = logs, queue[0]
+			}
+			select {
+			case out <- next:
+				queue = queue[1:]
+			case log := <-live:
+				// Skip the logs already delivered from the past ones, but forward
+				// any reorged out, as they may have been delivered too
+				if !log.Removed && last != nil && !logAfter(log, *last) {
+					continue
+				}
+				queue = append(queue, log)
+			case err := <-liveSub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// logAfter reports whether a log is positioned after another one in the chain.
+func logAfter(log, other types.Log) bool {
+	if log.BlockNumber != other.BlockNumber {
+		return log.BlockNumber > other.BlockNumber
+	}
+	return log.Index > other.Index
+}
+
 // UnpackLog unpacks a retrieved log into the provided output structure.
 func (c *BoundContract) UnpackLog(out interface{}, event string, log types.Log) error {
 	if log.Topics[0] != c.abi.Events[event].ID {
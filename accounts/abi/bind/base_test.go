@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -31,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/stretchr/testify/assert"
 )
@@ -166,6 +168,71 @@ func TestPassingBlockNumber(t *testing.T) {
 
 const hexData = "0x000000000000000000000000376c47978271565f56deb45495afa69e59c16ab200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000060000000000000000000000000000000000000000000000000000000000000000158"
 
+type mockFilterer struct {
+	past []types.Log
+	live chan<- types.Log
+}
+
+func (mf *mockFilterer) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return mf.past, nil
+}
+
+func (mf *mockFilterer) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	mf.live = ch
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	}), nil
+}
+
+// Tests that following the logs keeps draining the live ones while the consumer
+// is still reading the back-filled logs, delivering all of them in order.
+func TestFollowLogsDrainsLiveLogs(t *testing.T) {
+	parsedAbi, _ := abi.JSON(strings.NewReader(`[{"anonymous":false,"inputs":[],"name":"ping","type":"event"}]`))
+	topic := parsedAbi.Events["ping"].ID
+
+	filterer := new(mockFilterer)
+	for i := 0; i < 200; i++ {
+		filterer.past = append(filterer.past, types.Log{Topics: []common.Hash{topic}, BlockNumber: 1, Index: uint(i)})
+	}
+	bc := bind.NewBoundContract(common.Address{}, parsedAbi, nil, nil, filterer)
+
+	start := uint64(0)
+	logs, sub, err := bc.FollowLogs(&bind.WatchOpts{Start: &start}, "ping")
+	if err != nil {
+		t.Fatalf("failed to follow logs: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Push more live logs than the subscription buffers without reading any
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 300; i++ {
+			filterer.live <- types.Log{Topics: []common.Hash{topic}, BlockNumber: 2, Index: uint(i)}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("live logs not drained during the back-fill")
+	}
+	for i := 0; i < 500; i++ {
+		select {
+		case log := <-logs:
+			number, index := uint64(1), uint(i)
+			if i >= 200 {
+				number, index = 2, uint(i-200)
+			}
+			if log.BlockNumber != number || log.Index != index {
+				t.Fatalf("log %d mismatch: have %d/%d, want %d/%d", i, log.BlockNumber, log.Index, number, index)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("log %d not delivered", i)
+		}
+	}
+}
+
 func TestUnpackIndexedStringTyLogIntoMap(t *testing.T) {
 	hash := crypto.Keccak256Hash([]byte("testName"))
 	topics := []common.Hash{
@@ -41,6 +41,7 @@ const (
 	LangGo Lang = iota
 	LangJava
 	LangObjC
+	LangTypeScript
 )
 
 // Bind generates a Go wrapper around a contract ABI. This wrapper isn't meant
@@ -77,6 +78,7 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 			calls     = make(map[string]*tmplMethod)
 			transacts = make(map[string]*tmplMethod)
 			events    = make(map[string]*tmplEvent)
+			errs      = make(map[string]*tmplError)
 			fallback  *tmplMethod
 			receive   *tmplMethod
 
@@ -87,6 +89,7 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 			callIdentifiers     = make(map[string]bool)
 			transactIdentifiers = make(map[string]bool)
 			eventIdentifiers    = make(map[string]bool)
+			errorIdentifiers    = make(map[string]bool)
 		)
 
 		for _, input := range evmABI.Constructor.Inputs {
@@ -165,6 +168,26 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 			// Append the event to the accumulator list
 			events[original.Name] = &tmplEvent{Original: original, Normalized: normalized}
 		}
+		for _, original := range evmABI.Errors {
+			// Normalize the error for capital cases, inputs are already named
+			normalized := original
+
+			// Ensure there is no duplicated identifier
+			normalizedName := methodNormalizer[lang](alias(aliases, original.Name))
+			if errorIdentifiers[normalizedName] {
+				return "", fmt.Errorf("duplicated identifier \"%s\"(normalized \"%s\"), use --alias for renaming", original.Name, normalizedName)
+			}
+			errorIdentifiers[normalizedName] = true
+			normalized.Name = normalizedName
+
+			for _, input := range normalized.Inputs {
+				if hasStruct(input.Type) {
+					bindStructType[lang](input.Type, structs)
+				}
+			}
+			// Append the error to the accumulator list
+			errs[original.Name] = &tmplError{Original: original, Normalized: normalized}
+		}
 		// Add two special fallback functions if they exist
 		if evmABI.HasFallback() {
 			fallback = &tmplMethod{Original: evmABI.Fallback}
@@ -187,6 +210,7 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 			Fallback:    fallback,
 			Receive:     receive,
 			Events:      events,
+			Errors:      errs,
 			Libraries:   make(map[string]string),
 		}
 		// Function 4-byte signatures are stored in the same sequence
@@ -229,6 +253,8 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 		"namedtype":     namedType[lang],
 		"capitalise":    capitalise,
 		"decapitalise":  decapitalise,
+		"hashedtopic":   hashedTopic,
+		"list":          func(items ...interface{}) []interface{} { return items },
 	}
 	tmpl := template.Must(template.New("").Funcs(funcs).Parse(tmplSource[lang]))
 	if err := tmpl.Execute(buffer, data); err != nil {
@@ -242,15 +268,44 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 		}
 		return string(code), nil
 	}
+	// For TypeScript bindings drop the blank lines left over by the template
+	if lang == LangTypeScript {
+		return tidyTypeScript(buffer.String()), nil
+	}
 	// For all others just return as is for now
 	return buffer.String(), nil
 }
 
+// tidyTypeScript strips the trailing whitespace of the lines of a TypeScript
+// binding, collapsing the consecutive blank lines and dropping the ones opening
+// or closing a block.
+func tidyTypeScript(code string) string {
+	var lines []string
+	for _, line := range strings.Split(code, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			// Skip blank lines at the start, after another, or opening a block
+			if len(lines) == 0 || lines[len(lines)-1] == "" || strings.HasSuffix(lines[len(lines)-1], "{") {
+				continue
+			}
+		}
+		if strings.TrimSpace(line) == "}" && len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1] // drop blank lines closing a block
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
 // bindType is a set of type binders that convert Solidity types to some supported
 // programming language types.
 var bindType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
-	LangGo:   bindTypeGo,
-	LangJava: bindTypeJava,
+	LangGo:         bindTypeGo,
+	LangJava:       bindTypeJava,
+	LangTypeScript: bindTypeTypeScript,
 }
 
 // bindBasicTypeGo converts basic solidity types(except array, slice and tuple) to Go ones.
@@ -368,11 +423,40 @@ func bindTypeJava(kind abi.Type, structs map[string]*tmplStruct) string {
 	}
 }
 
+// bindBasicTypeTypeScript converts basic solidity types(except array, slice and
+// tuple) to TypeScript ones, as encoded and decoded by ethers.
+func bindBasicTypeTypeScript(kind abi.Type) string {
+	switch kind.T {
+	case abi.AddressTy, abi.StringTy, abi.FixedBytesTy, abi.BytesTy, abi.FunctionTy:
+		// Binary types are 0x-prefixed hexadecimal strings
+		return "string"
+	case abi.IntTy, abi.UintTy:
+		return "bigint"
+	case abi.BoolTy:
+		return "boolean"
+	default:
+		return kind.String()
+	}
+}
+
+// bindTypeTypeScript converts a Solidity type to a TypeScript one.
+func bindTypeTypeScript(kind abi.Type, structs map[string]*tmplStruct) string {
+	switch kind.T {
+	case abi.TupleTy:
+		return structs[kind.TupleRawName+kind.String()].Name
+	case abi.ArrayTy, abi.SliceTy:
+		return bindTypeTypeScript(*kind.Elem, structs) + "[]"
+	default:
+		return bindBasicTypeTypeScript(kind)
+	}
+}
+
 // bindTopicType is a set of type binders that convert Solidity types to some
 // supported programming language topic types.
 var bindTopicType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
-	LangGo:   bindTopicTypeGo,
-	LangJava: bindTopicTypeJava,
+	LangGo:         bindTopicTypeGo,
+	LangJava:       bindTopicTypeJava,
+	LangTypeScript: bindTopicTypeTypeScript,
 }
 
 // bindTopicTypeGo converts a Solidity topic type to a Go one. It is almost the same
@@ -409,11 +493,33 @@ func bindTopicTypeJava(kind abi.Type, structs map[string]*tmplStruct) string {
 	return bound
 }
 
+// bindTopicTypeTypeScript converts a Solidity topic type to a TypeScript one.
+// Unlike the Go and Java binders, all the types stored as hashes in the topics
+// are converted, as the hash is all that can be decoded.
+func bindTopicTypeTypeScript(kind abi.Type, structs map[string]*tmplStruct) string {
+	if hashedTopic(kind) {
+		return "string"
+	}
+	return bindTypeTypeScript(kind, structs)
+}
+
+// hashedTopic reports whether an indexed event parameter of the given type is
+// stored in the topics as the keccak256 hash of its encoding.
+func hashedTopic(kind abi.Type) bool {
+	switch kind.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return true
+	default:
+		return false
+	}
+}
+
 // bindStructType is a set of type binders that convert Solidity tuple types to some supported
 // programming language struct definition.
 var bindStructType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
-	LangGo:   bindStructTypeGo,
-	LangJava: bindStructTypeJava,
+	LangGo:         bindStructTypeGo,
+	LangJava:       bindStructTypeJava,
+	LangTypeScript: bindStructTypeTypeScript,
 }
 
 // bindStructTypeGo converts a Solidity tuple type to a Go one and records the mapping
@@ -492,11 +598,44 @@ func bindStructTypeJava(kind abi.Type, structs map[string]*tmplStruct) string {
 	}
 }
 
+// bindStructTypeTypeScript converts a Solidity tuple type to a TypeScript one and
+// records the mapping in the given map. The fields keep their raw names, which
+// ethers uses to access the decoded values.
+// Notably, this function will resolve and record nested struct recursively.
+func bindStructTypeTypeScript(kind abi.Type, structs map[string]*tmplStruct) string {
+	switch kind.T {
+	case abi.TupleTy:
+		id := kind.TupleRawName + kind.String()
+		if s, exist := structs[id]; exist {
+			return s.Name
+		}
+		var fields []*tmplField
+		for i, elem := range kind.TupleElems {
+			field := bindStructTypeTypeScript(*elem, structs)
+			fields = append(fields, &tmplField{Type: field, Name: kind.TupleRawNames[i], SolKind: *elem})
+		}
+		name := kind.TupleRawName
+		if name == "" {
+			name = fmt.Sprintf("Struct%d", len(structs))
+		}
+		structs[id] = &tmplStruct{
+			Name:   name,
+			Fields: fields,
+		}
+		return name
+	case abi.ArrayTy, abi.SliceTy:
+		return bindStructTypeTypeScript(*kind.Elem, structs) + "[]"
+	default:
+		return bindBasicTypeTypeScript(kind)
+	}
+}
+
 // namedType is a set of functions that transform language specific types to
 // named versions that may be used inside method names.
 var namedType = map[Lang]func(string, abi.Type) string{
-	LangGo:   func(string, abi.Type) string { panic("this shouldn't be needed") },
-	LangJava: namedTypeJava,
+	LangGo:         func(string, abi.Type) string { panic("this shouldn't be needed") },
+	LangJava:       namedTypeJava,
+	LangTypeScript: func(string, abi.Type) string { panic("this shouldn't be needed") },
 }
 
 // namedTypeJava converts some primitive data types to named variants that can
@@ -537,8 +676,9 @@ func alias(aliases map[string]string, n string) string {
 // methodNormalizer is a name transformer that modifies Solidity method names to
 // conform to target language naming conventions.
 var methodNormalizer = map[Lang]func(string) string{
-	LangGo:   abi.ToCamelCase,
-	LangJava: decapitalise,
+	LangGo:         abi.ToCamelCase,
+	LangJava:       decapitalise,
+	LangTypeScript: decapitalise,
 }
 
 // capitalise makes a camel-case string which starts with an upper case character.
@@ -907,6 +907,7 @@ var bindTests = []struct {
 		[]string{`608060405234801561001057600080fd5b5061043f806100206000396000f3006080604052600436106100615763ffffffff7c0100000000000000000000000000000000000000000000000000000000600035041663528300ff8114610066578063630c31e2146100ff5780636cc6b94014610138578063c7d116dd1461015b575b600080fd5b34801561007257600080fd5b506040805160206004803580820135601f81018490048402850184019095528484526100fd94369492936024939284019190819084018382808284375050604080516020601f89358b018035918201839004830284018301909452808352979a9998810197919650918201945092508291508401838280828437509497506101829650505050505050565b005b34801561010b57600080fd5b506100fd73ffffffffffffffffffffffffffffffffffffffff60043516602435604435151560643561033c565b34801561014457600080fd5b506100fd67ffffffffffffffff1960043516610394565b34801561016757600080fd5b506100fd60043560243560010b63ffffffff604435166103d6565b806040518082805190602001908083835b602083106101b25780518252601f199092019160209182019101610193565b51815160209384036101000a6000190180199092169116179052604051919093018190038120875190955087945090928392508401908083835b6020831061020b5780518252601f1990920191602091820191016101ec565b6001836020036101000a03801982511681845116808217855250505050505090500191505060405180910390207f3281fd4f5e152dd3385df49104a3f633706e21c9e80672e88d3bcddf33101f008484604051808060200180602001838103835285818151815260200191508051906020019080838360005b8381101561029c578181015183820152602001610284565b50505050905090810190601f1680156102c95780820380516001836020036101000a031916815260200191505b50838103825284518152845160209182019186019080838360005b838110156102fc5781810151838201526020016102e4565b50505050905090810190601f1680156103295780820380516001836020036101000a031916815260200191505b5094505050505060405180910390a35050565b60408051828152905183151591859173ffffffffffffffffffffffffffffffffffffffff8816917f1f097de4289df643bd9c11011cc61367aa12983405c021056e706eb5ba1250c8919081900360200190a450505050565b6040805167ffffffffffffffff19831680825291517fcdc4c1b1aed5524ffb4198d7a5839a34712baef5fa06884fac7559f4a5854e0a9181900360200190a250565b8063ffffffff168260010b847f3ca7f3a77e5e6e15e781850bc82e32adfa378a2a609370db24b4d0fae10da2c960405160405180910390a45050505600a165627a7a72305820468b5843bf653145bd924b323c64ef035d3dd922c170644b44d61aa666ea6eee0029`},
 		[]string{`[{"constant":false,"inputs":[{"name":"str","type":"string"},{"name":"blob","type":"bytes"}],"name":"raiseDynamicEvent","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"},{"constant":false,"inputs":[{"name":"addr","type":"address"},{"name":"id","type":"bytes32"},{"name":"flag","type":"bool"},{"name":"value","type":"uint256"}],"name":"raiseSimpleEvent","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"},{"constant":false,"inputs":[{"name":"blob","type":"bytes24"}],"name":"raiseFixedBytesEvent","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"},{"constant":false,"inputs":[{"name":"number","type":"uint256"},{"name":"short","type":"int16"},{"name":"long","type":"uint32"}],"name":"raiseNodataEvent","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"},{"anonymous":false,"inputs":[{"indexed":true,"name":"Addr","type":"address"},{"indexed":true,"name":"Id","type":"bytes32"},{"indexed":true,"name":"Flag","type":"bool"},{"indexed":false,"name":"Value","type":"uint256"}],"name":"SimpleEvent","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"name":"Number","type":"uint256"},{"indexed":true,"name":"Short","type":"int16"},{"indexed":true,"name":"Long","type":"uint32"}],"name":"NodataEvent","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"name":"IndexedString","type":"string"},{"indexed":true,"name":"IndexedBytes","type":"bytes"},{"indexed":false,"name":"NonIndexedString","type":"string"},{"indexed":false,"name":"NonIndexedBytes","type":"bytes"}],"name":"DynamicEvent","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"name":"IndexedBytes","type":"bytes24"},{"indexed":false,"name":"NonIndexedBytes","type":"bytes24"}],"name":"FixedBytesEvent","type":"event"}]`},
 		`
+			"fmt"
 			"math/big"
 			"time"
 
@@ -1077,6 +1078,36 @@ var bindTests = []struct {
 				t.Fatalf("unsubscribed simple event arrived: %v", event)
 			case <-time.After(250 * time.Millisecond):
 			}
+			// Follow the simple events from the genesis, ensuring the past ones are
+			// back-filled before the new ones are delivered
+			start := uint64(0)
+			fit2, err := eventer.FollowSimpleEvent(&bind.WatchOpts{Start: &start}, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to follow simple events: %v", err)
+			}
+			defer fit2.Close()
+
+			var values []uint64
+			for len(values) < 8 && fit2.Next() {
+				values = append(values, fit2.Event.Value.Uint64())
+			}
+			if err = fit2.Error(); err != nil {
+				t.Fatalf("followed simple event iteration failed: %v", err)
+			}
+			if have, want := fmt.Sprint(values), "[11 21 22 31 32 33 255 254]"; have != want {
+				t.Errorf("back-filled simple events mismatch: have %v, want %v", have, want)
+			}
+			if _, err := eventer.RaiseSimpleEvent(auth, common.Address{253}, [32]byte{253}, true, big.NewInt(253)); err != nil {
+				t.Fatalf("failed to raise followed simple event: %v", err)
+			}
+			sim.Commit()
+
+			if !fit2.Next() {
+				t.Fatalf("followed simple event not found: %v", fit2.Error())
+			}
+			if fit2.Event.Value.Uint64() != 253 {
+				t.Errorf("followed simple log content mismatch: have %v, want 253", fit2.Event)
+			}
 		`,
 		nil,
 		nil,
@@ -1874,6 +1905,7 @@ var bindTests = []struct {
 		[]string{"0x6080604052348015600f57600080fd5b5060998061001e6000396000f3fe6080604052348015600f57600080fd5b506004361060285760003560e01c8063726c638214602d575b600080fd5b60336035565b005b60405163024876cd60e61b815260016004820152600260248201526003604482015260640160405180910390fdfea264697066735822122093f786a1bc60216540cd999fbb4a6109e0fef20abcff6e9107fb2817ca968f3c64736f6c63430008070033"},
 		[]string{`[{"inputs":[{"internalType":"uint256","name":"","type":"uint256"}],"name":"MyError","type":"error"},{"inputs":[{"internalType":"uint256","name":"","type":"uint256"}],"name":"MyError1","type":"error"},{"inputs":[{"internalType":"uint256","name":"","type":"uint256"},{"internalType":"uint256","name":"","type":"uint256"}],"name":"MyError2","type":"error"},{"inputs":[{"internalType":"uint256","name":"a","type":"uint256"},{"internalType":"uint256","name":"b","type":"uint256"},{"internalType":"uint256","name":"c","type":"uint256"}],"name":"MyError3","type":"error"},{"inputs":[],"name":"Error","outputs":[],"stateMutability":"pure","type":"function"}]`},
 		`
+			"errors"
 			"math/big"
 	
 			"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -1899,17 +1931,111 @@ var bindTests = []struct {
 			if err != nil {
 				t.Error(err)
 			}
-			if err := contract.Error(new(bind.CallOpts)); err == nil {
+			err = contract.Error(new(bind.CallOpts))
+			if err == nil {
 				t.Fatalf("expected contract to throw error")
 			}
-			// TODO (MariusVanDerWijden unpack error using abigen
-			// once that is implemented
+			var myErr *NewErrorsMyError3Error
+			if !errors.As(err, &myErr) {
+				t.Fatalf("error type mismatch: have %T, want %T", err, myErr)
+			}
+			if myErr.A.Int64() != 1 || myErr.B.Int64() != 2 || myErr.C.Int64() != 3 {
+				t.Fatalf("error values mismatch: have %+v, want {A:1 B:2 C:3}", *myErr)
+			}
 	   `,
 		nil,
 		nil,
 		nil,
 		nil,
 	},
+	// The bytecode of RevertErrors is hand-assembled rather than compiled from the
+	// source, reverting from each method with the same data the source describes.
+	{
+		name: `RevertErrors`,
+		contract: `
+		pragma solidity >0.8.4;
+
+		contract RevertErrors {
+			error InsufficientBalance(uint256 needed, uint256 available);
+			error Unauthorized();
+
+			function balance(uint256 amount) public pure { revert InsufficientBalance(amount, 1); }
+			function transfer(uint256 amount) public { revert InsufficientBalance(amount, 1); }
+			function reason() public pure { revert("no reason"); }
+			function panic() public pure { assert(false); }
+			function guarded() public pure { revert Unauthorized(); }
+		}
+		`,
+		bytecode: []string{`0x60b080600b6000396000f360003560e01c806347bb89f01461004257806312514bba14610042578063e134e33d1461005e5780634700d3051461008957806372f7a0301461009f5760008060fd5b63cf47918160e01b600052600435600452600160245260446000fd5b6308c379a060e01b60005260206004526009602452686e6f20726561736f6e60b81b60445260646000fd5b634e487b7160e01b600052600160045260246000fd5b6382b4290060e01b60005260046000fd`},
+		abi:      []string{`[{"inputs":[{"internalType":"uint256","name":"needed","type":"uint256"},{"internalType":"uint256","name":"available","type":"uint256"}],"name":"InsufficientBalance","type":"error"},{"inputs":[],"name":"Unauthorized","type":"error"},{"inputs":[{"internalType":"uint256","name":"amount","type":"uint256"}],"name":"balance","outputs":[],"stateMutability":"pure","type":"function"},{"inputs":[],"name":"guarded","outputs":[],"stateMutability":"pure","type":"function"},{"inputs":[],"name":"panic","outputs":[],"stateMutability":"pure","type":"function"},{"inputs":[],"name":"reason","outputs":[],"stateMutability":"pure","type":"function"},{"inputs":[{"internalType":"uint256","name":"amount","type":"uint256"}],"name":"transfer","outputs":[],"stateMutability":"nonpayable","type":"function"}]`},
+		imports: `
+			"errors"
+			"math/big"
+
+			"github.com/ethereum/go-ethereum/accounts/abi/bind"
+			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/ethereum/go-ethereum/core"
+			"github.com/ethereum/go-ethereum/crypto"
+			"github.com/ethereum/go-ethereum/eth/ethconfig"
+			"github.com/ethereum/go-ethereum/rpc"
+		`,
+		tester: `
+			var (
+				key, _  = crypto.GenerateKey()
+				user, _ = bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+				sim     = backends.NewSimulatedBackend(core.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, ethconfig.Defaults.Miner.GasCeil)
+			)
+			defer sim.Close()
+
+			_, _, contract, err := DeployRevertErrors(user, sim)
+			if err != nil {
+				t.Fatalf("failed to deploy contract: %v", err)
+			}
+			sim.Commit()
+
+			// Custom errors should be unpacked into their typed counterparts
+			var insufficient *RevertErrorsInsufficientBalanceError
+			if err := contract.Balance(nil, big.NewInt(42)); !errors.As(err, &insufficient) {
+				t.Fatalf("call error type mismatch: have %T, want %T", err, insufficient)
+			} else if insufficient.Needed.Int64() != 42 || insufficient.Available.Int64() != 1 {
+				t.Fatalf("call error values mismatch: have %v, want needed 42, available 1", insufficient)
+			}
+			// The typed errors must keep the original one carrying the revert data
+			var dataErr rpc.DataError
+			if !errors.As(insufficient, &dataErr) {
+				t.Fatalf("custom error doesn't unwrap into the revert data error")
+			}
+			if data, ok := bind.RevertData(insufficient); !ok || len(data) != 4+2*32 {
+				t.Fatalf("revert data mismatch: have %x, ok %v", data, ok)
+			}
+			if _, err := contract.Transfer(user, big.NewInt(43)); !errors.As(err, &insufficient) {
+				t.Fatalf("gas estimation error type mismatch: have %T, want %T", err, insufficient)
+			} else if insufficient.Needed.Int64() != 43 {
+				t.Fatalf("gas estimation error values mismatch: have %v, want needed 43, available 1", insufficient)
+			}
+			var unauthorized *RevertErrorsUnauthorizedError
+			if err := contract.Guarded(nil); !errors.As(err, &unauthorized) {
+				t.Fatalf("call error type mismatch: have %T, want %T", err, unauthorized)
+			}
+			// Builtin errors should be unpacked into the generic bind errors
+			var revert *bind.RevertError
+			if err := contract.Reason(nil); !errors.As(err, &revert) {
+				t.Fatalf("revert error type mismatch: have %T, want %T", err, revert)
+			} else if revert.Reason != "no reason" {
+				t.Fatalf("revert reason mismatch: have %q, want %q", revert.Reason, "no reason")
+			} else if !errors.As(revert, &dataErr) {
+				t.Fatalf("revert error doesn't unwrap into the revert data error")
+			}
+			var panicked *bind.PanicError
+			if err := contract.Panic(nil); !errors.As(err, &panicked) {
+				t.Fatalf("panic error type mismatch: have %T, want %T", err, panicked)
+			} else if panicked.Code.Int64() != 1 {
+				t.Fatalf("panic code mismatch: have %v, want 1", panicked.Code)
+			} else if !errors.As(panicked, &dataErr) {
+				t.Fatalf("panic error doesn't unwrap into the revert data error")
+			}
+		`,
+	},
 	{
 		name: `ConstructorWithStructParam`,
 		contract: `
@@ -2435,3 +2561,38 @@ public class Test {
 		}
 	}
 }
+
+// Tests that the TypeScript binder generates encoders and decoders for all the
+// calls, events and custom errors of a contract.
+func TestTypeScriptBindings(t *testing.T) {
+	abi := `[{"inputs":[{"name":"owner","type":"address"}],"stateMutability":"nonpayable","type":"constructor"},{"inputs":[{"name":"needed","type":"uint256"},{"name":"available","type":"uint256"}],"name":"InsufficientBalance","type":"error"},{"inputs":[],"name":"Unauthorized","type":"error"},{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"memo","type":"string"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"},{"inputs":[{"name":"who","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"info","outputs":[{"name":"a","type":"uint8"},{"components":[{"name":"x","type":"uint256"},{"name":"tags","type":"bytes32[]"}],"internalType":"struct Token.Point","name":"p","type":"tuple"}],"stateMutability":"view","type":"function"},{"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"name":"transfer","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+	binding, err := Bind([]string{"Token"}, []string{abi}, []string{"6001"}, nil, "", LangTypeScript, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to generate binding: %v", err)
+	}
+	for _, want := range []string{
+		"export interface TokenPoint {\n  x: bigint;\n  tags: string[];\n}",
+		"export interface TokenTransfer {\n  from: string;\n  memo: string;\n  value: bigint;\n}",
+		"export interface TokenInsufficientBalanceError {\n  needed: bigint;\n  available: bigint;\n}",
+		"| { name: \"Unauthorized\"; args: TokenUnauthorizedError };",
+		"static readonly bytecode = \"0x6001\";",
+		"static encodeDeploy(owner: string): string {",
+		"static encodeBalanceOf(who: string): string {",
+		"static decodeBalanceOf(data: string): bigint {",
+		"static decodeInfo(data: string): [bigint, TokenPoint] {",
+		"static encodeTransfer(to: string, value: bigint): string {",
+		"memo: (result[1] as Indexed).hash,",
+		"static encodeTransferTopics(from: string | null = null, memo: string | null = null): Array<string | string[] | null> {",
+		"static decodeInsufficientBalanceError(data: string): TokenInsufficientBalanceError {",
+		"case \"0xcf479181\":",
+		"case \"0x82b42900\":",
+	} {
+		if !strings.Contains(binding, want) {
+			t.Errorf("generated binding missing %q:\n%s", want, binding)
+		}
+	}
+	if strings.Contains(binding, "decodeTransfer(") {
+		t.Errorf("generated binding decodes the return data of a method without outputs")
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// EventIterator iterates over the logs of a contract event, unpacking each of
+// them into the event type of the binding. It backs the typed iterators of the
+// generated bindings, both over past logs and over followed ones.
+type EventIterator struct {
+	unpack  func(types.Log) (interface{}, error) // Unpacks a log into the event type of the binding
+	current interface{}                          // Event unpacked from the current log
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// NewEventIterator creates an iterator over the logs delivered by a filter or
+// watch operation, unpacking them with the given function.
+func NewEventIterator(logs chan types.Log, sub ethereum.Subscription, unpack func(types.Log) (interface{}, error)) *EventIterator {
+	return &EventIterator{
+		unpack: unpack,
+		logs:   logs,
+		sub:    sub,
+	}
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *EventIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			return it.next(log)
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		return it.next(log)
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// next unpacks a log into the current event.
+func (it *EventIterator) next(log types.Log) bool {
+	event, err := it.unpack(log)
+	if err != nil {
+		it.fail = err
+		return false
+	}
+	it.current = event
+	return true
+}
+
+// Current returns the event unpacked by the last successful call to Next.
+func (it *EventIterator) Current() interface{} {
+	return it.current
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *EventIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *EventIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
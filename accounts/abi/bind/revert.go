@@ -0,0 +1,131 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// panicReasons maps the solidity panic codes to their meaning.
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "enum overflow",
+	0x22: "invalid encoded storage byte array accessed",
+	0x31: "out-of-bounds array access; popping on an empty array",
+	0x32: "out-of-bounds access of an array or bytesN",
+	0x41: "out of memory",
+	0x51: "uninitialized function",
+}
+
+// RevertError is returned by contract calls and gas estimations reverting with
+// a reason string, encoded as the builtin Error(string) solidity error.
+type RevertError struct {
+	Reason string // Revert reason passed to require or revert
+
+	err error // Original error carrying the revert data
+}
+
+// Error implements the error interface.
+func (e *RevertError) Error() string {
+	return "execution reverted: " + e.Reason
+}
+
+// Unwrap returns the original error carrying the revert data.
+func (e *RevertError) Unwrap() error {
+	return e.err
+}
+
+// PanicError is returned by contract calls and gas estimations reverting with
+// a panic code, encoded as the builtin Panic(uint256) solidity error.
+type PanicError struct {
+	Code *big.Int // Panic code raised by the compiler generated checks
+
+	err error // Original error carrying the revert data
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	if e.Code.IsUint64() {
+		if reason, ok := panicReasons[e.Code.Uint64()]; ok {
+			return fmt.Sprintf("execution reverted: panic %#x (%s)", e.Code, reason)
+		}
+	}
+	return fmt.Sprintf("execution reverted: panic %#x", e.Code)
+}
+
+// Unwrap returns the original error carrying the revert data.
+func (e *PanicError) Unwrap() error {
+	return e.err
+}
+
+// ErrorUnpacker converts the revert data of a failed contract call into a typed
+// custom error wrapping the original one, returning nil if the data matches none
+// of the known errors.
+type ErrorUnpacker func(data []byte, err error) error
+
+// RevertData extracts the revert data attached to an error returned by eth_call
+// or eth_estimateGas, either by a remote node or by the simulated backend.
+func RevertData(err error) ([]byte, bool) {
+	var dataErr interface {
+		ErrorData() interface{}
+	}
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	switch data := dataErr.ErrorData().(type) {
+	case []byte:
+		return data, true
+	case string:
+		blob, err := hexutil.Decode(data)
+		if err != nil {
+			return nil, false
+		}
+		return blob, true
+	}
+	return nil, false
+}
+
+// UnpackError converts a contract call or gas estimation failure carrying revert
+// data into a typed error: the one returned by the custom unpacker if it matches,
+// a RevertError or a PanicError for the builtin solidity errors, or the original
+// error otherwise. The typed errors unwrap into the original one.
+func UnpackError(err error, unpack ErrorUnpacker) error {
+	data, ok := RevertData(err)
+	if !ok {
+		return err
+	}
+	if unpack != nil {
+		if custom := unpack(data, err); custom != nil {
+			return custom
+		}
+	}
+	if reason, errUnpack := abi.UnpackRevert(data); errUnpack == nil {
+		return &RevertError{Reason: reason, err: err}
+	}
+	if code, errUnpack := abi.UnpackPanic(data); errUnpack == nil {
+		return &PanicError{Code: code, err: err}
+	}
+	return err
+}
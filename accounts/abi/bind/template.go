@@ -38,6 +38,7 @@ type tmplContract struct {
 	Fallback    *tmplMethod            // Additional special fallback function
 	Receive     *tmplMethod            // Additional special receive function
 	Events      map[string]*tmplEvent  // Contract events accessors
+	Errors      map[string]*tmplError  // Contract custom errors
 	Libraries   map[string]string      // Same as tmplData, but filtered to only keep what the contract needs
 	Library     bool                   // Indicator whether the contract is a library
 }
@@ -57,6 +58,13 @@ type tmplEvent struct {
 	Normalized abi.Event // Normalized version of the parsed fields
 }
 
+// tmplError is a wrapper around an abi.Error that contains a few preprocessed
+// and cached data fields.
+type tmplError struct {
+	Original   abi.Error // Original error as parsed by the abi package
+	Normalized abi.Error // Normalized version of the parsed error
+}
+
 // tmplField is a wrapper around a struct field with binding language
 // struct type definition and relative filed name.
 type tmplField struct {
@@ -75,8 +83,9 @@ type tmplStruct struct {
 // tmplSource is language to template mapping containing all the supported
 // programming languages the package can generate to.
 var tmplSource = map[Lang]string{
-	LangGo:   tmplSourceGo,
-	LangJava: tmplSourceJava,
+	LangGo:         tmplSourceGo,
+	LangJava:       tmplSourceJava,
+	LangTypeScript: tmplSourceTypeScript,
 }
 
 // tmplSourceGo is the Go source template that the generated Go contract binding
@@ -88,9 +97,10 @@ const tmplSourceGo = `
 package {{.Package}}
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 	"strings"
-	"errors"
 
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -103,6 +113,7 @@ import (
 // Reference imports to suppress errors if they are not otherwise used.
 var (
 	_ = errors.New
+	_ = fmt.Sprintf
 	_ = big.NewInt
 	_ = strings.NewReader
 	_ = ethereum.NotFound
@@ -165,8 +176,9 @@ var (
 		  {{end}}
 		  address, tx, contract, err := bind.DeployContract(auth, *parsed, common.FromHex({{.Type}}Bin), backend {{range .Constructor.Inputs}}, {{.Name}}{{end}})
 		  if err != nil {
-		    return common.Address{}, nil, nil, err
+		    return common.Address{}, nil, nil, {{if .Errors}}bind.UnpackError(err, unpack{{.Type}}Error){{else}}err{{end}}
 		  }
+		  {{if .Errors}}contract.SetErrorUnpacker(unpack{{.Type}}Error){{end}}
 		  return address, tx, &{{.Type}}{ {{.Type}}Caller: {{.Type}}Caller{contract: contract}, {{.Type}}Transactor: {{.Type}}Transactor{contract: contract}, {{.Type}}Filterer: {{.Type}}Filterer{contract: contract} }, nil
 		}
 	{{end}}
@@ -272,7 +284,13 @@ var (
 	  if err != nil {
 	    return nil, err
 	  }
-	  return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+	  {{if .Errors}}
+	    contract := bind.NewBoundContract(address, parsed, caller, transactor, filterer)
+	    contract.SetErrorUnpacker(unpack{{.Type}}Error)
+	    return contract, nil
+	  {{else}}
+	    return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+	  {{end}}
 	}
 
 	// Call invokes the (constant) contract method with params as input values and
@@ -425,68 +443,21 @@ var (
 	{{end}}
 
 	{{range .Events}}
-		// {{$contract.Type}}{{.Normalized.Name}}Iterator is returned from Filter{{.Normalized.Name}} and is used to iterate over the raw logs and unpacked data for {{.Normalized.Name}} events raised by the {{$contract.Type}} contract.
+		// {{$contract.Type}}{{.Normalized.Name}}Iterator is returned from Filter{{.Normalized.Name}} and Follow{{.Normalized.Name}} and is used to iterate over the raw logs and unpacked data for {{.Normalized.Name}} events raised by the {{$contract.Type}} contract.
 		type {{$contract.Type}}{{.Normalized.Name}}Iterator struct {
 			Event *{{$contract.Type}}{{.Normalized.Name}} // Event containing the contract specifics and raw log
 
-			contract *bind.BoundContract // Generic contract to use for unpacking event data
-			event    string              // Event name to use for unpacking event data
-
-			logs chan types.Log        // Log channel receiving the found contract events
-			sub  ethereum.Subscription // Subscription for errors, completion and termination
-			done bool                  // Whether the subscription completed delivering logs
-			fail error                 // Occurred error to stop iteration
+			*bind.EventIterator // Shared iterator retrieving and unpacking the logs
 		}
 		// Next advances the iterator to the subsequent event, returning whether there
 		// are any more events found. In case of a retrieval or parsing error, false is
 		// returned and Error() can be queried for the exact failure.
 		func (it *{{$contract.Type}}{{.Normalized.Name}}Iterator) Next() bool {
-			// If the iterator failed, stop iterating
-			if (it.fail != nil) {
+			if !it.EventIterator.Next() {
 				return false
 			}
-			// If the iterator completed, deliver directly whatever's available
-			if (it.done) {
-				select {
-				case log := <-it.logs:
-					it.Event = new({{$contract.Type}}{{.Normalized.Name}})
-					if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
-						it.fail = err
-						return false
-					}
-					it.Event.Raw = log
-					return true
-
-				default:
-					return false
-				}
-			}
-			// Iterator still in progress, wait for either a data or an error event
-			select {
-			case log := <-it.logs:
-				it.Event = new({{$contract.Type}}{{.Normalized.Name}})
-				if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
-					it.fail = err
-					return false
-				}
-				it.Event.Raw = log
-				return true
-
-			case err := <-it.sub.Err():
-				it.done = true
-				it.fail = err
-				return it.Next()
-			}
-		}
-		// Error returns any retrieval or parsing error occurred during filtering.
-		func (it *{{$contract.Type}}{{.Normalized.Name}}Iterator) Error() error {
-			return it.fail
-		}
-		// Close terminates the iteration process, releasing any pending underlying
-		// resources.
-		func (it *{{$contract.Type}}{{.Normalized.Name}}Iterator) Close() error {
-			it.sub.Unsubscribe()
-			return nil
+			it.Event = it.EventIterator.Current().(*{{$contract.Type}}{{.Normalized.Name}})
+			return true
 		}
 
 		// {{$contract.Type}}{{.Normalized.Name}} represents a {{.Normalized.Name}} event raised by the {{$contract.Type}} contract.
@@ -509,9 +480,31 @@ var (
 			if err != nil {
 				return nil, err
 			}
-			return &{{$contract.Type}}{{.Normalized.Name}}Iterator{contract: _{{$contract.Type}}.contract, event: "{{.Original.Name}}", logs: logs, sub: sub}, nil
+			return &{{$contract.Type}}{{.Normalized.Name}}Iterator{EventIterator: bind.NewEventIterator(logs, sub, func(log types.Log) (interface{}, error) {
+				return _{{$contract.Type}}.Parse{{.Normalized.Name}}(log)
+			})}, nil
  		}
 
+		// Follow{{.Normalized.Name}} is a log retrieval and subscription operation binding the contract event 0x{{printf "%x" .Original.ID}},
+		// back-filling the events from the start block of the options before following the new ones.
+		//
+		// Solidity: {{.Original.String}}
+		func (_{{$contract.Type}} *{{$contract.Type}}Filterer) Follow{{.Normalized.Name}}(opts *bind.WatchOpts{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type $structs}}{{end}}{{end}}) (*{{$contract.Type}}{{.Normalized.Name}}Iterator, error) {
+			{{range .Normalized.Inputs}}
+			{{if .Indexed}}var {{.Name}}Rule []interface{}
+			for _, {{.Name}}Item := range {{.Name}} {
+				{{.Name}}Rule = append({{.Name}}Rule, {{.Name}}Item)
+			}{{end}}{{end}}
+
+			logs, sub, err := _{{$contract.Type}}.contract.FollowLogs(opts, "{{.Original.Name}}"{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}Rule{{end}}{{end}})
+			if err != nil {
+				return nil, err
+			}
+			return &{{$contract.Type}}{{.Normalized.Name}}Iterator{EventIterator: bind.NewEventIterator(logs, sub, func(log types.Log) (interface{}, error) {
+				return _{{$contract.Type}}.Parse{{.Normalized.Name}}(log)
+			})}, nil
+		}
+
 		// Watch{{.Normalized.Name}} is a free log subscription operation binding the contract event 0x{{printf "%x" .Original.ID}}.
 		//
 		// Solidity: {{.Original.String}}
@@ -567,6 +560,63 @@ var (
 		}
 
  	{{end}}
+
+	{{range .Errors}}
+		// {{$contract.Type}}{{.Normalized.Name}}Error represents a {{.Normalized.Name}} custom error raised by the {{$contract.Type}} contract.
+		type {{$contract.Type}}{{.Normalized.Name}}Error struct { {{range .Normalized.Inputs}}
+			{{capitalise .Name}} {{bindtype .Type $structs}}; {{end}}
+
+			err error // Original error carrying the revert data
+		}
+
+		// Error implements the error interface, returning the custom error along with
+		// its unpacked values.
+		//
+		// Solidity: {{.Original.String}}
+		func (e *{{$contract.Type}}{{.Normalized.Name}}Error) Error() string {
+			return fmt.Sprintf("execution reverted: {{.Original.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if $i}}, {{end}}{{.Name}}: %v{{end}})"{{range .Normalized.Inputs}}, e.{{capitalise .Name}}{{end}})
+		}
+
+		// Unwrap returns the original error carrying the revert data.
+		func (e *{{$contract.Type}}{{.Normalized.Name}}Error) Unwrap() error {
+			return e.err
+		}
+	{{end}}
+
+	{{if .Errors}}
+		// unpack{{.Type}}Error converts the revert data of a failed call or gas estimation
+		// into the matching custom error of the {{.Type}} contract, wrapping the original
+		// error, or nil if none matches.
+		func unpack{{.Type}}Error(data []byte, cause error) error {
+			parsed, err := {{.Type}}MetaData.GetAbi()
+			if err != nil || len(data) < 4 {
+				return nil
+			}
+			var id [4]byte
+			copy(id[:], data)
+
+			errABI, err := parsed.ErrorByID(id)
+			if err != nil {
+				return nil
+			}
+			switch errABI.Name {
+			{{range .Errors}}
+			case "{{.Original.Name}}":
+				out := &{{$contract.Type}}{{.Normalized.Name}}Error{err: cause}
+				{{if .Normalized.Inputs}}
+					values, err := errABI.Inputs.Unpack(data[4:])
+					if err != nil {
+						return nil
+					}
+					{{range $i, $_ := .Normalized.Inputs}}out.{{capitalise .Name}} = *abi.ConvertType(values[{{$i}}], new({{bindtype .Type $structs}})).(*{{bindtype .Type $structs}})
+					{{end}}
+				{{end}}
+				return out
+			{{end}}
+			}
+			return nil
+		}
+	{{end}}
 {{end}}
 `
 
@@ -706,3 +756,123 @@ import java.util.*;
 }
 {{end}}
 `
+
+// tmplSourceTypeScript is the TypeScript source template that the generated
+// TypeScript contract binding is based on. The encoding and decoding is done by
+// the Interface of ethers.
+const tmplSourceTypeScript = `
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+import { Indexed, Interface } from "ethers";
+
+// RawLog is the subset of a log required to decode the events of a contract.
+export interface RawLog {
+  topics: ReadonlyArray<string>;
+  data: string;
+}
+
+{{$structs := .Structs}}
+{{range $structs}}
+// {{.Name}} is an auto generated low-level TypeScript binding around an user-defined struct.
+export interface {{.Name}} {
+{{range $field := .Fields}}  {{$field.Name}}: {{$field.Type}};
+{{end}}}
+{{end}}
+
+{{range $contract := .Contracts}}
+  {{range .Events}}
+// {{$contract.Type}}{{capitalise .Normalized.Name}} represents a {{capitalise .Normalized.Name}} event raised by the {{$contract.Type}} contract.
+export interface {{$contract.Type}}{{capitalise .Normalized.Name}} {
+{{range .Normalized.Inputs}}  {{.Name}}: {{if .Indexed}}{{bindtopictype .Type $structs}}{{else}}{{bindtype .Type $structs}}{{end}};
+{{end}}}
+  {{end}}
+  {{range .Errors}}
+// {{$contract.Type}}{{capitalise .Normalized.Name}}Error represents a {{.Original.Name}} custom error raised by the {{$contract.Type}} contract.
+export interface {{$contract.Type}}{{capitalise .Normalized.Name}}Error {
+{{range .Normalized.Inputs}}  {{.Name}}: {{bindtype .Type $structs}};
+{{end}}}
+  {{end}}
+  {{if .Errors}}
+// {{.Type}}Error is any of the custom errors raised by the {{.Type}} contract.
+export type {{.Type}}Error ={{range .Errors}}
+  | { name: "{{.Original.Name}}"; args: {{$contract.Type}}{{capitalise .Normalized.Name}}Error }{{end}};
+  {{end}}
+
+// {{.Type}} encodes and decodes the calls, events and custom errors of the {{.Type}} contract.
+export class {{.Type}} {
+  // abi is the input ABI used to generate the binding from.
+  static readonly abi = "{{.InputABI}}";
+  {{if .InputBin}}
+  // bytecode is the EVM bytecode used to deploy the contract.
+  static readonly bytecode = "0x{{.InputBin}}";
+  {{end}}
+  // iface is the ethers interface encoding and decoding the ABI.
+  static readonly iface = new Interface({{.Type}}.abi);
+  {{if .InputBin}}
+  // encodeDeploy encodes the transaction data deploying a new instance of the contract.
+  static encodeDeploy({{range $i, $_ := .Constructor.Inputs}}{{if $i}}, {{end}}{{.Name}}: {{bindtype .Type $structs}}{{end}}): string {
+    return {{$contract.Type}}.bytecode + {{$contract.Type}}.iface.encodeDeploy([{{range $i, $_ := .Constructor.Inputs}}{{if $i}}, {{end}}{{.Name}}{{end}}]).slice(2);
+  }
+  {{end}}
+  {{range $methods := list .Calls .Transacts}}{{range $methods}}
+  // encode{{capitalise .Normalized.Name}} encodes a call to the contract method 0x{{printf "%x" .Original.ID}}.
+  //
+  // Solidity: {{.Original.String}}
+  static encode{{capitalise .Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if $i}}, {{end}}{{.Name}}: {{bindtype .Type $structs}}{{end}}): string {
+    return {{$contract.Type}}.iface.encodeFunctionData("{{.Original.Sig}}", [{{range $i, $_ := .Normalized.Inputs}}{{if $i}}, {{end}}{{.Name}}{{end}}]);
+  }
+    {{if .Normalized.Outputs}}
+  // decode{{capitalise .Normalized.Name}} decodes the return data of a call to the contract method 0x{{printf "%x" .Original.ID}}.
+  //
+  // Solidity: {{.Original.String}}
+  static decode{{capitalise .Normalized.Name}}(data: string): {{if eq (len .Normalized.Outputs) 1}}{{range .Normalized.Outputs}}{{bindtype .Type $structs}}{{end}}{{else}}[{{range $i, $_ := .Normalized.Outputs}}{{if $i}}, {{end}}{{bindtype .Type $structs}}{{end}}]{{end}} {
+    const result = {{$contract.Type}}.iface.decodeFunctionResult("{{.Original.Sig}}", data);
+    return {{if eq (len .Normalized.Outputs) 1}}result[0]{{else}}[{{range $i, $_ := .Normalized.Outputs}}{{if $i}}, {{end}}result[{{$i}}]{{end}}]{{end}};
+  }
+    {{end}}
+  {{end}}{{end}}
+  {{range .Events}}
+  // decode{{capitalise .Normalized.Name}}Event decodes a log of the contract event 0x{{printf "%x" .Original.ID}}.
+  //
+  // Solidity: {{.Original.String}}
+  static decode{{capitalise .Normalized.Name}}Event(log: RawLog): {{$contract.Type}}{{capitalise .Normalized.Name}} {
+    {{if .Normalized.Inputs}}const result = {{end}}{{$contract.Type}}.iface.decodeEventLog("{{.Original.Sig}}", log.data, log.topics);
+    return {
+{{range $i, $_ := .Normalized.Inputs}}      {{.Name}}: {{if and .Indexed (hashedtopic .Type)}}(result[{{$i}}] as Indexed).hash{{else}}result[{{$i}}]{{end}},
+{{end}}    };
+  }
+
+  // encode{{capitalise .Normalized.Name}}Topics encodes the topics filtering for the contract event 0x{{printf "%x" .Original.ID}},
+  // with null matching any value of an indexed parameter.
+  //
+  // Solidity: {{.Original.String}}
+  static encode{{capitalise .Normalized.Name}}Topics({{$sep := ""}}{{range .Normalized.Inputs}}{{if .Indexed}}{{$sep}}{{.Name}}: {{bindtype .Type $structs}} | null = null{{$sep = ", "}}{{end}}{{end}}): Array<string | string[] | null> {
+    return {{$contract.Type}}.iface.encodeFilterTopics("{{.Original.Sig}}", [{{$sep := ""}}{{range .Normalized.Inputs}}{{if .Indexed}}{{$sep}}{{.Name}}{{$sep = ", "}}{{end}}{{end}}]);
+  }
+  {{end}}
+  {{range .Errors}}
+  // decode{{capitalise .Normalized.Name}}Error decodes the revert data of the custom error 0x{{printf "%x" (slice .Original.ID.Bytes 0 4)}}.
+  //
+  // Solidity: {{.Original.String}}
+  static decode{{capitalise .Normalized.Name}}Error(data: string): {{$contract.Type}}{{capitalise .Normalized.Name}}Error {
+    {{if .Normalized.Inputs}}const result = {{end}}{{$contract.Type}}.iface.decodeErrorResult("{{.Original.Sig}}", data);
+    return {
+{{range $i, $_ := .Normalized.Inputs}}      {{.Name}}: result[{{$i}}],
+{{end}}    };
+  }
+  {{end}}
+  {{if .Errors}}
+  // decodeError decodes the revert data of any custom error of the contract,
+  // returning null if it doesn't match any of them.
+  static decodeError(data: string): {{.Type}}Error | null {
+    switch (data.slice(0, 10).toLowerCase()) {
+{{range .Errors}}      case "0x{{printf "%x" (slice .Original.ID.Bytes 0 4)}}":
+        return { name: "{{.Original.Name}}", args: {{$contract.Type}}.decode{{capitalise .Normalized.Name}}Error(data) };
+{{end}}    }
+    return null;
+  }
+  {{end}}
+}
+{{end}}
+`
@@ -71,7 +71,7 @@ var (
 	}
 	langFlag = cli.StringFlag{
 		Name:  "lang",
-		Usage: "Destination language for the bindings (go, java, objc)",
+		Usage: "Destination language for the bindings (go, java, objc, ts)",
 		Value: "go",
 	}
 	aliasFlag = cli.StringFlag{
@@ -99,9 +99,6 @@ func init() {
 
 func abigen(c *cli.Context) error {
 	utils.CheckExclusive(c, abiFlag, jsonFlag) // Only one source can be selected.
-	if c.GlobalString(pkgFlag.Name) == "" {
-		utils.Fatalf("No destination package specified (--pkg)")
-	}
 	var lang bind.Lang
 	switch c.GlobalString(langFlag.Name) {
 	case "go":
@@ -111,9 +108,15 @@ func abigen(c *cli.Context) error {
 	case "objc":
 		lang = bind.LangObjC
 		utils.Fatalf("Objc binding generation is uncompleted")
+	case "ts":
+		lang = bind.LangTypeScript
 	default:
 		utils.Fatalf("Unsupported destination language \"%s\" (--lang)", c.GlobalString(langFlag.Name))
 	}
+	// TypeScript modules are not named, all the others need a package
+	if c.GlobalString(pkgFlag.Name) == "" && lang != bind.LangTypeScript {
+		utils.Fatalf("No destination package specified (--pkg)")
+	}
 	// If the entire solidity code was specified, build and bind based on that
 	var (
 		abis    []string
@@ -38,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/trie"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -47,8 +48,10 @@ var (
 		Name:      "init",
 		Usage:     "Bootstrap and initialize a new genesis block",
 		ArgsUsage: "<genesisPath>",
-		Flags:     utils.DatabasePathFlags,
-		Category:  "BLOCKCHAIN COMMANDS",
+		Flags: append([]cli.Flag{
+			utils.StateSchemeFlag,
+		}, utils.DatabasePathFlags...),
+		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The init command initializes a new genesis block and definition for the network.
 This is a destructive action and changes the network in which you will be
@@ -93,6 +96,8 @@ The dumpgenesis command dumps the genesis block configuration in JSON format to
 			utils.MetricsInfluxDBBucketFlag,
 			utils.MetricsInfluxDBOrganizationFlag,
 			utils.TxLookupLimitFlag,
+			utils.StateSchemeFlag,
+			utils.StateHistoryFlag,
 		}, utils.DatabasePathFlags...),
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -196,7 +201,12 @@ func initGenesis(ctx *cli.Context) error {
 		if err != nil {
 			utils.Fatalf("Failed to open database: %v", err)
 		}
-		_, hash, err := core.SetupGenesisBlock(chaindb, genesis)
+		// The light client only supports the hash-based state scheme
+		triedb := trie.NewDatabase(chaindb)
+		if name == "chaindata" {
+			triedb = trie.NewDatabaseWithConfig(chaindb, &trie.Config{Scheme: utils.ParseStateScheme(ctx, chaindb)})
+		}
+		_, hash, err := core.SetupGenesisBlockWithOverride(chaindb, triedb, genesis, nil, nil)
 		if err != nil {
 			utils.Fatalf("Failed to write genesis block: %v", err)
 		}
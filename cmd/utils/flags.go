@@ -67,6 +67,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
 	pcsclite "github.com/gballet/go-libpcsclite"
 	gopsutil "github.com/shirou/gopsutil/mem"
 	"gopkg.in/urfave/cli.v1"
@@ -122,6 +123,11 @@ var (
 		Name:  "datadir.ancient",
 		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backing database implementation to use ('leveldb' or 'pebble')",
+		Value: "leveldb",
+	}
 	MinFreeDiskSpaceFlag = DirectoryFlag{
 		Name:  "datadir.minfreedisk",
 		Usage: "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -240,6 +246,20 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	StateSchemeFlag = cli.StringFlag{
+		Name:  "state.scheme",
+		Usage: `Scheme to use for storing ethereum state ("hash" or "path", default = the scheme of the existing database or "hash")`,
+	}
+	StateHistoryFlag = cli.Uint64Flag{
+		Name:  "history.state",
+		Usage: "Number of recent blocks to retain state history for, path scheme only (default = 90,000 blocks, 0 = entire chain)",
+		Value: ethconfig.Defaults.StateHistory,
+	}
+	ChainHistoryFlag = cli.Uint64Flag{
+		Name:  "history.chain",
+		Usage: "Number of recent blocks to retain headers, bodies and receipts in the ancient store for (default = 0, entire chain)",
+		Value: ethconfig.Defaults.ChainHistory,
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -365,6 +385,24 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk journal for remote transactions to survive node restarts (default = disabled)",
+	}
+	TxPoolRemoteJournalSizeFlag = cli.Uint64Flag{
+		Name:  "txpool.remotejournalsize",
+		Usage: "Maximum size of the remote transaction journal in megabytes",
+		Value: core.DefaultTxPoolConfig.RemoteJournalSize / 1024 / 1024,
+	}
+	TxPoolRemoteJournalAgeFlag = cli.DurationFlag{
+		Name:  "txpool.remotejournalage",
+		Usage: "Maximum time since the journaled remote transactions were first seen",
+		Value: core.DefaultTxPoolConfig.RemoteJournalAge,
+	}
+	TxPoolFilterListFlag = cli.StringFlag{
+		Name:  "txpool.filterlist",
+		Usage: "JSON file of sender and recipient allow and deny lists to admit transactions by (reloadable via admin_reloadTxFilter)",
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -615,7 +653,7 @@ var (
 	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
-		Usage: "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
+		Usage: "Enable GraphQL on the HTTP-RPC server, and GraphQL subscriptions on the WS-RPC server if enabled. Note that GraphQL can only be started if an HTTP server is started as well.",
 	}
 	GraphQLCORSDomainFlag = cli.StringFlag{
 		Name:  "graphql.corsdomain",
@@ -627,6 +665,20 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 	}
+	GraphQLMaxCostFlag = cli.Uint64Flag{
+		Name:  "graphql.maxcost",
+		Usage: "Maximum estimated cost of a GraphQL query, queries above it are rejected (0 = no limit)",
+		Value: node.DefaultConfig.GraphQLMaxCost,
+	}
+	GraphQLTimeoutFlag = cli.DurationFlag{
+		Name:  "graphql.timeout",
+		Usage: "Maximum execution time of a GraphQL query (0 = no limit)",
+		Value: node.DefaultConfig.GraphQLTimeout,
+	}
+	GraphQLTracingFlag = cli.BoolFlag{
+		Name:  "graphql.tracing",
+		Usage: "Enable transaction tracing through GraphQL, including custom JavaScript tracers",
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -668,6 +720,16 @@ var (
 		Name:  "rpc.allow-unprotected-txs",
 		Usage: "Allow for unprotected (non EIP155 signed) transactions to be submitted via RPC",
 	}
+	BatchRequestLimit = cli.IntFlag{
+		Name:  "rpc.batch-request-limit",
+		Usage: "Maximum number of requests in a batch (0 = no limit)",
+		Value: node.DefaultConfig.BatchRequestLimit,
+	}
+	BatchResponseMaxSize = cli.IntFlag{
+		Name:  "rpc.batch-response-max-size",
+		Usage: "Maximum number of bytes returned from a batched call (0 = no limit)",
+		Value: node.DefaultConfig.BatchResponseMaxSize,
+	}
 
 	// Network Settings
 	MaxPeersFlag = cli.IntFlag{
@@ -850,6 +912,7 @@ var (
 		DataDirFlag,
 		AncientFlag,
 		RemoteDBFlag,
+		DBEngineFlag,
 	}
 )
 
@@ -1056,6 +1119,12 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.GlobalBool(AllowUnprotectedTxs.Name)
 	}
+	if ctx.GlobalIsSet(BatchRequestLimit.Name) {
+		cfg.BatchRequestLimit = ctx.GlobalInt(BatchRequestLimit.Name)
+	}
+	if ctx.GlobalIsSet(BatchResponseMaxSize.Name) {
+		cfg.BatchResponseMaxSize = ctx.GlobalInt(BatchResponseMaxSize.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -1067,6 +1136,15 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = SplitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(GraphQLMaxCostFlag.Name) {
+		cfg.GraphQLMaxCost = ctx.GlobalUint64(GraphQLMaxCostFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLTimeoutFlag.Name) {
+		cfg.GraphQLTimeout = ctx.GlobalDuration(GraphQLTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLTracingFlag.Name) {
+		cfg.GraphQLTracing = ctx.GlobalBool(GraphQLTracingFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -1344,6 +1422,14 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(InsecureUnlockAllowedFlag.Name) {
 		cfg.InsecureUnlockAllowed = ctx.GlobalBool(InsecureUnlockAllowedFlag.Name)
 	}
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		dbEngine := ctx.GlobalString(DBEngineFlag.Name)
+		if dbEngine != "leveldb" && dbEngine != "pebble" {
+			Fatalf("Invalid choice for db.engine '%s', allowed 'leveldb' or 'pebble'", dbEngine)
+		}
+		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
+		cfg.DBEngine = dbEngine
+	}
 }
 
 func setSmartCard(ctx *cli.Context, cfg *node.Config) {
@@ -1435,6 +1521,18 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalSizeFlag.Name) {
+		cfg.RemoteJournalSize = ctx.GlobalUint64(TxPoolRemoteJournalSizeFlag.Name) * 1024 * 1024
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalAgeFlag.Name) {
+		cfg.RemoteJournalAge = ctx.GlobalDuration(TxPoolRemoteJournalAgeFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolFilterListFlag.Name) {
+		cfg.FilterList = ctx.GlobalString(TxPoolFilterListFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1592,6 +1690,16 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(LightServeFlag.Name) && ctx.GlobalUint64(TxLookupLimitFlag.Name) != 0 {
 		log.Warn("LES server cannot serve old transaction status and cannot connect below les/4 protocol version if transaction lookup index is limited")
 	}
+	if ctx.GlobalString(GCModeFlag.Name) == "archive" && ctx.GlobalUint64(ChainHistoryFlag.Name) != 0 {
+		ctx.GlobalSet(ChainHistoryFlag.Name, "0")
+		log.Warn("Disable chain history pruning for archive node")
+	}
+	if ctx.GlobalIsSet(LightServeFlag.Name) && ctx.GlobalUint64(ChainHistoryFlag.Name) != 0 {
+		log.Warn("LES server cannot serve pruned chain history")
+	}
+	if ctx.GlobalString(GCModeFlag.Name) == "archive" && ctx.GlobalString(StateSchemeFlag.Name) == rawdb.PathScheme {
+		Fatalf("--%s=archive is not supported by the path-based state scheme", GCModeFlag.Name)
+	}
 	var ks *keystore.KeyStore
 	if keystores := stack.AccountManager().Backends(keystore.KeyStoreType); len(keystores) > 0 {
 		ks = keystores[0].(*keystore.KeyStore)
@@ -1656,6 +1764,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(StateSchemeFlag.Name) {
+		cfg.StateScheme = ctx.GlobalString(StateSchemeFlag.Name)
+	}
+	if ctx.GlobalIsSet(StateHistoryFlag.Name) {
+		cfg.StateHistory = ctx.GlobalUint64(StateHistoryFlag.Name)
+	}
+	if ctx.GlobalIsSet(ChainHistoryFlag.Name) {
+		cfg.ChainHistory = ctx.GlobalUint64(ChainHistoryFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1882,7 +1999,8 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
-	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
+	_, isLightClient := backend.(*les.LesApiBackend)
+	if err := graphql.New(stack, backend, isLightClient, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
 		Fatalf("Failed to register the GraphQL service: %v", err)
 	}
 }
@@ -2012,7 +2130,8 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chainDb ethdb.Database) {
 	var err error
 	chainDb = MakeChainDatabase(ctx, stack, false) // TODO(rjl493456442) support read-only database
-	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
+	scheme := ParseStateScheme(ctx, chainDb)
+	config, _, err := core.SetupGenesisBlockWithOverride(chainDb, trie.NewDatabaseWithConfig(chainDb, &trie.Config{Scheme: scheme}), MakeGenesis(ctx), nil, nil)
 	if err != nil {
 		Fatalf("%v", err)
 	}
@@ -2034,6 +2153,11 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		TrieTimeLimit:       ethconfig.Defaults.TrieTimeout,
 		SnapshotLimit:       ethconfig.Defaults.SnapshotCache,
 		Preimages:           ctx.GlobalBool(CachePreimagesFlag.Name),
+		StateScheme:         scheme,
+		StateHistory:        ctx.GlobalUint64(StateHistoryFlag.Name),
+	}
+	if cache.TrieDirtyDisabled && scheme == rawdb.PathScheme {
+		Fatalf("--%s=archive is not supported by the path-based state scheme", GCModeFlag.Name)
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
@@ -2059,6 +2183,16 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	return chain, chainDb
 }
 
+// ParseStateScheme resolves the state scheme to use from the command line flag
+// and the scheme of the state already persisted in the database.
+func ParseStateScheme(ctx *cli.Context, disk ethdb.Database) string {
+	scheme, err := rawdb.ParseStateScheme(ctx.GlobalString(StateSchemeFlag.Name), disk)
+	if err != nil {
+		Fatalf("%v", err)
+	}
+	return scheme
+}
+
 // MakeConsolePreloads retrieves the absolute paths for the console JavaScript
 // scripts to preload before starting.
 func MakeConsolePreloads(ctx *cli.Context) []string {
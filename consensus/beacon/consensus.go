@@ -263,6 +263,15 @@ func (beacon *Beacon) Prepare(chain consensus.ChainHeaderReader, header *types.H
 	return nil
 }
 
+// VerifyState implements consensus.StateVerifier, delegating the verification of
+// the pre-merge headers to the eth1 engine if it has state dependent fields.
+func (beacon *Beacon) VerifyState(chain consensus.ChainHeaderReader, header *types.Header) error {
+	if verifier, ok := beacon.ethone.(consensus.StateVerifier); ok && !beacon.IsPoSHeader(header) {
+		return verifier.VerifyState(chain, header)
+	}
+	return nil
+}
+
 // Finalize implements consensus.Engine, setting the final state on the header
 func (beacon *Beacon) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
 	// Finalize is different with Prepare, it can be used in both block generation
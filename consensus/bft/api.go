@@ -0,0 +1,77 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// API is a user facing RPC API to allow querying the validator set and the
+// progress of the byzantine fault tolerant consensus.
+type API struct {
+	chain consensus.ChainHeaderReader
+	bft   *BFT
+}
+
+// GetValidators retrieves the list of validators at the specified block.
+func (api *API) GetValidators(number *rpc.BlockNumber) ([]common.Address, error) {
+	// Retrieve the requested block number (or current if none requested)
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	// Ensure we have an actually valid block and return the validators from it
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	return extra.Validators, nil
+}
+
+// GetValidatorsAtHash retrieves the list of validators at the specified block.
+func (api *API) GetValidatorsAtHash(hash common.Hash) ([]common.Address, error) {
+	header := api.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	return extra.Validators, nil
+}
+
+// Status retrieves the state of the consensus round in progress.
+func (api *API) Status() (*Status, error) {
+	api.bft.lock.RLock()
+	rounds := api.bft.rounds
+	api.bft.lock.RUnlock()
+
+	if rounds == nil {
+		return nil, errNotStarted
+	}
+	status := rounds.currentStatus()
+	return &status, nil
+}
@@ -0,0 +1,631 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bft implements a round based byzantine fault tolerant consensus engine
+// with immediate finality for permissioned networks.
+//
+// The blocks of every height are agreed on by a fixed set of validators, listed
+// in the extra-data of every header. In each round, a proposer picked round robin
+// among the validators broadcasts a block, the validators accept it with prepare
+// votes and commit to it with commit votes, once a quorum of them prepared it. A
+// quorum of commit signatures is included in the header, making the block final.
+// The commit signatures are the ones of exactly a quorum of validators, ordered as
+// the validator set, leaving as little room as possible for diverging encodings of
+// the same final block.
+// Rounds failing to commit in time are changed by a quorum of round change votes.
+//
+// The quorum is ceil(2N/3) votes of N validators, which amounts to 2F+1 votes
+// for N = 3F+1 validators tolerating F faulty ones.
+package bft
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+
+	extraVanity = 32 // Fixed number of extra-data prefix bytes reserved for validator vanity
+
+	defaultPeriod         = 1     // Default minimum number of seconds between blocks
+	defaultRequestTimeout = 10000 // Default milliseconds to wait for a round to commit
+)
+
+var (
+	uncleHash = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
+
+	blockDifficulty = big.NewInt(1) // Difficulty of every block, as there are no forks to choose from
+)
+
+// Various error messages to mark blocks invalid. These should be private to
+// prevent engine specific errors from being referenced in the remainder of the
+// codebase, inherently breaking if the engine is swapped out. Please put common
+// error types into the consensus package.
+var (
+	// errUnknownBlock is returned when the list of validators is requested for a
+	// block that is not part of the local blockchain.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errMissingVanity is returned if a block's extra-data section is shorter than
+	// 32 bytes, which is required to store the validator vanity.
+	errMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
+
+	// errInvalidExtra is returned if the consensus specific content of a block's
+	// extra-data section can't be decoded.
+	errInvalidExtra = errors.New("invalid consensus extra-data")
+
+	// errInvalidValidators is returned if a block's validator set is empty or not
+	// in strictly ascending order.
+	errInvalidValidators = errors.New("invalid validator set")
+
+	// errMismatchingValidators is returned if a block's validator set differs from
+	// the one of its parent.
+	errMismatchingValidators = errors.New("mismatching validator set")
+
+	// errInvalidNonce is returned if a block's nonce is non-zero.
+	errInvalidNonce = errors.New("non-zero nonce")
+
+	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
+	errInvalidMixDigest = errors.New("non-zero mix digest")
+
+	// errInvalidUncleHash is returned if a block contains an non-empty uncle list.
+	errInvalidUncleHash = errors.New("non empty uncle hash")
+
+	// errInvalidDifficulty is returned if the difficulty of a block is not 1.
+	errInvalidDifficulty = errors.New("invalid difficulty")
+
+	// errInvalidTimestamp is returned if the timestamp of a block is lower than
+	// the previous block's timestamp + the minimum block period.
+	errInvalidTimestamp = errors.New("invalid timestamp")
+
+	// errUnauthorizedProposer is returned if a block is sealed by a non-validator.
+	errUnauthorizedProposer = errors.New("unauthorized proposer")
+
+	// errUnauthorizedValidator is returned if a vote is cast, or a block sealing
+	// is attempted by a non-validator.
+	errUnauthorizedValidator = errors.New("unauthorized validator")
+
+	// errInvalidCommittedSeal is returned if a committed seal is not signed by the
+	// validator casting it.
+	errInvalidCommittedSeal = errors.New("invalid committed seal")
+
+	// errInsufficientCommittedSeals is returned if a block doesn't contain the
+	// committed seals of a quorum of validators.
+	errInsufficientCommittedSeals = errors.New("insufficient committed seals")
+
+	// errNonCanonicalCommittedSeals is returned if a block's committed seals are
+	// not ordered as the validator set, or there are more of them than a quorum.
+	errNonCanonicalCommittedSeals = errors.New("non-canonical committed seals")
+
+	// errInvalidMessage is returned if a consensus message can't be decoded.
+	errInvalidMessage = errors.New("invalid consensus message")
+
+	// errNotStarted is returned if blocks are attempted to be sealed before the
+	// engine is started.
+	errNotStarted = errors.New("bft engine not started")
+)
+
+// SignerFn hashes and signs the data to be signed by a backing account.
+type SignerFn func(signer accounts.Account, mimeType string, message []byte) ([]byte, error)
+
+// Chain is the blockchain the engine agrees on the blocks of, following its head
+// and inserting the blocks committed by remote proposers.
+type Chain interface {
+	consensus.ChainHeaderReader
+
+	// SubscribeChainHeadEvent registers a subscription for new chain heads.
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+
+	// InsertChain imports committed blocks into the chain.
+	InsertChain(chain types.Blocks) (int, error)
+}
+
+// Broadcaster relays the consensus messages to the other validators.
+type Broadcaster interface {
+	// Broadcast sends a consensus message to all the connected peers.
+	Broadcast(payload []byte)
+}
+
+// BFT is the round based byzantine fault tolerant consensus engine.
+type BFT struct {
+	config *params.BFTConfig // Consensus engine configuration parameters
+
+	signatures *lru.ARCCache // Proposers of recent blocks to speed up verification
+
+	signer common.Address // Ethereum address of the signing key
+	signFn SignerFn       // Signer function to authorize hashes with
+	lock   sync.RWMutex   // Protects the signer and the rounds fields
+
+	rounds *roundManager // Consensus state machine, nil until started
+}
+
+// New creates a BFT consensus engine. The validator set is taken from the
+// genesis extra-data.
+func New(config *params.BFTConfig) *BFT {
+	// Set any missing consensus parameters to their defaults
+	conf := *config
+	if conf.Period == 0 {
+		conf.Period = defaultPeriod
+	}
+	if conf.RequestTimeout == 0 {
+		conf.RequestTimeout = defaultRequestTimeout
+	}
+	signatures, _ := lru.NewARC(inmemorySignatures)
+
+	return &BFT{
+		config:     &conf,
+		signatures: signatures,
+	}
+}
+
+// Start launches the consensus state machine following the given chain and
+// exchanging consensus messages through the broadcaster.
+func (b *BFT) Start(chain Chain, broadcaster Broadcaster) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.rounds != nil {
+		return
+	}
+	b.rounds = newRoundManager(b, chain, broadcaster)
+	b.rounds.start()
+}
+
+// Authorize injects a private key into the consensus engine to propose and vote
+// on blocks with.
+func (b *BFT) Authorize(signer common.Address, signFn SignerFn) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.signer = signer
+	b.signFn = signFn
+}
+
+// credentials returns the signing key injected into the consensus engine.
+func (b *BFT) credentials() (common.Address, SignerFn) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	return b.signer, b.signFn
+}
+
+// HandleMessage validates a consensus message received from a remote peer and
+// queues it for processing. An error is returned if the message is invalid and
+// should not be relayed any further.
+func (b *BFT) HandleMessage(payload []byte) error {
+	b.lock.RLock()
+	rounds := b.rounds
+	b.lock.RUnlock()
+
+	if rounds == nil {
+		return errNotStarted
+	}
+	msg, err := decodeMessage(payload)
+	if err != nil {
+		return err
+	}
+	return rounds.deliver(msg)
+}
+
+// Author implements consensus.Engine, returning the Ethereum address recovered
+// from the proposer seal in the header's extra-data section.
+func (b *BFT) Author(header *types.Header) (common.Address, error) {
+	return b.proposer(header)
+}
+
+// proposer extracts the address of the validator that proposed the block.
+func (b *BFT) proposer(header *types.Header) (common.Address, error) {
+	// If the signature's already cached, return that
+	hash := header.Hash()
+	if address, known := b.signatures.Get(hash); known {
+		return address.(common.Address), nil
+	}
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	signer, err := recoverSigner(sealData(header), extra.Seal)
+	if err != nil {
+		return common.Address{}, err
+	}
+	b.signatures.Add(hash, signer)
+	return signer, nil
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules.
+func (b *BFT) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	return b.verifyHeader(chain, header, nil, true)
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
+// method returns a quit channel to abort the operations and a results channel to
+// retrieve the async verifications (the order is that of the input slice).
+func (b *BFT) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		for i, header := range headers {
+			err := b.verifyHeader(chain, header, headers[:i], true)
+
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyHeader checks whether a header conforms to the consensus rules. The
+// caller may optionally pass in a batch of parents (ascending order) to avoid
+// looking those up from the database. Proposals not yet voted on are verified
+// without their committed seals.
+func (b *BFT) verifyHeader(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header, committed bool) error {
+	if header.Number == nil {
+		return errUnknownBlock
+	}
+	number := header.Number.Uint64()
+
+	// Don't waste time checking blocks from the future
+	if header.Time > uint64(time.Now().Unix()) {
+		return consensus.ErrFutureBlock
+	}
+	// Ensure that the extra-data contains a valid validator set
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return err
+	}
+	if len(extra.Validators) == 0 {
+		return errInvalidValidators
+	}
+	for i := 1; i < len(extra.Validators); i++ {
+		if bytes.Compare(extra.Validators[i-1][:], extra.Validators[i][:]) >= 0 {
+			return errInvalidValidators
+		}
+	}
+	// Nonces and mix digests are unused, enforce them to be zero
+	if header.Nonce != (types.BlockNonce{}) {
+		return errInvalidNonce
+	}
+	if header.MixDigest != (common.Hash{}) {
+		return errInvalidMixDigest
+	}
+	// Ensure that the block doesn't contain any uncles which are meaningless in BFT
+	if header.UncleHash != uncleHash {
+		return errInvalidUncleHash
+	}
+	// Ensure that the block's difficulty is fixed, there are no forks to choose from
+	if number > 0 && (header.Difficulty == nil || header.Difficulty.Cmp(blockDifficulty) != 0) {
+		return errInvalidDifficulty
+	}
+	// Verify that the gas limit is <= 2^63-1
+	if header.GasLimit > params.MaxGasLimit {
+		return fmt.Errorf("invalid gasLimit: have %v, max %v", header.GasLimit, params.MaxGasLimit)
+	}
+	// If all checks passed, validate any special fields for hard forks
+	if err := misc.VerifyForkHashes(chain.Config(), header, false); err != nil {
+		return err
+	}
+	// All basic checks passed, verify cascading fields
+	return b.verifyCascadingFields(chain, header, extra, parents, committed)
+}
+
+// verifyCascadingFields verifies all the header fields that are not standalone,
+// rather depend on a batch of previous headers. The caller may optionally pass
+// in a batch of parents (ascending order) to avoid looking those up from the
+// database. This is useful for concurrently verifying a batch of new headers.
+func (b *BFT) verifyCascadingFields(chain consensus.ChainHeaderReader, header *types.Header, extra *Extra, parents []*types.Header, committed bool) error {
+	// The genesis block is the always valid dead-end
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	// Ensure that the block's timestamp isn't too close to its parent
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+	if parent.Time+b.config.Period > header.Time {
+		return errInvalidTimestamp
+	}
+	// Verify that the gasUsed is <= gasLimit
+	if header.GasUsed > header.GasLimit {
+		return fmt.Errorf("invalid gasUsed: have %d, gasLimit %d", header.GasUsed, header.GasLimit)
+	}
+	if !chain.Config().IsLondon(header.Number) {
+		// Verify BaseFee not present before EIP-1559 fork.
+		if header.BaseFee != nil {
+			return fmt.Errorf("invalid baseFee before fork: have %d, want <nil>", header.BaseFee)
+		}
+		if err := misc.VerifyGaslimit(parent.GasLimit, header.GasLimit); err != nil {
+			return err
+		}
+	} else if err := misc.VerifyEip1559Header(chain.Config(), parent, header); err != nil {
+		// Verify the header's EIP-1559 attributes.
+		return err
+	}
+	// Ensure the validator set is carried over from the parent
+	parentExtra, err := ExtractExtra(parent)
+	if err != nil {
+		return err
+	}
+	if !equalValidators(extra.Validators, parentExtra.Validators) {
+		return errMismatchingValidators
+	}
+	// All basic checks passed, verify the seals and return
+	return b.verifySeals(header, extra, committed)
+}
+
+// verifySeals checks whether the block is proposed by a validator and, unless
+// it's a proposal not yet voted on, whether it's committed by a quorum of them,
+// with the committed seals in canonical form.
+func (b *BFT) verifySeals(header *types.Header, extra *Extra, committed bool) error {
+	proposer, err := b.proposer(header)
+	if err != nil {
+		return err
+	}
+	if !containsValidator(extra.Validators, proposer) {
+		return errUnauthorizedProposer
+	}
+	if !committed {
+		return nil
+	}
+	var (
+		data = commitData(proposalHash(header))
+		last = -1
+	)
+	for _, seal := range extra.CommittedSeals {
+		signer, err := recoverSigner(data, seal)
+		if err != nil {
+			return errInvalidCommittedSeal
+		}
+		index := validatorIndex(extra.Validators, signer)
+		if index < 0 {
+			return errUnauthorizedValidator
+		}
+		// Seals must be ordered as the validator set, which rules out duplicates
+		if index <= last {
+			return errNonCanonicalCommittedSeals
+		}
+		last = index
+	}
+	switch quorum := quorumSize(len(extra.Validators)); {
+	case len(extra.CommittedSeals) < quorum:
+		return errInsufficientCommittedSeals
+	case len(extra.CommittedSeals) > quorum:
+		return errNonCanonicalCommittedSeals
+	}
+	return nil
+}
+
+// VerifyUncles implements consensus.Engine, always returning an error for any
+// uncles as this consensus mechanism doesn't permit uncles.
+func (b *BFT) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errors.New("uncles not allowed")
+	}
+	return nil
+}
+
+// Prepare implements consensus.Engine, preparing all the consensus fields of the
+// header for running the transactions on top.
+func (b *BFT) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	number := header.Number.Uint64()
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	parentExtra, err := ExtractExtra(parent)
+	if err != nil {
+		return err
+	}
+	header.Nonce = types.BlockNonce{}
+	header.MixDigest = common.Hash{}
+	header.Difficulty = new(big.Int).Set(blockDifficulty)
+
+	// Carry over the validator set, leaving the seals empty
+	if len(header.Extra) < extraVanity {
+		header.Extra = append(header.Extra, make([]byte, extraVanity-len(header.Extra))...)
+	}
+	if header.Extra, err = EncodeExtra(header.Extra[:extraVanity], &Extra{Validators: parentExtra.Validators}); err != nil {
+		return err
+	}
+	// Ensure the timestamp has the correct delay
+	header.Time = parent.Time + b.config.Period
+	if header.Time < uint64(time.Now().Unix()) {
+		header.Time = uint64(time.Now().Unix())
+	}
+	return nil
+}
+
+// Finalize implements consensus.Engine, ensuring no uncles are set, nor block
+// rewards given.
+func (b *BFT) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+	// No block rewards in BFT, so the state remains as is and uncles are dropped
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+	header.UncleHash = types.CalcUncleHash(nil)
+}
+
+// FinalizeAndAssemble implements consensus.Engine, ensuring no uncles are set,
+// nor block rewards given, and returns the final block.
+func (b *BFT) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	// Finalize block
+	b.Finalize(chain, header, state, txs, uncles)
+
+	// Assemble and return the final block for sealing
+	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil)), nil
+}
+
+// Seal implements consensus.Engine, signing the block as its proposer and handing
+// it to the consensus state machine. The block is proposed once the validator is
+// the proposer of the current round, and delivered on the results channel once
+// a quorum of validators committed to it.
+func (b *BFT) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	header := block.Header()
+
+	// Sealing the genesis block is not supported
+	if header.Number.Uint64() == 0 {
+		return errUnknownBlock
+	}
+	// Don't hold the signer fields for the entire sealing procedure
+	b.lock.RLock()
+	signer, signFn, rounds := b.signer, b.signFn, b.rounds
+	b.lock.RUnlock()
+
+	if rounds == nil {
+		return errNotStarted
+	}
+	// Bail out if we're unauthorized to propose a block
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return err
+	}
+	if !containsValidator(extra.Validators, signer) {
+		return errUnauthorizedValidator
+	}
+	// Sign all the things!
+	sig, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeBFT, sealData(header))
+	if err != nil {
+		return err
+	}
+	extra.Seal = sig
+	if header.Extra, err = EncodeExtra(header.Extra[:extraVanity], extra); err != nil {
+		return err
+	}
+	// Wait until sealing is terminated or the block is due, then propose it
+	delay := time.Until(time.Unix(int64(header.Time), 0))
+	go func() {
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+		rounds.propose(block.WithSeal(header), results)
+	}()
+	return nil
+}
+
+// SealHash returns the hash of a block prior to it being sealed.
+func (b *BFT) SealHash(header *types.Header) common.Hash {
+	return SealHash(header)
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. It returns the fixed
+// difficulty of every block.
+func (b *BFT) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	return new(big.Int).Set(blockDifficulty)
+}
+
+// Close implements consensus.Engine, terminating the consensus state machine.
+func (b *BFT) Close() error {
+	b.lock.RLock()
+	rounds := b.rounds
+	b.lock.RUnlock()
+
+	if rounds != nil {
+		rounds.stop()
+	}
+	return nil
+}
+
+// APIs implements consensus.Engine, returning the user facing RPC API to query
+// the validator set and the consensus state.
+func (b *BFT) APIs(chain consensus.ChainHeaderReader) []rpc.API {
+	return []rpc.API{{
+		Namespace: "bft",
+		Version:   "1.0",
+		Service:   &API{chain: chain, bft: b},
+		Public:    false,
+	}}
+}
+
+// sealData returns the data the proposer of a block signs.
+func sealData(header *types.Header) []byte {
+	blob, _ := rlp.EncodeToBytes(filteredHeader(header, false))
+	return blob
+}
+
+// quorumSize returns the number of votes needed among the given number of
+// validators, ceil(2N/3).
+func quorumSize(validators int) int {
+	return (2*validators + 2) / 3
+}
+
+// faultTolerance returns the number of faulty validators tolerated among the
+// given number of validators.
+func faultTolerance(validators int) int {
+	return (validators - 1) / 3
+}
+
+// containsValidator reports whether the address is in the validator set.
+func containsValidator(validators []common.Address, addr common.Address) bool {
+	for _, validator := range validators {
+		if validator == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// validatorIndex returns the position of the address in the validator set, or
+// -1 if it's not a validator.
+func validatorIndex(validators []common.Address, addr common.Address) int {
+	for i, validator := range validators {
+		if validator == addr {
+			return i
+		}
+	}
+	return -1
+}
+
+// equalValidators reports whether two validator sets are the same.
+func equalValidators(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
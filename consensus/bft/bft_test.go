@@ -0,0 +1,476 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// testNode is a validator of an in-memory test network, proposing a block on
+// top of every new chain head like the miner does.
+type testNode struct {
+	key    *ecdsa.PrivateKey
+	addr   common.Address
+	engine *BFT
+	chain  *core.BlockChain
+	net    *testNetwork
+	online bool
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// testNetwork delivers the consensus messages of every validator to the other
+// online ones, asynchronously as the p2p layer does.
+type testNetwork struct {
+	nodes []*testNode
+}
+
+// testBroadcaster is the view of the test network of a single validator.
+type testBroadcaster struct {
+	net  *testNetwork
+	self *testNode
+}
+
+func (b *testBroadcaster) Broadcast(payload []byte) {
+	for _, node := range b.net.nodes {
+		if node != b.self && node.online {
+			go node.engine.HandleMessage(payload)
+		}
+	}
+}
+
+// newTestNetwork creates a network of validators sharing the same genesis, with
+// the given validators offline.
+func newTestNetwork(t *testing.T, validators int, timeout uint64, offline ...int) *testNetwork {
+	t.Helper()
+
+	keys := make([]*ecdsa.PrivateKey, validators)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := crypto.PubkeyToAddress(keys[i].PublicKey), crypto.PubkeyToAddress(keys[j].PublicKey)
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+	addrs := make([]common.Address, validators)
+	for i, key := range keys {
+		addrs[i] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = nil
+	config.BFT = &params.BFTConfig{Period: 1, RequestTimeout: timeout}
+
+	extra, err := EncodeExtra(nil, &Extra{Validators: addrs})
+	if err != nil {
+		t.Fatalf("failed to encode genesis extra-data: %v", err)
+	}
+	genesis := &core.Genesis{
+		Config:     &config,
+		ExtraData:  extra,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: common.Big1,
+		Timestamp:  uint64(time.Now().Unix()),
+	}
+	net := new(testNetwork)
+	for i, key := range keys {
+		db := rawdb.NewMemoryDatabase()
+		genesis.MustCommit(db)
+
+		engine := New(config.BFT)
+		chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		node := &testNode{
+			key:    key,
+			addr:   addrs[i],
+			engine: engine,
+			chain:  chain,
+			net:    net,
+			online: true,
+			quit:   make(chan struct{}),
+			done:   make(chan struct{}),
+		}
+		for _, index := range offline {
+			if index == i {
+				node.online = false
+			}
+		}
+		net.nodes = append(net.nodes, node)
+	}
+	for _, node := range net.nodes {
+		if node.online {
+			node.start(t)
+		}
+	}
+	return net
+}
+
+// start launches the consensus engine of the validator and its block producer.
+func (n *testNode) start(t *testing.T) {
+	key := n.key
+	n.engine.Authorize(n.addr, func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+		return crypto.Sign(crypto.Keccak256(data), key)
+	})
+	n.engine.Start(n.chain, &testBroadcaster{net: n.net, self: n})
+
+	go func() {
+		defer close(n.done)
+
+		heads := make(chan core.ChainHeadEvent, 16)
+		sub := n.chain.SubscribeChainHeadEvent(heads)
+		defer sub.Unsubscribe()
+
+		results := make(chan *types.Block, 10)
+		stop := n.seal(t, results)
+		for {
+			select {
+			case <-heads:
+				close(stop)
+				stop = n.seal(t, results)
+			case block := <-results:
+				if _, err := n.chain.InsertChain(types.Blocks{block}); err != nil {
+					t.Errorf("failed to insert sealed block: %v", err)
+				}
+			case <-n.quit:
+				close(stop)
+				return
+			}
+		}
+	}()
+}
+
+// seal assembles an empty block on top of the current head and hands it to the
+// consensus engine.
+func (n *testNode) seal(t *testing.T, results chan *types.Block) chan struct{} {
+	parent := n.chain.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   parent.GasLimit(),
+		Coinbase:   n.addr,
+		BaseFee:    misc.CalcBaseFee(n.chain.Config(), parent.Header()),
+	}
+	stop := make(chan struct{})
+	if err := n.engine.Prepare(n.chain, header); err != nil {
+		t.Errorf("failed to prepare header: %v", err)
+		return stop
+	}
+	statedb, err := n.chain.StateAt(parent.Root())
+	if err != nil {
+		t.Errorf("failed to retrieve parent state: %v", err)
+		return stop
+	}
+	block, err := n.engine.FinalizeAndAssemble(n.chain, header, statedb, nil, nil, nil)
+	if err != nil {
+		t.Errorf("failed to assemble block: %v", err)
+		return stop
+	}
+	if err := n.engine.Seal(n.chain, block, results, stop); err != nil {
+		t.Errorf("failed to seal block: %v", err)
+	}
+	return stop
+}
+
+// stop terminates all the validators of the network.
+func (net *testNetwork) stop() {
+	for _, node := range net.nodes {
+		if node.online {
+			close(node.quit)
+			<-node.done
+			node.engine.Close()
+		}
+		node.chain.Stop()
+	}
+}
+
+// waitHeight waits until all the online validators reached the given height.
+func (net *testNetwork) waitHeight(t *testing.T, height uint64, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for _, node := range net.nodes {
+		if !node.online {
+			continue
+		}
+		for node.chain.CurrentBlock().NumberU64() < height {
+			if time.Now().After(deadline) {
+				t.Fatalf("validator %x stuck at height %d, want %d", node.addr, node.chain.CurrentBlock().NumberU64(), height)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// Tests that a network of validators agrees on the same blocks, each committed
+// by a quorum of validators.
+func TestCommit(t *testing.T) {
+	net := newTestNetwork(t, 4, 2000)
+	defer net.stop()
+
+	net.waitHeight(t, 3, 20*time.Second)
+
+	ref := net.nodes[0].chain
+	for _, node := range net.nodes[1:] {
+		for number := uint64(1); number <= 3; number++ {
+			if have, want := node.chain.GetBlockByNumber(number).Hash(), ref.GetBlockByNumber(number).Hash(); have != want {
+				t.Fatalf("block %d mismatch: have %x, want %x", number, have, want)
+			}
+		}
+	}
+	for number := uint64(1); number <= 3; number++ {
+		header := ref.GetHeaderByNumber(number)
+		if err := net.nodes[0].engine.VerifyHeader(ref, header, true); err != nil {
+			t.Fatalf("block %d: failed to verify header: %v", number, err)
+		}
+		extra, err := ExtractExtra(header)
+		if err != nil {
+			t.Fatalf("block %d: failed to extract extra-data: %v", number, err)
+		}
+		if len(extra.CommittedSeals) != quorumSize(4) {
+			t.Fatalf("block %d: committed seal count mismatch: have %d, want %d", number, len(extra.CommittedSeals), quorumSize(4))
+		}
+	}
+}
+
+// Tests that the validators move to the next round and agree on a block if the
+// proposer of the first round is offline.
+func TestRoundChange(t *testing.T) {
+	// The proposer of the first round of block 1 is the second validator
+	net := newTestNetwork(t, 4, 200, 1)
+	defer net.stop()
+
+	net.waitHeight(t, 1, 20*time.Second)
+
+	header := net.nodes[0].chain.GetHeaderByNumber(1)
+	proposer, err := net.nodes[0].engine.Author(header)
+	if err != nil {
+		t.Fatalf("failed to retrieve proposer: %v", err)
+	}
+	if proposer == net.nodes[1].addr {
+		t.Fatalf("block proposed by offline validator %x", proposer)
+	}
+}
+
+// Tests that headers without a quorum of committed seals, with seals of
+// non-validators, or with seals not in canonical form are rejected.
+func TestVerifyCommittedSeals(t *testing.T) {
+	net := newTestNetwork(t, 4, 2000)
+	defer net.stop()
+
+	net.waitHeight(t, 1, 20*time.Second)
+
+	var (
+		chain  = net.nodes[0].chain
+		engine = net.nodes[0].engine
+		header = chain.GetHeaderByNumber(1)
+	)
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		t.Fatalf("failed to extract extra-data: %v", err)
+	}
+	tamper := func(seals [][]byte) *types.Header {
+		cpy := types.CopyHeader(header)
+		ext := *extra
+		ext.CommittedSeals = seals
+		if cpy.Extra, err = EncodeExtra(header.Extra[:extraVanity], &ext); err != nil {
+			t.Fatalf("failed to encode extra-data: %v", err)
+		}
+		return cpy
+	}
+	if err := engine.VerifyHeader(chain, tamper(extra.CommittedSeals[:2]), true); !errors.Is(err, errInsufficientCommittedSeals) {
+		t.Errorf("missing seals: error mismatch: have %v, want %v", err, errInsufficientCommittedSeals)
+	}
+	outsider, _ := crypto.GenerateKey()
+	seal, _ := crypto.Sign(crypto.Keccak256(commitData(proposalHash(header))), outsider)
+	if err := engine.VerifyHeader(chain, tamper(append(extra.CommittedSeals[:2], seal)), true); !errors.Is(err, errUnauthorizedValidator) {
+		t.Errorf("outsider seal: error mismatch: have %v, want %v", err, errUnauthorizedValidator)
+	}
+	if err := engine.VerifyHeader(chain, tamper(append(extra.CommittedSeals[:2], extra.CommittedSeals[0])), true); !errors.Is(err, errNonCanonicalCommittedSeals) {
+		t.Errorf("duplicate seal: error mismatch: have %v, want %v", err, errNonCanonicalCommittedSeals)
+	}
+	reversed := [][]byte{extra.CommittedSeals[2], extra.CommittedSeals[1], extra.CommittedSeals[0]}
+	if err := engine.VerifyHeader(chain, tamper(reversed), true); !errors.Is(err, errNonCanonicalCommittedSeals) {
+		t.Errorf("reversed seals: error mismatch: have %v, want %v", err, errNonCanonicalCommittedSeals)
+	}
+	// Sign with the validators not sealing the block to exceed the quorum
+	var (
+		all  = make([][]byte, 0, len(net.nodes))
+		data = crypto.Keccak256(commitData(proposalHash(header)))
+	)
+	for _, node := range net.nodes {
+		seal, _ := crypto.Sign(data, node.key)
+		all = append(all, seal)
+	}
+	if err := engine.VerifyHeader(chain, tamper(all), true); !errors.Is(err, errNonCanonicalCommittedSeals) {
+		t.Errorf("excess seals: error mismatch: have %v, want %v", err, errNonCanonicalCommittedSeals)
+	}
+}
+
+// signMessage signs a consensus message with the given validator key and encodes
+// it for delivery.
+func signMessage(t *testing.T, key *ecdsa.PrivateKey, msg *message) []byte {
+	t.Helper()
+
+	sig, err := crypto.Sign(crypto.Keccak256(msg.signingData()), key)
+	if err != nil {
+		t.Fatalf("failed to sign message: %v", err)
+	}
+	msg.Signature = sig
+	payload, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+	return payload
+}
+
+// Tests that messages of rounds too far ahead are rejected, and that the final
+// block is only imported from the proposer of its round.
+func TestMessageFiltering(t *testing.T) {
+	// Run the state machine of a single passive validator, driven by the messages
+	// signed in the test
+	net := newTestNetwork(t, 4, 60000, 0, 1, 2, 3)
+	defer net.stop()
+
+	node := net.nodes[0]
+	node.engine.Start(node.chain, &testBroadcaster{net: net, self: node})
+	defer node.engine.Close()
+
+	for start := time.Now(); node.engine.rounds.currentStatus().Sequence != 1; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("consensus state machine not started")
+		}
+	}
+	tests := []struct {
+		sequence, round uint64
+		err             error
+	}{
+		{1, maxFutureRounds, nil},
+		{1, maxFutureRounds + 1, errFutureRound},
+		{1 + maxFutureSequences, maxFutureRounds, nil},
+		{1 + maxFutureSequences, maxFutureRounds + 1, errFutureRound},
+		{2 + maxFutureSequences, 0, errFutureSequence},
+	}
+	for i, tt := range tests {
+		payload := signMessage(t, net.nodes[1].key, &message{Code: msgRoundChange, Sequence: tt.sequence, Round: tt.round})
+		if err := node.engine.HandleMessage(payload); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Assemble a block of the first round of sequence 1, committed by all the
+	// validators
+	var (
+		parent = node.chain.CurrentBlock()
+		header = &types.Header{
+			ParentHash:  parent.Hash(),
+			Number:      big.NewInt(1),
+			GasLimit:    parent.GasLimit(),
+			BaseFee:     misc.CalcBaseFee(node.chain.Config(), parent.Header()),
+			Root:        parent.Root(),
+			UncleHash:   uncleHash,
+			TxHash:      types.EmptyRootHash,
+			ReceiptHash: types.EmptyRootHash,
+		}
+		proposer = net.nodes[1] // validators[(sequence + round) % 4]
+	)
+	if err := node.engine.Prepare(node.chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	extra, _ := ExtractExtra(header)
+	extra.Seal, _ = crypto.Sign(crypto.Keccak256(sealData(header)), proposer.key)
+	header.Extra, _ = EncodeExtra(header.Extra[:extraVanity], extra)
+	for _, validator := range net.nodes[:quorumSize(4)] {
+		seal, _ := crypto.Sign(crypto.Keccak256(commitData(proposalHash(header))), validator.key)
+		extra.CommittedSeals = append(extra.CommittedSeals, seal)
+	}
+	header.Extra, _ = EncodeExtra(header.Extra[:extraVanity], extra)
+	block := types.NewBlockWithHeader(header)
+	payload, _ := rlp.EncodeToBytes(block)
+
+	time.Sleep(time.Until(time.Unix(int64(header.Time), 0)))
+	if err := node.engine.VerifyHeader(node.chain, header, true); err != nil {
+		t.Fatalf("failed to verify final block: %v", err)
+	}
+	final := func(sender *testNode) {
+		msg := &message{Code: msgFinal, Sequence: 1, Digest: proposalHash(header), Block: payload}
+		if err := node.engine.HandleMessage(signMessage(t, sender.key, msg)); err != nil {
+			t.Fatalf("failed to deliver final block: %v", err)
+		}
+	}
+	// Final blocks of other validators should be ignored
+	final(net.nodes[2])
+	time.Sleep(100 * time.Millisecond)
+	if head := node.chain.CurrentBlock().NumberU64(); head != 0 {
+		t.Fatalf("final block of non-proposer imported: head %d", head)
+	}
+	// The final block of the proposer should be imported
+	final(proposer)
+	for start := time.Now(); node.chain.CurrentBlock().Hash() != block.Hash(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("final block of proposer not imported")
+		}
+	}
+}
+
+// Tests that the messages of future sequences are only kept for the next one, and
+// that a single validator can't fill up the backlog.
+func TestBacklogLimits(t *testing.T) {
+	net := newTestNetwork(t, 4, 60000, 0, 1, 2, 3)
+	defer net.stop()
+
+	node := net.nodes[0]
+	m := newRoundManager(node.engine, node.chain, &testBroadcaster{net: net, self: node})
+	m.timer = time.NewTimer(time.Hour)
+	defer m.timer.Stop()
+	m.startSequence(node.chain.CurrentHeader())
+
+	spammer, honest := net.nodes[1].addr, net.nodes[2].addr
+	for i := 0; i < 2*maxSenderBacklog; i++ {
+		m.handleMessage(&message{Code: msgPrepare, Sequence: 2, Round: uint64(i) % maxFutureRounds, sender: spammer})
+	}
+	m.handleMessage(&message{Code: msgPrepare, Sequence: 2, Round: maxFutureRounds + 1, sender: honest})
+	m.handleMessage(&message{Code: msgPrepare, Sequence: 2 + maxFutureSequences, sender: honest})
+	m.handleMessage(&message{Code: msgPrepare, Sequence: 2, sender: honest})
+
+	if have, want := m.backlogged[spammer], maxSenderBacklog; have != want {
+		t.Errorf("spammer backlog mismatch: have %d, want %d", have, want)
+	}
+	if have, want := m.backlogged[honest], 1; have != want {
+		t.Errorf("honest backlog mismatch: have %d, want %d", have, want)
+	}
+	if have, want := len(m.backlog), maxSenderBacklog+1; have != want {
+		t.Errorf("backlog size mismatch: have %d, want %d", have, want)
+	}
+}
@@ -0,0 +1,716 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// maxBacklog is the maximum number of messages of future sequences kept until
+	// the local chain catches up with them.
+	maxBacklog = 1024
+
+	// maxSenderBacklog is the maximum number of messages of future sequences kept
+	// from a single validator, so that no validator can crowd out the others.
+	maxSenderBacklog = 64
+
+	// maxFutureSequences is the number of sequences ahead of the current one, whose
+	// messages are accepted. Validators further behind catch up via chain sync.
+	maxFutureSequences = 1
+
+	// maxFutureRounds is the number of rounds ahead of the current one, whose
+	// messages are accepted. Messages of rounds further ahead are dropped, bounding
+	// the votes kept for the current sequence.
+	maxFutureRounds = 10
+
+	// maxTimeoutShift is the maximum number of times the round timeout is doubled.
+	maxTimeoutShift = 8
+
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 16
+)
+
+var (
+	// errOldMessage is returned if a consensus message is received for a sequence
+	// that is already committed.
+	errOldMessage = errors.New("consensus message of past sequence")
+
+	// errFutureSequence is returned if a consensus message is received for a
+	// sequence too far ahead of the current one.
+	errFutureSequence = errors.New("consensus message of future sequence")
+
+	// errFutureRound is returned if a consensus message is received for a round
+	// too far ahead of the current one.
+	errFutureRound = errors.New("consensus message of future round")
+
+	// errStopped is returned if a consensus message is delivered to a stopped
+	// engine.
+	errStopped = errors.New("bft engine stopped")
+
+	// errInvalidProposal is returned if a proposed block doesn't extend the local
+	// chain head or its body doesn't match its header.
+	errInvalidProposal = errors.New("invalid proposal")
+)
+
+// roundStatus is the progress of the current round.
+type roundStatus uint8
+
+const (
+	statusAccepting   roundStatus = iota // Waiting for the proposal of the round
+	statusPreprepared                    // Proposal accepted, waiting for a quorum of prepares
+	statusPrepared                       // Proposal prepared, waiting for a quorum of commits
+	statusCommitted                      // Proposal committed, waiting for the final block
+)
+
+// String implements the stringer interface.
+func (s roundStatus) String() string {
+	switch s {
+	case statusAccepting:
+		return "accepting"
+	case statusPreprepared:
+		return "preprepared"
+	case statusPrepared:
+		return "prepared"
+	case statusCommitted:
+		return "committed"
+	default:
+		return "unknown"
+	}
+}
+
+// Status is the state of the consensus round in progress.
+type Status struct {
+	Sequence   uint64           `json:"sequence"`
+	Round      uint64           `json:"round"`
+	Proposer   common.Address   `json:"proposer"`
+	State      string           `json:"state"`
+	Validators []common.Address `json:"validators"`
+}
+
+// proposal is a local block waiting to be proposed, along with the channel of
+// the miner to hand it back on once committed.
+type proposal struct {
+	block   *types.Block
+	results chan<- *types.Block
+}
+
+// messageSet is the set of votes cast in a round, one per validator.
+type messageSet map[common.Address]*message
+
+// roundManager is the consensus state machine agreeing on the block of every
+// sequence with the other validators.
+type roundManager struct {
+	bft         *BFT
+	chain       Chain
+	broadcaster Broadcaster
+
+	msgCh     chan *message
+	proposeCh chan *proposal
+	quit      chan struct{}
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+
+	// Fields below are owned by the loop goroutine
+	head         *types.Header          // Chain head the current sequence extends
+	sequence     uint64                 // Number of the block being agreed on
+	validators   []common.Address       // Validator set of the current sequence
+	round        uint64                 // Round in progress
+	desiredRound uint64                 // Highest round voted to change to
+	state        roundStatus            // Progress of the current round
+	proposed     bool                   // Whether the local validator proposed in the current round
+	proposal     *types.Block           // Proposal accepted in the current round
+	locked       *types.Block           // Proposal prepared in the sequence, the only one accepted afterwards
+	pending      *proposal              // Latest local block to propose in the sequence
+	preprepares  map[uint64]*message    // Proposals of future rounds
+	prepares     map[uint64]messageSet  // Prepare votes by round
+	commits      map[uint64]messageSet  // Commit votes by round
+	roundChanges map[uint64]messageSet  // Round change votes by target round
+	backlog      []*message             // Messages of future sequences
+	backlogged   map[common.Address]int // Number of messages in the backlog by sender
+	timer        *time.Timer            // Round timeout
+
+	status Status       // Snapshot of the loop state for the API and message delivery
+	lock   sync.RWMutex // Protects the status field
+}
+
+// newRoundManager creates a consensus state machine following the given chain.
+func newRoundManager(bft *BFT, chain Chain, broadcaster Broadcaster) *roundManager {
+	return &roundManager{
+		bft:         bft,
+		chain:       chain,
+		broadcaster: broadcaster,
+		msgCh:       make(chan *message),
+		proposeCh:   make(chan *proposal),
+		quit:        make(chan struct{}),
+	}
+}
+
+// start launches the loop of the state machine.
+func (m *roundManager) start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// stop terminates the loop of the state machine.
+func (m *roundManager) stop() {
+	m.stopOnce.Do(func() { close(m.quit) })
+	m.wg.Wait()
+}
+
+// deliver queues a remote message for processing, after ensuring it belongs to
+// a sequence not yet committed, to a sequence and round not too far ahead of the
+// current ones, and it's sent by a validator.
+func (m *roundManager) deliver(msg *message) error {
+	m.lock.RLock()
+	sequence, round, validators := m.status.Sequence, m.status.Round, m.status.Validators
+	m.lock.RUnlock()
+
+	switch {
+	case msg.Sequence < sequence:
+		return errOldMessage
+	case msg.Sequence > sequence+maxFutureSequences:
+		return errFutureSequence
+	case msg.Sequence == sequence && msg.Round > round+maxFutureRounds:
+		return errFutureRound
+	case msg.Sequence > sequence && msg.Round > maxFutureRounds:
+		return errFutureRound
+	}
+	if !containsValidator(validators, msg.sender) {
+		return errUnauthorizedValidator
+	}
+	select {
+	case m.msgCh <- msg:
+		return nil
+	case <-m.quit:
+		return errStopped
+	}
+}
+
+// propose queues a local block for proposing.
+func (m *roundManager) propose(block *types.Block, results chan<- *types.Block) {
+	select {
+	case m.proposeCh <- &proposal{block: block, results: results}:
+	case <-m.quit:
+	}
+}
+
+// currentStatus returns the state of the consensus round in progress.
+func (m *roundManager) currentStatus() Status {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.status
+}
+
+// loop is the main event loop of the state machine, processing the messages,
+// the local proposals, the round timeouts and the chain head changes.
+func (m *roundManager) loop() {
+	defer m.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := m.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	m.timer = time.NewTimer(0)
+	defer m.timer.Stop()
+
+	m.startSequence(m.chain.CurrentHeader())
+	for {
+		select {
+		case ev := <-heads:
+			if ev.Block.NumberU64() >= m.sequence {
+				m.startSequence(ev.Block.Header())
+			}
+		case p := <-m.proposeCh:
+			m.handleProposal(p)
+
+		case msg := <-m.msgCh:
+			m.handleMessage(msg)
+
+		case <-m.timer.C:
+			m.handleTimeout()
+
+		case <-sub.Err():
+			return
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// startSequence starts agreeing on the block extending the given chain head.
+func (m *roundManager) startSequence(head *types.Header) {
+	extra, err := ExtractExtra(head)
+	if err != nil {
+		log.Error("Invalid chain head extra-data", "number", head.Number, "hash", head.Hash(), "err", err)
+		extra = new(Extra)
+	}
+	m.head, m.sequence, m.validators = head, head.Number.Uint64()+1, extra.Validators
+	m.desiredRound, m.locked = 0, nil
+	if m.pending != nil && m.pending.block.ParentHash() != head.Hash() {
+		m.pending = nil
+	}
+	m.preprepares = make(map[uint64]*message)
+	m.prepares = make(map[uint64]messageSet)
+	m.commits = make(map[uint64]messageSet)
+	m.roundChanges = make(map[uint64]messageSet)
+
+	m.startRound(0)
+
+	// Process the messages received early for the new sequence, keep the ones of
+	// later sequences
+	backlog := m.backlog
+	m.backlog, m.backlogged = nil, make(map[common.Address]int)
+	for _, msg := range backlog {
+		if msg.Sequence >= m.sequence {
+			m.handleMessage(msg)
+		}
+	}
+}
+
+// startRound moves the current sequence to the given round.
+func (m *roundManager) startRound(round uint64) {
+	m.round = round
+	if m.desiredRound < round {
+		m.desiredRound = round
+	}
+	m.state, m.proposed, m.proposal = statusAccepting, false, nil
+
+	// Drop the votes of the past rounds
+	for r := range m.preprepares {
+		if r < round {
+			delete(m.preprepares, r)
+		}
+	}
+	for _, votes := range []map[uint64]messageSet{m.prepares, m.commits, m.roundChanges} {
+		for r := range votes {
+			if r < round {
+				delete(votes, r)
+			}
+		}
+	}
+	m.resetTimer()
+	m.updateStatus()
+
+	log.Debug("Started consensus round", "sequence", m.sequence, "round", round, "proposer", m.proposer(round))
+
+	// Propose if it's our turn, or accept the proposal received early
+	m.tryPropose()
+	if msg := m.preprepares[round]; msg != nil {
+		m.handlePreprepare(msg)
+	}
+}
+
+// resetTimer restarts the timeout of the desired round. The first round is
+// timed from when its block is due.
+func (m *roundManager) resetTimer() {
+	shift := m.desiredRound
+	if shift > maxTimeoutShift {
+		shift = maxTimeoutShift
+	}
+	timeout := time.Duration(m.bft.config.RequestTimeout) * time.Millisecond << shift
+	if m.desiredRound == 0 {
+		timeout += time.Until(time.Unix(int64(m.head.Time+m.bft.config.Period), 0))
+	}
+	if !m.timer.Stop() {
+		select {
+		case <-m.timer.C:
+		default:
+		}
+	}
+	m.timer.Reset(timeout)
+}
+
+// updateStatus refreshes the snapshot of the loop state.
+func (m *roundManager) updateStatus() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.status = Status{
+		Sequence:   m.sequence,
+		Round:      m.round,
+		Proposer:   m.proposer(m.round),
+		State:      m.state.String(),
+		Validators: m.validators,
+	}
+}
+
+// proposer returns the validator proposing in the given round of the current
+// sequence, picked round robin.
+func (m *roundManager) proposer(round uint64) common.Address {
+	if len(m.validators) == 0 {
+		return common.Address{}
+	}
+	return m.validators[(m.sequence+round)%uint64(len(m.validators))]
+}
+
+// tryPropose proposes a block if the local validator is the proposer of the
+// current round and it didn't propose yet. A proposal prepared in an earlier
+// round takes precedence over the local block.
+func (m *roundManager) tryPropose() {
+	if m.proposed || m.state != statusAccepting {
+		return
+	}
+	if signer, _ := m.bft.credentials(); signer != m.proposer(m.round) {
+		return
+	}
+	block := m.locked
+	if block == nil {
+		if m.pending == nil {
+			return
+		}
+		block = m.pending.block
+	}
+	payload, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		log.Error("Failed to encode proposal", "err", err)
+		return
+	}
+	m.proposed = true
+
+	log.Info("Proposing block", "number", block.Number(), "sealhash", SealHash(block.Header()), "round", m.round)
+	m.broadcast(&message{
+		Code:     msgPreprepare,
+		Sequence: m.sequence,
+		Round:    m.round,
+		Digest:   proposalHash(block.Header()),
+		Block:    payload,
+		block:    block,
+	})
+}
+
+// handleProposal stores a local block to be proposed in the current sequence.
+func (m *roundManager) handleProposal(p *proposal) {
+	if p.block.NumberU64() != m.sequence || p.block.ParentHash() != m.head.Hash() {
+		return // stale block
+	}
+	m.pending = p
+	m.tryPropose()
+}
+
+// handleMessage processes a consensus message sent by a validator.
+func (m *roundManager) handleMessage(msg *message) {
+	switch {
+	case msg.Sequence > m.sequence:
+		if msg.Sequence > m.sequence+maxFutureSequences || msg.Round > maxFutureRounds {
+			return
+		}
+		if len(m.backlog) < maxBacklog && m.backlogged[msg.sender] < maxSenderBacklog {
+			m.backlog = append(m.backlog, msg)
+			m.backlogged[msg.sender]++
+		}
+		return
+	case msg.Sequence < m.sequence:
+		return
+	}
+	if !containsValidator(m.validators, msg.sender) || msg.Round > m.round+maxFutureRounds {
+		return
+	}
+	switch msg.Code {
+	case msgPreprepare:
+		switch {
+		case msg.sender != m.proposer(msg.Round) || msg.Round < m.round:
+		case msg.Round > m.round:
+			m.preprepares[msg.Round] = msg
+		default:
+			m.handlePreprepare(msg)
+		}
+	case msgPrepare:
+		if msg.Round >= m.round {
+			addVote(m.prepares, msg)
+			m.checkPrepared()
+		}
+	case msgCommit:
+		if msg.Round >= m.round {
+			addVote(m.commits, msg)
+			m.checkCommitted()
+		}
+	case msgRoundChange:
+		if msg.Round > m.round {
+			addVote(m.roundChanges, msg)
+			m.checkRoundChange()
+		}
+	case msgFinal:
+		m.handleFinal(msg)
+	}
+}
+
+// handlePreprepare accepts the proposal of the current round, if it's valid and
+// it matches the proposal prepared earlier in the sequence, if any.
+func (m *roundManager) handlePreprepare(msg *message) {
+	if m.state != statusAccepting {
+		return
+	}
+	if err := m.verifyProposal(msg.block, msg.Digest); err != nil {
+		log.Debug("Rejected block proposal", "sequence", msg.Sequence, "round", msg.Round, "proposer", msg.sender, "err", err)
+		return
+	}
+	if m.locked != nil && proposalHash(m.locked.Header()) != msg.Digest {
+		log.Debug("Rejected proposal conflicting with locked one", "sequence", msg.Sequence, "round", msg.Round, "proposer", msg.sender)
+		return
+	}
+	m.proposal, m.state = msg.block, statusPreprepared
+	m.updateStatus()
+
+	m.vote(msgPrepare)
+	m.checkPrepared()
+	m.checkCommitted()
+}
+
+// verifyProposal checks whether a proposed block extends the local chain head
+// and conforms to the consensus rules, apart from the committed seals.
+func (m *roundManager) verifyProposal(block *types.Block, digest common.Hash) error {
+	header := block.Header()
+	if header.Number.Uint64() != m.sequence || header.ParentHash != m.head.Hash() || proposalHash(header) != digest {
+		return errInvalidProposal
+	}
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash {
+		return errInvalidProposal
+	}
+	if len(block.Uncles()) > 0 {
+		return errInvalidProposal
+	}
+	return m.bft.verifyHeader(m.chain, header, nil, false)
+}
+
+// checkPrepared commits to the accepted proposal once a quorum of validators
+// prepared it, locking the sequence on it.
+func (m *roundManager) checkPrepared() {
+	if m.state != statusPreprepared {
+		return
+	}
+	if countVotes(m.prepares[m.round], proposalHash(m.proposal.Header())) < quorumSize(len(m.validators)) {
+		return
+	}
+	m.locked, m.state = m.proposal, statusPrepared
+	m.updateStatus()
+
+	m.vote(msgCommit)
+}
+
+// checkCommitted seals the accepted proposal once a quorum of validators
+// committed to it. Only the proposer of the round distributes the final block,
+// with the canonical set of committed seals: the ones of the first quorum of
+// validators in the validator set order.
+func (m *roundManager) checkCommitted() {
+	if m.state != statusPreprepared && m.state != statusPrepared {
+		return
+	}
+	digest := proposalHash(m.proposal.Header())
+	if countVotes(m.commits[m.round], digest) < quorumSize(len(m.validators)) {
+		return
+	}
+	m.locked, m.state = m.proposal, statusCommitted
+	m.updateStatus()
+
+	if signer, _ := m.bft.credentials(); signer != m.proposer(m.round) {
+		return
+	}
+	var seals [][]byte
+	for _, validator := range m.validators {
+		if vote := m.commits[m.round][validator]; vote != nil && vote.Digest == digest {
+			seals = append(seals, vote.CommittedSeal)
+		}
+		if len(seals) == quorumSize(len(m.validators)) {
+			break
+		}
+	}
+	header := m.proposal.Header()
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		log.Error("Invalid proposal extra-data", "err", err)
+		return
+	}
+	extra.CommittedSeals = seals
+	if header.Extra, err = EncodeExtra(header.Extra[:extraVanity], extra); err != nil {
+		log.Error("Failed to encode committed seals", "err", err)
+		return
+	}
+	block := m.proposal.WithSeal(header)
+	payload, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		log.Error("Failed to encode final block", "err", err)
+		return
+	}
+	m.broadcast(&message{
+		Code:     msgFinal,
+		Sequence: m.sequence,
+		Round:    m.round,
+		Digest:   digest,
+		Block:    payload,
+		block:    block,
+	})
+}
+
+// handleFinal imports the final block of the current sequence, distributed by
+// the proposer of the round it was committed in.
+func (m *roundManager) handleFinal(msg *message) {
+	if msg.sender != m.proposer(msg.Round) {
+		return
+	}
+	block := msg.block
+	if block.NumberU64() != m.sequence || block.ParentHash() != m.head.Hash() || proposalHash(block.Header()) != msg.Digest {
+		return
+	}
+	if err := m.bft.verifyHeader(m.chain, block.Header(), nil, true); err != nil {
+		log.Debug("Rejected final block", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return
+	}
+	// Hand the local proposal back to the miner to write and announce it, insert
+	// anything else directly
+	if p := m.pending; p != nil && SealHash(p.block.Header()) == SealHash(block.Header()) {
+		select {
+		case p.results <- block:
+			return
+		default:
+			log.Warn("Sealing result is not read by miner", "sealhash", SealHash(block.Header()))
+		}
+	}
+	if _, err := m.chain.InsertChain(types.Blocks{block}); err != nil {
+		log.Error("Failed to import final block", "number", block.Number(), "hash", block.Hash(), "err", err)
+	}
+}
+
+// handleTimeout votes to change the round once the current one didn't commit in
+// time, or the round voted for didn't start in time.
+func (m *roundManager) handleTimeout() {
+	m.desiredRound++
+	if m.desiredRound <= m.round {
+		m.desiredRound = m.round + 1
+	}
+	log.Debug("Consensus round timed out", "sequence", m.sequence, "round", m.round, "next", m.desiredRound)
+
+	m.resetTimer()
+	m.broadcast(&message{
+		Code:     msgRoundChange,
+		Sequence: m.sequence,
+		Round:    m.desiredRound,
+	})
+}
+
+// checkRoundChange joins the round changes of more than F validators, as at least
+// one honest validator is among them, and moves to the round voted for by a
+// quorum of validators.
+func (m *roundManager) checkRoundChange() {
+	var target, next uint64
+	for round, votes := range m.roundChanges {
+		if round > m.desiredRound && len(votes) > faultTolerance(len(m.validators)) && round > target {
+			target = round
+		}
+		if round > m.round && len(votes) >= quorumSize(len(m.validators)) && round > next {
+			next = round
+		}
+	}
+	if next > m.round {
+		m.startRound(next)
+	}
+	if target > m.desiredRound {
+		m.desiredRound = target
+		m.resetTimer()
+
+		m.broadcast(&message{
+			Code:     msgRoundChange,
+			Sequence: m.sequence,
+			Round:    target,
+		})
+	}
+}
+
+// vote casts a vote of the given kind on the accepted proposal.
+func (m *roundManager) vote(code uint64) {
+	digest := proposalHash(m.proposal.Header())
+	msg := &message{
+		Code:     code,
+		Sequence: m.sequence,
+		Round:    m.round,
+		Digest:   digest,
+	}
+	if code == msgCommit {
+		seal, err := m.sign(commitData(digest))
+		if err != nil {
+			log.Error("Failed to sign committed seal", "err", err)
+			return
+		}
+		msg.CommittedSeal = seal
+	}
+	m.broadcast(msg)
+}
+
+// broadcast signs a message, sends it to the other validators and processes it
+// locally. Nothing is sent if the local node is not a validator.
+func (m *roundManager) broadcast(msg *message) {
+	signer, signFn := m.bft.credentials()
+	if signFn == nil || !containsValidator(m.validators, signer) {
+		return
+	}
+	sig, err := m.sign(msg.signingData())
+	if err != nil {
+		log.Error("Failed to sign consensus message", "err", err)
+		return
+	}
+	msg.Signature, msg.sender = sig, signer
+
+	payload, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		log.Error("Failed to encode consensus message", "err", err)
+		return
+	}
+	m.broadcaster.Broadcast(payload)
+	m.handleMessage(msg)
+}
+
+// sign signs the given data with the local validator key.
+func (m *roundManager) sign(data []byte) ([]byte, error) {
+	signer, signFn := m.bft.credentials()
+	if signFn == nil {
+		return nil, errUnauthorizedValidator
+	}
+	return signFn(accounts.Account{Address: signer}, accounts.MimetypeBFT, data)
+}
+
+// addVote records a vote in the set of its round, replacing any previous vote of
+// the same validator.
+func addVote(sets map[uint64]messageSet, msg *message) {
+	set := sets[msg.Round]
+	if set == nil {
+		set = make(messageSet)
+		sets[msg.Round] = set
+	}
+	set[msg.sender] = msg
+}
+
+// countVotes returns the number of votes in the set cast on the given proposal.
+func countVotes(set messageSet, digest common.Hash) int {
+	var count int
+	for _, vote := range set {
+		if vote.Digest == digest {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Extra is the consensus specific content of the header extra-data, following
+// the fixed size vanity prefix.
+type Extra struct {
+	Validators     []common.Address // Validator set in ascending order
+	Seal           []byte           // Signature of the proposer over the seal hash
+	CommittedSeals [][]byte         // Signatures of the validators over the proposal hash
+}
+
+// ExtractExtra decodes the consensus specific content of the header extra-data.
+func ExtractExtra(header *types.Header) (*Extra, error) {
+	if len(header.Extra) < extraVanity {
+		return nil, errMissingVanity
+	}
+	extra := new(Extra)
+	if err := rlp.DecodeBytes(header.Extra[extraVanity:], extra); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidExtra, err)
+	}
+	return extra, nil
+}
+
+// EncodeExtra assembles the header extra-data from the vanity prefix, padded or
+// truncated to the fixed size, and the consensus specific content. It can be
+// used to create the genesis extra-data with the initial validator set.
+func EncodeExtra(vanity []byte, extra *Extra) ([]byte, error) {
+	blob, err := rlp.EncodeToBytes(extra)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, extraVanity, extraVanity+len(blob))
+	copy(prefix, vanity)
+	return append(prefix, blob...), nil
+}
+
+// filteredHeader returns a copy of the header with the committed seals removed
+// from the extra-data, along with the proposer seal unless requested otherwise.
+func filteredHeader(header *types.Header, keepSeal bool) *types.Header {
+	cpy := types.CopyHeader(header)
+
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return cpy // Invalid extra-data, the hash won't validate anyway
+	}
+	if !keepSeal {
+		extra.Seal = nil
+	}
+	extra.CommittedSeals = nil
+
+	if blob, err := EncodeExtra(header.Extra[:extraVanity], extra); err == nil {
+		cpy.Extra = blob
+	}
+	return cpy
+}
+
+// SealHash returns the hash of a block prior to it being sealed by the proposer.
+func SealHash(header *types.Header) common.Hash {
+	return filteredHeader(header, false).Hash()
+}
+
+// proposalHash returns the hash of a proposed block the validators vote on. It
+// covers the proposer seal, but not the committed seals which are only added
+// once the vote completed.
+func proposalHash(header *types.Header) common.Hash {
+	return filteredHeader(header, true).Hash()
+}
+
+// commitData returns the data a validator signs to commit to a proposal.
+func commitData(hash common.Hash) []byte {
+	return append(hash.Bytes(), byte(msgCommit))
+}
+
+// recoverSigner extracts the address that signed the Keccak256 hash of the data.
+func recoverSigner(data []byte, sig []byte) (common.Address, error) {
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(data), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// Codes of the consensus messages exchanged by the validators.
+const (
+	msgPreprepare  uint64 = iota // Proposal of a block for a round
+	msgPrepare                   // Vote accepting the proposal of a round
+	msgCommit                    // Vote committing to the prepared proposal of a round
+	msgRoundChange               // Vote moving to a later round
+	msgFinal                     // Block sealed with the commit votes of a round
+)
+
+// message is a signed consensus message exchanged by the validators.
+type message struct {
+	Code          uint64
+	Sequence      uint64      // Number of the block being agreed on
+	Round         uint64      // Round of the vote within the sequence
+	Digest        common.Hash // Proposal hash voted on, empty for round changes
+	Block         []byte      // RLP encoded block for proposals and final blocks
+	CommittedSeal []byte      // Signature over the proposal hash for commit votes
+	Signature     []byte      // Signature of the sender over all the fields above
+
+	sender common.Address // Sender recovered from the signature, cached
+	block  *types.Block   // Decoded block for proposals and final blocks, cached
+}
+
+// signingData returns the data the sender of the message signs.
+func (msg *message) signingData() []byte {
+	blob, _ := rlp.EncodeToBytes(&message{
+		Code:          msg.Code,
+		Sequence:      msg.Sequence,
+		Round:         msg.Round,
+		Digest:        msg.Digest,
+		Block:         msg.Block,
+		CommittedSeal: msg.CommittedSeal,
+	})
+	return blob
+}
+
+// decodeMessage decodes a consensus message and recovers its sender.
+func decodeMessage(payload []byte) (*message, error) {
+	msg := new(message)
+	if err := rlp.DecodeBytes(payload, msg); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidMessage, err)
+	}
+	sender, err := recoverSigner(msg.signingData(), msg.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidMessage, err)
+	}
+	msg.sender = sender
+
+	switch msg.Code {
+	case msgPreprepare, msgFinal:
+		msg.block = new(types.Block)
+		if err := rlp.DecodeBytes(msg.Block, msg.block); err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidMessage, err)
+		}
+	case msgCommit:
+		signer, err := recoverSigner(commitData(msg.Digest), msg.CommittedSeal)
+		if err != nil || signer != sender {
+			return nil, errInvalidCommittedSeal
+		}
+	case msgPrepare, msgRoundChange:
+	default:
+		return nil, fmt.Errorf("%w: unknown code %d", errInvalidMessage, msg.Code)
+	}
+	return msg, nil
+}
@@ -90,6 +90,10 @@ var (
 	// has a vote nonce set to non-zeroes.
 	errInvalidCheckpointVote = errors.New("vote nonce in checkpoint block non-zero")
 
+	// errInvalidContractVote is returned if a block whose signer set is governed
+	// by the signer contract has a vote nonce set to non-zeroes.
+	errInvalidContractVote = errors.New("vote nonce in signer contract governed block non-zero")
+
 	// errMissingVanity is returned if a block's extra-data section is shorter than
 	// 32 bytes, which is required to store the signer vanity.
 	errMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
@@ -265,6 +269,9 @@ func (c *Clique) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 	if checkpoint && !bytes.Equal(header.Nonce[:], nonceDropVote) {
 		return errInvalidCheckpointVote
 	}
+	if c.config.IsSignerContract(header.Number) && !bytes.Equal(header.Nonce[:], nonceDropVote) {
+		return errInvalidContractVote
+	}
 	// Check that the extra-data contains both the vanity and signature
 	if len(header.Extra) < extraVanity {
 		return errMissingVanity
@@ -352,12 +359,24 @@ func (c *Clique) verifyCascadingFields(chain consensus.ChainHeaderReader, header
 	}
 	// If the block is a checkpoint block, verify the signer list
 	if number%c.config.Epoch == 0 {
-		signers := make([]byte, len(snap.Signers)*common.AddressLength)
-		for i, signer := range snap.signers() {
-			copy(signers[i*common.AddressLength:], signer[:])
-		}
 		extraSuffix := len(header.Extra) - extraSeal
-		if !bytes.Equal(header.Extra[extraVanity:extraSuffix], signers) {
+		checkpointSigners := header.Extra[extraVanity:extraSuffix]
+
+		if c.config.IsSignerContract(header.Number) {
+			// The signer list is read from the signer contract, which needs the parent
+			// state. Light clients, header syncs and batches with not yet processed
+			// parents can't access it, the list is verified by VerifyState when the
+			// block is processed instead.
+			err := c.verifyContractSigners(chain, header, parent)
+			switch {
+			case err == errSignerStateUnavailable:
+				if len(checkpointSigners) == 0 {
+					return errInvalidCheckpointSigners
+				}
+			case err != nil:
+				return err
+			}
+		} else if !bytes.Equal(checkpointSigners, encodeSigners(snap.signers())) {
 			return errMismatchingCheckpointSigners
 		}
 	}
@@ -365,6 +384,19 @@ func (c *Clique) verifyCascadingFields(chain consensus.ChainHeaderReader, header
 	return c.verifySeal(snap, header, parents)
 }
 
+// verifyContractSigners checks that the signer list of a checkpoint header matches
+// the signer set of the signer contract as of the parent block.
+func (c *Clique) verifyContractSigners(chain consensus.ChainHeaderReader, header *types.Header, parent *types.Header) error {
+	signers, err := c.contractSigners(chain, parent)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(header.Extra[extraVanity:len(header.Extra)-extraSeal], encodeSigners(signers)) {
+		return errMismatchingCheckpointSigners
+	}
+	return nil
+}
+
 // snapshot retrieves the authorization snapshot at a given point in time.
 func (c *Clique) snapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
 	// Search for a snapshot in memory or on disk for checkpoints
@@ -395,11 +427,7 @@ func (c *Clique) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 			if checkpoint != nil {
 				hash := checkpoint.Hash()
 
-				signers := make([]common.Address, (len(checkpoint.Extra)-extraVanity-extraSeal)/common.AddressLength)
-				for i := 0; i < len(signers); i++ {
-					copy(signers[i][:], checkpoint.Extra[extraVanity+i*common.AddressLength:])
-				}
-				snap = newSnapshot(c.config, c.signatures, number, hash, signers)
+				snap = newSnapshot(c.config, c.signatures, number, hash, decodeSigners(checkpoint))
 				if err := snap.store(c.db); err != nil {
 					return nil, err
 				}
@@ -508,7 +536,7 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 		return err
 	}
 	c.lock.RLock()
-	if number%c.config.Epoch != 0 {
+	if number%c.config.Epoch != 0 && !c.config.IsSignerContract(header.Number) {
 		// Gather all the proposals that make sense voting on
 		addresses := make([]common.Address, 0, len(c.proposals))
 		for address, authorize := range c.proposals {
@@ -540,10 +568,18 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 	}
 	header.Extra = header.Extra[:extraVanity]
 
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
 	if number%c.config.Epoch == 0 {
-		for _, signer := range snap.signers() {
-			header.Extra = append(header.Extra, signer[:]...)
+		signers := snap.signers()
+		if c.config.IsSignerContract(header.Number) {
+			if signers, err = c.contractSigners(chain, parent); err != nil {
+				return err
+			}
 		}
+		header.Extra = append(header.Extra, encodeSigners(signers)...)
 	}
 	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
 
@@ -551,10 +587,6 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 	header.MixDigest = common.Hash{}
 
 	// Ensure the timestamp has the correct delay
-	parent := chain.GetHeader(header.ParentHash, number-1)
-	if parent == nil {
-		return consensus.ErrUnknownAncestor
-	}
 	header.Time = parent.Time + c.config.Period
 	if header.Time < uint64(time.Now().Unix()) {
 		header.Time = uint64(time.Now().Unix())
@@ -570,6 +602,21 @@ func (c *Clique) Finalize(chain consensus.ChainHeaderReader, header *types.Heade
 	header.UncleHash = types.CalcUncleHash(nil)
 }
 
+// VerifyState implements consensus.StateVerifier, checking the signer list of the
+// checkpoints governed by the signer contract against the contract. Contrary to
+// the header verification, a missing parent state is an error here.
+func (c *Clique) VerifyState(chain consensus.ChainHeaderReader, header *types.Header) error {
+	number := header.Number.Uint64()
+	if number == 0 || number%c.config.Epoch != 0 || !c.config.IsSignerContract(header.Number) {
+		return nil
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	return c.verifyContractSigners(chain, header, parent)
+}
+
 // FinalizeAndAssemble implements consensus.Engine, ensuring no uncles are set,
 // nor block rewards given, and returns the final block.
 func (c *Clique) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
@@ -749,3 +796,23 @@ func encodeSigHeader(w io.Writer, header *types.Header) {
 		panic("can't encode: " + err.Error())
 	}
 }
+
+// encodeSigners concatenates the given signers into the checkpoint extra-data
+// signer list layout.
+func encodeSigners(signers []common.Address) []byte {
+	blob := make([]byte, len(signers)*common.AddressLength)
+	for i, signer := range signers {
+		copy(blob[i*common.AddressLength:], signer[:])
+	}
+	return blob
+}
+
+// decodeSigners extracts the signer list from the extra-data of a checkpoint
+// header.
+func decodeSigners(header *types.Header) []common.Address {
+	signers := make([]common.Address, (len(header.Extra)-extraVanity-extraSeal)/common.AddressLength)
+	for i := 0; i < len(signers); i++ {
+		copy(signers[i][:], header.Extra[extraVanity+i*common.AddressLength:])
+	}
+	return signers
+}
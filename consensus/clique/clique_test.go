@@ -17,10 +17,12 @@
 package clique
 
 import (
+	"bytes"
 	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -125,3 +127,141 @@ func TestSealHash(t *testing.T) {
 		t.Errorf("have %x, want %x", have, want)
 	}
 }
+
+// signerContractCode is a minimal signer contract returning the signer set from
+// its storage (length at slot 0, signers from slot 1 onwards) on getSigners, and
+// overwriting its storage with the ABI words of the calldata on any other call.
+var signerContractCode = common.FromHex("3660041460235760005b80602002600401803611156021573581556001016009565b005b602060005260005460005b81811160455780548160200260200152600101602e565b506020026040016000f3")
+
+// Tests that the signer set of a chain governed by a signer contract is taken
+// from the contract on checkpoints, and that checkpoints not matching it are
+// rejected.
+func TestSignerContractGovernance(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		key1, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		key2, _  = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr1    = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2    = crypto.PubkeyToAddress(key2.PublicKey)
+		contract = common.HexToAddress("0x0000000000000000000000000000000000001000")
+		config   = *params.AllCliqueProtocolChanges
+	)
+	config.Clique = &params.CliqueConfig{
+		Period:              0,
+		Epoch:               2,
+		SignerContract:      &contract,
+		SignerContractBlock: big.NewInt(0),
+	}
+	engine := New(config.Clique, db)
+	engine.fakeDiff = true
+
+	genspec := &core.Genesis{
+		Config:    &config,
+		ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal),
+		Alloc: map[common.Address]core.GenesisAccount{
+			addr1: {Balance: big.NewInt(10000000000000000)},
+			contract: {
+				Balance: new(big.Int),
+				Code:    signerContractCode,
+				Storage: map[common.Hash]common.Hash{
+					common.BigToHash(big.NewInt(0)): common.BigToHash(big.NewInt(1)),
+					common.BigToHash(big.NewInt(1)): common.BytesToHash(addr1[:]),
+				},
+			},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	copy(genspec.ExtraData[extraVanity:], addr1[:])
+	genesis := genspec.MustCommit(db)
+
+	// Add the second signer to the contract in the first block
+	signers := []common.Address{addr1, addr2}
+	if bytes.Compare(addr2[:], addr1[:]) < 0 {
+		signers = []common.Address{addr2, addr1}
+	}
+	input := append([]byte{0xde, 0xad, 0xbe, 0xef}, common.BigToHash(big.NewInt(2)).Bytes()...)
+	for _, signer := range signers {
+		input = append(input, common.BytesToHash(signer[:]).Bytes()...)
+	}
+	blocks, _ := core.GenerateChain(&config, genesis, engine, db, 4, func(i int, block *core.BlockGen) {
+		block.SetDifficulty(diffInTurn)
+		if i == 0 {
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr1), contract, new(big.Int), 200000, block.BaseFee(), input), types.LatestSigner(&config), key1)
+			if err != nil {
+				panic(err)
+			}
+			block.AddTx(tx)
+		}
+	})
+	// Seal the blocks, the second one being a checkpoint with the given signers
+	// and the third one signed by the newly added signer.
+	seal := func(checkpoint []common.Address) []*types.Block {
+		sealed := make([]*types.Block, len(blocks))
+		for i, block := range blocks {
+			header := block.Header()
+			if i > 0 {
+				header.ParentHash = sealed[i-1].Hash()
+			}
+			header.Extra = make([]byte, extraVanity+extraSeal)
+			if header.Number.Uint64()%config.Clique.Epoch == 0 {
+				header.Extra = append(header.Extra[:extraVanity], append(encodeSigners(checkpoint), make([]byte, extraSeal)...)...)
+			}
+			header.Difficulty = diffInTurn
+
+			key := key1
+			if i == 2 {
+				key = key2
+			}
+			sig, _ := crypto.Sign(SealHash(header).Bytes(), key)
+			copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+			sealed[i] = block.WithSeal(header)
+		}
+		return sealed
+	}
+	// Checkpoints not matching the contract should be rejected, even if imported
+	// together with their parent, whose state isn't available during the header
+	// verification
+	invalid := seal([]common.Address{addr1})
+
+	batchdb := rawdb.NewMemoryDatabase()
+	genspec.MustCommit(batchdb)
+	batchchain, _ := core.NewBlockChain(batchdb, nil, &config, New(config.Clique, batchdb), vm.Config{}, nil, nil)
+	defer batchchain.Stop()
+
+	if n, err := batchchain.InsertChain(invalid[:2]); n != 1 || err != errMismatchingCheckpointSigners {
+		t.Fatalf("batch checkpoint error mismatch: have %d/%v, want 1/%v", n, err, errMismatchingCheckpointSigners)
+	}
+	chain, _ := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(invalid[:1]); err != nil {
+		t.Fatalf("failed to insert initial block: %v", err)
+	}
+	if _, err := chain.InsertChain(invalid[1:2]); err != errMismatchingCheckpointSigners {
+		t.Fatalf("checkpoint error mismatch: have %v, want %v", err, errMismatchingCheckpointSigners)
+	}
+	// Checkpoints matching the contract should update the signer set
+	valid := seal(signers)
+	for i, block := range valid {
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("failed to insert block %d: %v", i, err)
+		}
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 4 {
+		t.Fatalf("chain head mismatch: have %d, want %d", head, 4)
+	}
+	// Checkpoints can't be verified against the contract without the state
+	if err := engine.VerifyState(struct{ consensus.ChainHeaderReader }{chain}, valid[1].Header()); err != errSignerStateUnavailable {
+		t.Fatalf("stateless verification error mismatch: have %v, want %v", err, errSignerStateUnavailable)
+	}
+	if err := engine.VerifyState(chain, valid[1].Header()); err != nil {
+		t.Fatalf("failed to verify checkpoint against the state: %v", err)
+	}
+	snap, err := engine.snapshot(chain, 4, chain.CurrentBlock().Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if have := snap.signers(); len(have) != 2 || have[0] != signers[0] || have[1] != signers[1] {
+		t.Fatalf("signer set mismatch: have %x, want %x", have, signers)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// signerContractGas is the gas allowance of the call retrieving the signer set
+// from the signer contract.
+const signerContractGas = 50000000
+
+// signerContractABI is the interface the signer contract needs to implement:
+//
+//	function getSigners() external view returns (address[] memory);
+const signerContractABI = `[{"inputs":[],"name":"getSigners","outputs":[{"internalType":"address[]","name":"","type":"address[]"}],"stateMutability":"view","type":"function"}]`
+
+var (
+	// errSignerStateUnavailable is returned if the signer set is requested from
+	// the signer contract, but the state of the parent block is not available.
+	errSignerStateUnavailable = errors.New("signer contract state unavailable")
+
+	// errNoContractSigners is returned if the signer contract returned an empty
+	// signer set.
+	errNoContractSigners = errors.New("empty signer set in signer contract")
+)
+
+// stateReader is implemented by chains able to provide the state of their blocks
+// (i.e. full nodes), needed to call into the signer contract.
+type stateReader interface {
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// parsedSignerContractABI is the parsed interface of the signer contract.
+var parsedSignerContractABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(signerContractABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// contractSigners retrieves the signer set in ascending order from the signer
+// contract, calling it on top of the state of the given parent block.
+func (c *Clique) contractSigners(chain consensus.ChainHeaderReader, parent *types.Header) ([]common.Address, error) {
+	reader, ok := chain.(stateReader)
+	if !ok {
+		return nil, errSignerStateUnavailable
+	}
+	statedb, err := reader.StateAt(parent.Root)
+	if err != nil {
+		return nil, errSignerStateUnavailable
+	}
+	input, err := parsedSignerContractABI.Pack("getSigners")
+	if err != nil {
+		return nil, err
+	}
+	// Call the contract as of the parent block, with no dependency on the fields
+	// of the header being sealed or verified.
+	context := vm.BlockContext{
+		CanTransfer: func(db vm.StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db vm.StateDB, sender, recipient common.Address, amount *big.Int) {
+			db.SubBalance(sender, amount)
+			db.AddBalance(recipient, amount)
+		},
+		GetHash: func(n uint64) common.Hash {
+			header := parent
+			for header != nil && header.Number.Uint64() > n {
+				header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+			}
+			if header == nil || header.Number.Uint64() != n {
+				return common.Hash{}
+			}
+			return header.Hash()
+		},
+		Coinbase:    parent.Coinbase,
+		GasLimit:    parent.GasLimit,
+		BlockNumber: new(big.Int).Set(parent.Number),
+		Time:        new(big.Int).SetUint64(parent.Time),
+		Difficulty:  new(big.Int).Set(parent.Difficulty),
+	}
+	if parent.BaseFee != nil {
+		context.BaseFee = new(big.Int).Set(parent.BaseFee)
+	}
+	evm := vm.NewEVM(context, vm.TxContext{GasPrice: new(big.Int)}, statedb, chain.Config(), vm.Config{})
+
+	output, _, err := evm.StaticCall(vm.AccountRef(common.Address{}), *c.config.SignerContract, input, signerContractGas)
+	if err != nil {
+		return nil, fmt.Errorf("signer contract call failed: %v", err)
+	}
+	results, err := parsedSignerContractABI.Unpack("getSigners", output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signer contract output: %v", err)
+	}
+	signers := *abi.ConvertType(results[0], new([]common.Address)).(*[]common.Address)
+
+	// Sort and deduplicate the set to match the checkpoint extra-data layout
+	sort.Sort(signersAscending(signers))
+	deduped := make([]common.Address, 0, len(signers))
+	for _, signer := range signers {
+		if len(deduped) == 0 || signer != deduped[len(deduped)-1] {
+			deduped = append(deduped, signer)
+		}
+	}
+	if len(deduped) == 0 {
+		return nil, errNoContractSigners
+	}
+	return deduped, nil
+}
@@ -229,6 +229,24 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 		}
 		snap.Recents[number] = signer
 
+		// If the signer set is governed by the signer contract, header votes are
+		// not permitted and the set is replaced on checkpoints instead
+		if s.config.IsSignerContract(header.Number) {
+			if number%s.config.Epoch == 0 {
+				snap.Signers = make(map[common.Address]struct{})
+				for _, signer := range decodeSigners(header) {
+					snap.Signers[signer] = struct{}{}
+				}
+				// Signer list might have shrunk, delete any leftover recent caches
+				limit := uint64(len(snap.Signers)/2 + 1)
+				for seen := range snap.Recents {
+					if seen+limit <= number {
+						delete(snap.Recents, seen)
+					}
+				}
+			}
+			continue
+		}
 		// Header authorized, discard any previous votes from the signer
 		for i, vote := range snap.Votes {
 			if vote.Signer == signer && vote.Address == header.Coinbase {
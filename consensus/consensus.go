@@ -121,6 +121,15 @@ type Engine interface {
 	Close() error
 }
 
+// StateVerifier is an optional interface of consensus engines with header fields
+// depending on the chain state, which can't be verified together with the rest
+// of the header if the state of its parent is not available yet.
+type StateVerifier interface {
+	// VerifyState checks the state dependent consensus fields of a header against
+	// the state of its parent block. It's invoked when the block is processed.
+	VerifyState(chain ChainHeaderReader, header *types.Header) error
+}
+
 // PoW is a consensus engine based on proof-of-work.
 type PoW interface {
 	Engine
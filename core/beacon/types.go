@@ -72,6 +72,13 @@ type executableDataMarshaling struct {
 	Transactions  []hexutil.Bytes
 }
 
+// ExecutionPayloadEnvelope is a built payload along with the priority fees paid
+// to its fee recipient.
+type ExecutionPayloadEnvelope struct {
+	ExecutionPayload *ExecutableDataV1 `json:"executionPayload"`
+	BlockValue       *hexutil.Big      `json:"blockValue"`
+}
+
 type PayloadStatusV1 struct {
 	Status          string       `json:"status"`
 	LatestValidHash *common.Hash `json:"latestValidHash"`
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -131,6 +132,11 @@ type CacheConfig struct {
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
 
+	StateScheme   string // Scheme used to store the state trie nodes, hash-based if empty
+	StateHistory  uint64 // Number of recent blocks to keep state history for (path scheme), 0 for all
+	StatePruneDir string // Directory to store the online state pruning bloom in, online pruning disabled if empty
+	ChainHistory  uint64 // Number of recent blocks to keep the headers, bodies and receipts for, 0 for all
+
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
 
@@ -167,6 +173,11 @@ type BlockChain struct {
 	triegc *prque.Prque   // Priority queue mapping block numbers to tries to gc
 	gcproc time.Duration  // Accumulates canonical block processing for trie dumping
 
+	pruner    *pruner.OnlinePruner        // Online state pruner, nil if not running
+	pruneLast *pruner.OnlinePruneProgress // Progress report of the last finished online pruning
+	pruneLock sync.Mutex                  // Lock protecting the online pruner fields
+	pruneHook func()                      // Testing hook invoked before the online pruning is run
+
 	// txLookupLimit is the maximum number of blocks from head whose tx indices
 	// are reserved:
 	//  * 0:   means no limit and regenerate any missing indexes
@@ -220,6 +231,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if cacheConfig == nil {
 		cacheConfig = defaultCacheConfig
 	}
+	if cacheConfig.StateScheme == rawdb.PathScheme && cacheConfig.TrieDirtyDisabled {
+		return nil, errors.New("archive mode is not supported by the path-based state scheme")
+	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	receiptsCache, _ := lru.New(receiptsCacheLimit)
@@ -233,9 +247,11 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		db:          db,
 		triegc:      prque.New(nil),
 		stateCache: state.NewDatabaseWithConfig(db, &trie.Config{
-			Cache:     cacheConfig.TrieCleanLimit,
-			Journal:   cacheConfig.TrieCleanJournal,
-			Preimages: cacheConfig.Preimages,
+			Cache:        cacheConfig.TrieCleanLimit,
+			Journal:      cacheConfig.TrieCleanJournal,
+			Preimages:    cacheConfig.Preimages,
+			Scheme:       cacheConfig.StateScheme,
+			StateHistory: cacheConfig.StateHistory,
 		}),
 		quit:          make(chan struct{}),
 		chainmu:       syncx.NewClosableMutex(),
@@ -380,6 +396,16 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		}
 		bc.snaps, _ = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, head.Root(), !bc.cacheConfig.SnapshotWait, true, recover)
 	}
+	// Resume the online state pruning if it was interrupted by a shutdown
+	if rawdb.ReadOnlinePruneStatus(bc.db) != nil {
+		if bc.cacheConfig.StatePruneDir == "" {
+			log.Warn("Online state pruning interrupted, but no pruning directory configured")
+		} else if p, err := pruner.ResumeOnlinePruner(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.StatePruneDir); err != nil {
+			log.Error("Failed to resume online state pruning", "err", err)
+		} else {
+			bc.startPruner(p)
+		}
+	}
 
 	// Start future block processor.
 	bc.wg.Add(1)
@@ -392,6 +418,11 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		bc.wg.Add(1)
 		go bc.maintainTxIndex(txIndexBlock)
 	}
+	// Start chain history pruner.
+	if bc.cacheConfig.ChainHistory > 0 {
+		bc.wg.Add(1)
+		go bc.maintainHistory(txLookupLimit != nil)
+	}
 
 	// If periodic cache journal is required, spin it up.
 	if bc.cacheConfig.TrieCleanRejournal > 0 {
@@ -497,6 +528,10 @@ func (bc *BlockChain) loadLastState() error {
 // was fast synced or full synced and in which state, the method will try to
 // delete minimal data from disk whilst retaining chain consistency.
 func (bc *BlockChain) SetHead(head uint64) error {
+	// The chain can't be rewound into the pruned history, its blocks are gone.
+	if err := rawdb.CheckHistoryAvailable(bc.db, head); err != nil {
+		return err
+	}
 	_, err := bc.setHeadBeyondRoot(head, common.Hash{}, false)
 	return err
 }
@@ -550,6 +585,14 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, root common.Hash, repair bo
 					if root != (common.Hash{}) && !beyondRoot && newHeadBlock.Root() == root {
 						beyondRoot, rootNumber = true, newHeadBlock.NumberU64()
 					}
+					// With the path-based scheme, the persisted state may be reverted
+					// to the state of the block instead of rewinding further.
+					if !bc.HasState(newHeadBlock.Root()) && bc.stateCache.TrieDB().Recoverable(newHeadBlock.Root()) {
+						if err := bc.stateCache.TrieDB().Recover(newHeadBlock.Root()); err != nil {
+							log.Crit("Failed to revert persisted state", "err", err)
+						}
+						log.Debug("Reverted persisted state", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
+					}
 					if _, err := state.New(newHeadBlock.Root(), bc.stateCache, bc.snaps); err != nil {
 						log.Trace("Block state missing, rewinding further", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
 						if pivot == nil || newHeadBlock.NumberU64() > *pivot {
@@ -573,7 +616,7 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, root common.Hash, repair bo
 							// if the historical chain pruning is enabled. In that case the logic
 							// needs to be improved here.
 							if !bc.HasState(bc.genesisBlock.Root()) {
-								if err := CommitGenesisState(bc.db, bc.genesisBlock.Hash()); err != nil {
+								if err := CommitGenesisState(bc.db, bc.stateCache.TrieDB(), bc.genesisBlock.Hash()); err != nil {
 									log.Crit("Failed to commit genesis state", "err", err)
 								}
 								log.Debug("Recommitted genesis state to disk")
@@ -832,7 +875,14 @@ func (bc *BlockChain) Stop() {
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
 	//  - HEAD-1:   So we don't do large reorgs if our HEAD becomes an uncle
 	//  - HEAD-127: So we have a hard limit on the number of blocks reexecuted
-	if !bc.cacheConfig.TrieDirtyDisabled {
+	//
+	// The path-based scheme only persists a single state, so the in-memory layers
+	// are journalled instead.
+	if bc.stateCache.TrieDB().Scheme() == rawdb.PathScheme {
+		if err := bc.stateCache.TrieDB().Journal(bc.CurrentBlock().Root()); err != nil {
+			log.Error("Failed to journal state layers", "err", err)
+		}
+	} else if !bc.cacheConfig.TrieDirtyDisabled {
 		triedb := bc.stateCache.TrieDB()
 
 		for _, offset := range []uint64{0, 1, TriesInMemory - 1} {
@@ -1237,6 +1287,17 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	}
 	triedb := bc.stateCache.TrieDB()
 
+	// With the path-based scheme, stack the state on top of its parent, the
+	// trie database takes care of flushing old states by itself
+	if triedb.Scheme() == rawdb.PathScheme {
+		if err := triedb.Update(root, bc.GetHeader(block.ParentHash(), block.NumberU64()-1).Root); err != nil {
+			return err
+		}
+		if nodes, _ := triedb.Size(); nodes > common.StorageSize(bc.cacheConfig.TrieDirtyLimit)*1024*1024 {
+			return triedb.Cap(common.StorageSize(bc.cacheConfig.TrieDirtyLimit)*1024*1024 - ethdb.IdealBatchSize)
+		}
+		return nil
+	}
 	// If we're running an archive node, always flush
 	if bc.cacheConfig.TrieDirtyDisabled {
 		return triedb.Commit(root, false, nil)
@@ -1827,6 +1888,13 @@ func (bc *BlockChain) insertSideChain(block *types.Block, it *insertIterator) (i
 	)
 	parent := it.previous()
 	for parent != nil && !bc.HasState(parent.Root) {
+		// With the path-based scheme, revert the persisted state if possible
+		if bc.stateCache.TrieDB().Recoverable(parent.Root) {
+			if err := bc.stateCache.TrieDB().Recover(parent.Root); err != nil {
+				return it.index, err
+			}
+			break
+		}
 		hashes = append(hashes, parent.Hash())
 		numbers = append(numbers, parent.Number.Uint64())
 
@@ -1883,6 +1951,13 @@ func (bc *BlockChain) recoverAncestors(block *types.Block) (common.Hash, error)
 		parent  = block
 	)
 	for parent != nil && !bc.HasState(parent.Root()) {
+		// With the path-based scheme, revert the persisted state if possible
+		if bc.stateCache.TrieDB().Recoverable(parent.Root()) {
+			if err := bc.stateCache.TrieDB().Recover(parent.Root()); err != nil {
+				return common.Hash{}, err
+			}
+			break
+		}
 		hashes = append(hashes, parent.Hash())
 		numbers = append(numbers, parent.NumberU64())
 		parent = bc.GetBlock(parent.ParentHash(), parent.NumberU64()-1)
@@ -2245,16 +2320,33 @@ func (bc *BlockChain) skipBlock(err error, it *insertIterator) bool {
 func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	defer bc.wg.Done()
 
+	// The transactions of blocks with pruned history can't be indexed, so limit
+	// the index to the retained history.
+	limit := bc.txLookupLimit
+	if history := bc.cacheConfig.ChainHistory; history != 0 && (limit == 0 || history < limit) {
+		limit = history
+	}
+	// historyTail caps the first block to index to the first one still having
+	// its body available.
+	historyTail := func(from uint64) uint64 {
+		if tail, err := bc.db.Tail(); err == nil && tail > from {
+			return tail
+		}
+		return from
+	}
+
 	// Before starting the actual maintenance, we need to handle a special case,
 	// where user might init Geth with an external ancient database. If so, we
 	// need to reindex all necessary transactions before starting to process any
 	// pruning requests.
 	if ancients > 0 {
 		var from = uint64(0)
-		if bc.txLookupLimit != 0 && ancients > bc.txLookupLimit {
-			from = ancients - bc.txLookupLimit
+		if limit != 0 && ancients > limit {
+			from = ancients - limit
+		}
+		if from = historyTail(from); from < ancients {
+			rawdb.IndexTransactions(bc.db, from, ancients, bc.quit)
 		}
-		rawdb.IndexTransactions(bc.db, from, ancients, bc.quit)
 	}
 
 	// indexBlocks reindexes or unindexes transactions depending on user configuration
@@ -2264,17 +2356,17 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 		// If the user just upgraded Geth to a new version which supports transaction
 		// index pruning, write the new tail and remove anything older.
 		if tail == nil {
-			if bc.txLookupLimit == 0 || head < bc.txLookupLimit {
+			if limit == 0 || head < limit {
 				// Nothing to delete, write the tail and return
 				rawdb.WriteTxIndexTail(bc.db, 0)
 			} else {
 				// Prune all stale tx indices and record the tx index tail
-				rawdb.UnindexTransactions(bc.db, 0, head-bc.txLookupLimit+1, bc.quit)
+				rawdb.UnindexTransactions(bc.db, 0, head-limit+1, bc.quit)
 			}
 			return
 		}
 		// If a previous indexing existed, make sure that we fill in any missing entries
-		if bc.txLookupLimit == 0 || head < bc.txLookupLimit {
+		if limit == 0 || head < limit {
 			if *tail > 0 {
 				// It can happen when chain is rewound to a historical point which
 				// is even lower than the indexes tail, recap the indexing target
@@ -2283,17 +2375,21 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 				if end > head+1 {
 					end = head + 1
 				}
-				rawdb.IndexTransactions(bc.db, 0, end, bc.quit)
+				if from := historyTail(0); from < end {
+					rawdb.IndexTransactions(bc.db, from, end, bc.quit)
+				}
 			}
 			return
 		}
 		// Update the transaction index to the new chain state
-		if head-bc.txLookupLimit+1 < *tail {
+		if head-limit+1 < *tail {
 			// Reindex a part of missing indices and rewind index tail to HEAD-limit
-			rawdb.IndexTransactions(bc.db, head-bc.txLookupLimit+1, *tail, bc.quit)
+			if from := historyTail(head - limit + 1); from < *tail {
+				rawdb.IndexTransactions(bc.db, from, *tail, bc.quit)
+			}
 		} else {
 			// Unindex a part of stale indices and forward index tail to HEAD-limit
-			rawdb.UnindexTransactions(bc.db, *tail, head-bc.txLookupLimit+1, bc.quit)
+			rawdb.UnindexTransactions(bc.db, *tail, head-limit+1, bc.quit)
 		}
 	}
 
@@ -2327,6 +2423,71 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	}
 }
 
+// maintainHistory is responsible for deleting the block history beyond the
+// configured retention from the tail of the ancient store.
+//
+// User can use flag `history.chain` to specify the number of recent blocks to
+// retain the headers, bodies and receipts for. Only the frozen chain segments
+// are ever deleted, and the genesis block is always retained.
+func (bc *BlockChain) maintainHistory(txIndexed bool) {
+	defer bc.wg.Done()
+
+	// The recent blocks are needed for persisting their state on shutdown and
+	// for rewinding after a crash, never prune them.
+	retain := bc.cacheConfig.ChainHistory
+	if retain < TriesInMemory {
+		retain = TriesInMemory
+	}
+	prune := func(head uint64) {
+		if head < retain {
+			return
+		}
+		tail := head - retain + 1
+		frozen, err := bc.db.Ancients()
+		if err != nil {
+			return // No ancient store
+		}
+		if tail > frozen {
+			tail = frozen
+		}
+		// Transaction indices can only be deleted while the bodies are still
+		// available, so wait for the indexer to catch up.
+		if txIndexed {
+			indexed := rawdb.ReadTxIndexTail(bc.db)
+			if indexed == nil {
+				return
+			}
+			if *indexed < tail {
+				tail = *indexed
+			}
+		}
+		if pruned, err := bc.db.Tail(); err != nil || pruned >= tail {
+			return
+		}
+		if err := bc.db.TruncateTail(tail); err != nil {
+			log.Error("Failed to prune chain history", "tail", tail, "err", err)
+			return
+		}
+		log.Debug("Pruned chain history", "tail", tail)
+	}
+	headCh := make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	prune(bc.CurrentBlock().NumberU64())
+	for {
+		select {
+		case head := <-headCh:
+			prune(head.Block.NumberU64())
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	rawdb.WriteBadBlock(bc.db, block)
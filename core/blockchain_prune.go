@@ -0,0 +1,196 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// PruneState starts deleting the stale state from the database in the background,
+// while the chain keeps running. All the state of the bottom-most snapshot diff
+// layer (usually HEAD-127) and of the blocks after it is retained. The bloom size
+// is the megabytes of memory to allocate for tracking the live state.
+//
+// An interrupted pruning is resumed automatically on the next startup.
+func (bc *BlockChain) PruneState(bloomSize uint64) error {
+	triedb := bc.stateCache.TrieDB()
+	if triedb.Scheme() != rawdb.HashScheme {
+		return errors.New("state pruning is not required for the path-based scheme")
+	}
+	if bc.cacheConfig.TrieDirtyDisabled {
+		return errors.New("state pruning is not supported in archive mode")
+	}
+	if bc.cacheConfig.StatePruneDir == "" {
+		return errors.New("state pruning directory not configured")
+	}
+	if bc.snaps == nil {
+		return errors.New("state pruning requires the snapshot")
+	}
+	if generating, err := bc.snaps.Generating(); err != nil {
+		return err
+	} else if generating {
+		return errors.New("snapshot not fully generated yet")
+	}
+	if !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	bc.pruneLock.Lock()
+	running := bc.pruner != nil
+	bc.pruneLock.Unlock()
+	if running {
+		return errors.New("state pruning already in progress")
+	}
+	// Use the bottom-most diff layer as the target, similarly to the offline
+	// pruner. It's unlikely to be reorged and it's still held in memory.
+	layers := bc.snaps.Snapshots(bc.CurrentBlock().Root(), TriesInMemory, true)
+	if len(layers) != TriesInMemory {
+		return fmt.Errorf("snapshot not old enough yet: need %d more blocks", TriesInMemory-len(layers))
+	}
+	// Consecutive blocks might have the same root in which case no diff layer
+	// is created, pick the bottom-most layer with the state still available.
+	var target common.Hash
+	for i := len(layers) - 1; i >= 2; i-- {
+		if bc.HasState(layers[i].Root()) {
+			target = layers[i].Root()
+			break
+		}
+	}
+	if target == (common.Hash{}) {
+		return errors.New("no snapshot paired state")
+	}
+	// Persist the target state, so that it's the base of the live state in
+	// the database.
+	if err := triedb.Commit(target, false, nil); err != nil {
+		return err
+	}
+	p, err := pruner.NewOnlinePruner(bc.db, triedb, bc.cacheConfig.StatePruneDir, target, bloomSize)
+	if err != nil {
+		return err
+	}
+	log.Info("Started online state pruning", "target", target)
+	bc.startPruner(p)
+	return nil
+}
+
+// PruneStateProgress returns the progress of the running online state pruning,
+// or of the last finished one. Nil is returned if no pruning was executed since
+// the startup.
+func (bc *BlockChain) PruneStateProgress() *pruner.OnlinePruneProgress {
+	bc.pruneLock.Lock()
+	defer bc.pruneLock.Unlock()
+
+	if bc.pruner != nil {
+		progress := bc.pruner.Progress()
+		return &progress
+	}
+	return bc.pruneLast
+}
+
+// startPruner runs the online state pruner in the background until it's done
+// or until the chain is stopped.
+//
+// Note, the chain mutex must be held or the chain must not be running yet.
+func (bc *BlockChain) startPruner(p *pruner.OnlinePruner) {
+	p.Retain(bc.pruneRoots(p.Target()))
+
+	bc.pruneLock.Lock()
+	bc.pruner = p
+	bc.pruneLock.Unlock()
+
+	bc.wg.Add(1)
+	go func() {
+		defer bc.wg.Done()
+
+		if bc.pruneHook != nil {
+			bc.pruneHook()
+		}
+		err := p.Run(bc.quit)
+
+		progress := p.Progress()
+		progress.Running = false
+		if err != nil {
+			progress.Error = err.Error()
+		}
+		bc.pruneLock.Lock()
+		bc.pruner, bc.pruneLast = nil, &progress
+		bc.pruneLock.Unlock()
+	}()
+}
+
+// pruneRoots collects the roots of the recent states which must be retained by
+// the online state pruning besides its target: the ones of the recent canonical
+// blocks, the ones still tracked in memory, the older canonical ones persisted
+// since the target and the one of the snapshot disk layer, which the chain is
+// rewound to if the head state is lost in a crash.
+//
+// Note, the chain mutex must be held or the chain must not be running yet.
+func (bc *BlockChain) pruneRoots(target common.Hash) []common.Hash {
+	var (
+		roots []common.Hash
+		seen  = make(map[common.Hash]struct{})
+	)
+	add := func(root common.Hash) {
+		if _, ok := seen[root]; !ok {
+			seen[root] = struct{}{}
+			roots = append(roots, root)
+		}
+	}
+	// Gather the in-memory tries, including the side chain ones
+	var (
+		tries []common.Hash
+		prios []int64
+	)
+	for !bc.triegc.Empty() {
+		root, prio := bc.triegc.Pop()
+		tries, prios = append(tries, root.(common.Hash)), append(prios, prio)
+	}
+	for i, root := range tries {
+		bc.triegc.Push(root, prios[i])
+		add(root)
+	}
+	// Gather the recent canonical states, which might be persisted
+	head := bc.CurrentBlock().NumberU64()
+	for i := uint64(0); i < TriesInMemory && i <= head; i++ {
+		if header := bc.GetHeaderByNumber(head - i); header != nil {
+			add(header.Root)
+		}
+	}
+	// Gather the older canonical states persisted since the target. These are
+	// only present if the pruning is resumed, the ones flushed by the chain in
+	// the meantime are not tracked by the bloom regenerated after the restart.
+	for number := int64(head) - TriesInMemory; number > 0; number-- {
+		header := bc.GetHeaderByNumber(uint64(number))
+		if header == nil || header.Root == target {
+			break
+		}
+		if rawdb.HasTrieNode(bc.db, header.Root) {
+			add(header.Root)
+		}
+	}
+	if bc.snaps != nil {
+		add(bc.snaps.DiskRoot())
+	}
+	return roots
+}
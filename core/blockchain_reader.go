@@ -17,6 +17,7 @@
 package core
 
 import (
+	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -280,10 +281,18 @@ func (bc *BlockChain) HasBlockAndState(hash common.Hash, number uint64) bool {
 	return bc.HasState(block.Root())
 }
 
+// errTrieNodeByHash is returned when looking up a trie node by its hash alone in a
+// path-based state, where nodes are only addressable by their owner and path.
+var errTrieNodeByHash = errors.New("trie node lookup by hash not supported by the path-based scheme")
+
 // TrieNode retrieves a blob of data associated with a trie node
 // either from ephemeral in-memory cache, or from persistent storage.
 func (bc *BlockChain) TrieNode(hash common.Hash) ([]byte, error) {
-	return bc.stateCache.TrieDB().Node(hash)
+	triedb := bc.stateCache.TrieDB()
+	if triedb.Scheme() == rawdb.PathScheme {
+		return nil, errTrieNodeByHash
+	}
+	return triedb.Node(hash)
 }
 
 // ContractCode retrieves a blob of data associated with a contract hash
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
@@ -1731,6 +1732,378 @@ func TestLargeReorgTrieGC(t *testing.T) {
 	}
 }
 
+// Tests that the path-based state scheme keeps the recent states available,
+// restores them after a restart and reverts the persisted state when the chain
+// is rewound or reorged beyond the in-memory states.
+func TestPathSchemeChain(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		gendb   = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	generate := func(parent *types.Block, n int, seed byte) []*types.Block {
+		blocks, _ := GenerateChain(gspec.Config, parent, engine, gendb, n, func(i int, b *BlockGen) {
+			b.SetCoinbase(common.Address{seed})
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(address), common.Address{seed, byte(i)}, big.NewInt(1000), params.TxGas, b.header.BaseFee, nil), signer, key)
+			b.AddTx(tx)
+		})
+		return blocks
+	}
+	var (
+		blocks     = generate(genesis, 2*TriesInMemory, 1)
+		competitor = generate(blocks[9], 2*TriesInMemory, 2)
+	)
+	diskdb := rawdb.NewMemoryDatabase()
+	if _, err := gspec.commit(diskdb, trie.NewDatabaseWithConfig(diskdb, &trie.Config{Scheme: rawdb.PathScheme})); err != nil {
+		t.Fatalf("failed to commit genesis: %v", err)
+	}
+	if scheme := rawdb.ReadStateScheme(diskdb); scheme != rawdb.PathScheme {
+		t.Fatalf("state scheme mismatch: have %q, want %q", scheme, rawdb.PathScheme)
+	}
+	cacheConfig := &CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+		StateScheme:    rawdb.PathScheme,
+	}
+	chain, err := NewBlockChain(diskdb, cacheConfig, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// The recent states must be available, the older ones not anymore
+	for i := len(blocks) - TriesInMemory; i < len(blocks); i++ {
+		if !chain.HasState(blocks[i].Root()) {
+			t.Fatalf("block %d: recent state missing", blocks[i].NumberU64())
+		}
+	}
+	if chain.HasState(blocks[len(blocks)-TriesInMemory-2].Root()) {
+		t.Fatalf("block %d: old state still available", blocks[len(blocks)-TriesInMemory-2].NumberU64())
+	}
+	// Nodes can't be looked up by hash alone in a path-based state
+	if _, err := chain.TrieNode(blocks[len(blocks)-1].Root()); err != errTrieNodeByHash {
+		t.Fatalf("trie node lookup by hash error mismatch: have %v, want %v", err, errTrieNodeByHash)
+	}
+	// Restart the chain, the recent states must be restored from the journal
+	chain.Stop()
+
+	chain, err = NewBlockChain(diskdb, cacheConfig, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to recreate tester chain: %v", err)
+	}
+	if head := chain.CurrentBlock().Hash(); head != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("head block mismatch: have %x, want %x", head, blocks[len(blocks)-1].Hash())
+	}
+	for i := len(blocks) - TriesInMemory; i < len(blocks); i++ {
+		if !chain.HasState(blocks[i].Root()) {
+			t.Fatalf("block %d: recent state missing after restart", blocks[i].NumberU64())
+		}
+	}
+	// Rewind the chain beyond the in-memory states, the persisted state must be
+	// reverted instead of rewinding to genesis
+	if err := chain.SetHead(blocks[len(blocks)-TriesInMemory-20].NumberU64()); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if head := chain.CurrentBlock().Hash(); head != blocks[len(blocks)-TriesInMemory-20].Hash() {
+		t.Fatalf("rewound head mismatch: have %x, want %x", head, blocks[len(blocks)-TriesInMemory-20].Hash())
+	}
+	if !chain.HasState(chain.CurrentBlock().Root()) {
+		t.Fatal("rewound head state missing")
+	}
+	// Reorg onto the competitor chain, which forks off way below the persisted state
+	if _, err := chain.InsertChain(competitor); err != nil {
+		t.Fatalf("failed to insert competitor chain: %v", err)
+	}
+	if head := chain.CurrentBlock().Hash(); head != competitor[len(competitor)-1].Hash() {
+		t.Fatalf("reorged head mismatch: have %x, want %x", head, competitor[len(competitor)-1].Hash())
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to open head state: %v", err)
+	}
+	if nonce := statedb.GetNonce(address); nonce != uint64(10+len(competitor)) {
+		t.Fatalf("head nonce mismatch: have %d, want %d", nonce, 10+len(competitor))
+	}
+	chain.Stop()
+}
+
+// Tests that the online state pruning deletes the stale state while the chain
+// keeps importing blocks, and that it's resumed after a restart.
+func TestOnlineStatePruning(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		code    = common.FromHex("0x600100")                   // PUSH1 1 STOP
+		deploy  = common.FromHex("0x626001006000526003601df3") // Init code returning the code above
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		gendb   = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, gendb, 3*TriesInMemory+40, func(i int, b *BlockGen) {
+		if i == 0 {
+			tx, _ := types.SignTx(types.NewContractCreation(b.TxNonce(address), new(big.Int), 100000, b.header.BaseFee, deploy), signer, key)
+			b.AddTx(tx)
+		}
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(address), common.Address{byte(i), byte(i >> 8)}, big.NewInt(1000), params.TxGas, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+	})
+	// Import the first part of the chain in archive mode to accumulate stale state
+	diskdb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(diskdb)
+
+	archiveConfig := &CacheConfig{
+		TrieCleanLimit:    256,
+		TrieDirtyLimit:    256,
+		TrieDirtyDisabled: true,
+		TrieTimeLimit:     5 * time.Minute,
+		SnapshotLimit:     256,
+		SnapshotWait:      true,
+	}
+	chain, err := NewBlockChain(diskdb, archiveConfig, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks[:2*TriesInMemory]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if err := chain.PruneState(1); err == nil {
+		t.Fatal("state pruning allowed in archive mode")
+	}
+	chain.Stop()
+
+	// Move the contract code into the legacy scheme, keyed by its bare hash
+	codeHash := crypto.Keccak256Hash(code)
+	if !rawdb.HasCodeWithPrefix(diskdb, codeHash) {
+		t.Fatal("contract code not deployed")
+	}
+	rawdb.DeleteCode(diskdb, codeHash)
+	diskdb.Put(codeHash.Bytes(), code)
+
+	// Restart in pruning mode, flushing a trie with every block, and import a few
+	// blocks to have recent states only held in memory
+	cacheConfig := &CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  time.Nanosecond,
+		SnapshotLimit:  256,
+		SnapshotWait:   true,
+		StatePruneDir:  t.TempDir(),
+	}
+	chain, err = NewBlockChain(diskdb, cacheConfig, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to recreate tester chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks[2*TriesInMemory : 2*TriesInMemory+10]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Start pruning, but hold it back until more than TriesInMemory blocks are
+	// imported and flushed, so that the chain garbage collects all the recent
+	// states the pruning started with
+	release := make(chan struct{})
+	chain.pruneHook = func() {
+		select {
+		case <-release:
+		case <-chain.quit:
+		}
+	}
+	if err := chain.PruneState(1); err != nil {
+		t.Fatalf("failed to start state pruning: %v", err)
+	}
+	if err := chain.PruneState(1); err == nil && chain.PruneStateProgress().Running {
+		t.Fatal("concurrent state pruning allowed")
+	}
+	if _, err := chain.InsertChain(blocks[2*TriesInMemory+10 : 3*TriesInMemory+20]); err != nil {
+		t.Fatalf("failed to insert chain during pruning: %v", err)
+	}
+	close(release)
+	if _, err := chain.InsertChain(blocks[3*TriesInMemory+20 : 3*TriesInMemory+30]); err != nil {
+		t.Fatalf("failed to insert chain during pruning: %v", err)
+	}
+	// Interrupt the pruning, it must be resumed and finished after the restart
+	chain.Stop()
+
+	chain, err = NewBlockChain(diskdb, cacheConfig, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to recreate tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[3*TriesInMemory+30:]); err != nil {
+		t.Fatalf("failed to insert chain during pruning: %v", err)
+	}
+	for start := time.Now(); rawdb.ReadOnlinePruneStatus(diskdb) != nil; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 30*time.Second {
+			t.Fatal("state pruning not finished in time")
+		}
+	}
+	if progress := chain.PruneStateProgress(); progress != nil && (progress.Running || progress.Error != "") {
+		t.Fatalf("unexpected pruning result: %+v", progress)
+	}
+	if files, _ := os.ReadDir(cacheConfig.StatePruneDir); len(files) != 0 {
+		t.Fatalf("state bloom left on disk: %v", files)
+	}
+	// The old states must be gone except the snapshot base, the ones imported
+	// since the pruning started must be complete if available
+	for i := 0; i < TriesInMemory; i++ {
+		if blocks[i].Root() == chain.Snapshots().DiskRoot() {
+			continue
+		}
+		if chain.HasState(blocks[i].Root()) {
+			t.Fatalf("block %d: stale state still available", blocks[i].NumberU64())
+		}
+	}
+	// The states of blocks 2*TriesInMemory+1 to 2*TriesInMemory+31 are flushed
+	// by the chain while pruning, interrupted or not, and must all be retained
+	for i, block := range blocks[2*TriesInMemory:] {
+		if !chain.HasState(block.Root()) {
+			if i <= 30 {
+				t.Fatalf("block %d: state flushed during pruning missing", block.NumberU64())
+			}
+			continue
+		}
+		tr, err := trie.New(common.Hash{}, block.Root(), chain.StateCache().TrieDB())
+		if err != nil {
+			t.Fatalf("block %d: recent state missing: %v", block.NumberU64(), err)
+		}
+		it := tr.NodeIterator(nil)
+		for it.Next(true) {
+		}
+		if it.Error() != nil {
+			t.Fatalf("block %d: recent state incomplete: %v", block.NumberU64(), it.Error())
+		}
+	}
+	// The live legacy contract code must be retained
+	if !bytes.Equal(rawdb.ReadCode(diskdb, codeHash), code) {
+		t.Fatal("live legacy contract code deleted")
+	}
+	// The chain must keep working on top of the pruned state
+	more, _ := GenerateChain(gspec.Config, blocks[len(blocks)-1], engine, gendb, 10, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(address), common.Address{0xff, byte(i)}, big.NewInt(1000), params.TxGas, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+	})
+	if _, err := chain.InsertChain(more); err != nil {
+		t.Fatalf("failed to insert chain after pruning: %v", err)
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to open head state: %v", err)
+	}
+	if nonce := statedb.GetNonce(address); nonce != uint64(len(blocks)+len(more)+1) {
+		t.Fatalf("head nonce mismatch: have %d, want %d", nonce, len(blocks)+len(more)+1)
+	}
+}
+
+// Tests that the chain history beyond the configured retention is deleted from
+// the tail of the ancient store, and that it's reported as pruned.
+func TestChainHistoryPruning(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		gendb   = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, gendb, 401, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(address), common.Address{byte(i), byte(i >> 8)}, big.NewInt(1000), params.TxGas, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+	})
+	datadir := t.TempDir()
+	db, err := rawdb.NewLevelDBDatabaseWithFreezer(datadir, 0, 0, datadir, "", false)
+	if err != nil {
+		t.Fatalf("Failed to create persistent database: %v", err)
+	}
+	defer db.Close() // Might double close, should be fine
+	gspec.MustCommit(db)
+
+	config := *defaultCacheConfig
+	config.ChainHistory = 2 * TriesInMemory
+
+	chain, err := NewBlockChain(db, &config, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks[:400]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Move the blocks into the ancient store and trigger the pruning with a
+	// new head block
+	type freezer interface {
+		Freeze(threshold uint64) error
+	}
+	db.(freezer).Freeze(16)
+
+	if _, err := chain.InsertChain(blocks[400:]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	tail := uint64(len(blocks)) - config.ChainHistory + 1
+	for i := 0; ; i++ {
+		if pruned, _ := db.Tail(); pruned == tail {
+			break
+		} else if i == 100 {
+			t.Fatalf("chain history tail mismatch: have %d, want %d", pruned, tail)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	chain.Stop()
+	db.Close()
+
+	// Reopen the database and ensure the pruned history is reported as such
+	db, err = rawdb.NewLevelDBDatabaseWithFreezer(datadir, 0, 0, datadir, "", false)
+	if err != nil {
+		t.Fatalf("Failed to reopen persistent database: %v", err)
+	}
+	chain, err = NewBlockChain(db, &config, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to recreate tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	for _, block := range blocks[:tail-1] {
+		if chain.GetBlockByNumber(block.NumberU64()) != nil || chain.GetBlock(block.Hash(), block.NumberU64()) != nil {
+			t.Fatalf("block #%d not pruned", block.NumberU64())
+		}
+		if err := rawdb.CheckHistoryAvailable(db, block.NumberU64()); err != rawdb.ErrHistoryPruned {
+			t.Fatalf("block #%d history availability mismatch: have %v, want %v", block.NumberU64(), err, rawdb.ErrHistoryPruned)
+		}
+	}
+	for _, block := range blocks[tail-1:] {
+		if chain.GetBlockByNumber(block.NumberU64()) == nil || chain.GetReceiptsByHash(block.Hash()) == nil {
+			t.Fatalf("block #%d missing", block.NumberU64())
+		}
+		if err := rawdb.CheckHistoryAvailable(db, block.NumberU64()); err != nil {
+			t.Fatalf("block #%d history unavailable: %v", block.NumberU64(), err)
+		}
+	}
+	if chain.GetBlockByNumber(0) == nil {
+		t.Fatal("genesis block pruned")
+	}
+	if err := chain.SetHead(tail - 1); err != rawdb.ErrHistoryPruned {
+		t.Fatalf("rewinding into pruned history mismatch: have %v, want %v", err, rawdb.ErrHistoryPruned)
+	}
+}
+
 func TestBlockchainRecovery(t *testing.T) {
 	// Configure and generate a sample block chain
 	var (
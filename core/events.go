@@ -24,6 +24,16 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// TxChangesEvent is posted when the status of a batch of transactions changes in
+// the transaction pool.
+type TxChangesEvent struct{ Changes []*TxChange }
+
+// PendingBlockEvent is posted when the miner updates the pending block.
+type PendingBlockEvent struct {
+	Block    *types.Block
+	Receipts types.Receipts
+}
+
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
 
@@ -61,6 +61,19 @@ func DeleteCanonicalHash(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// CheckHistoryAvailable returns ErrHistoryPruned if the chain history of the
+// block at number was deleted from the tail of the ancient store, or nil if it
+// may still be available. The genesis block is never pruned.
+func CheckHistoryAvailable(db ethdb.AncientReaderOp, number uint64) error {
+	if number == 0 {
+		return nil
+	}
+	if tail, err := db.Tail(); err == nil && number < tail {
+		return ErrHistoryPruned
+	}
+	return nil
+}
+
 // ReadAllHashes retrieves all the hashes assigned to blocks at a certain heights,
 // both canonical and reorged forks included.
 func ReadAllHashes(db ethdb.Iteratee, number uint64) []common.Hash {
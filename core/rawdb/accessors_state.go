@@ -118,3 +118,26 @@ func DeleteTrieNode(db ethdb.KeyValueWriter, hash common.Hash) {
 		log.Crit("Failed to delete trie node", "err", err)
 	}
 }
+
+// ReadOnlinePruneStatus retrieves the serialized status of the online state
+// pruning, which is only present while a pruning is in progress.
+func ReadOnlinePruneStatus(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(onlinePruneStatusKey)
+	return data
+}
+
+// WriteOnlinePruneStatus stores the serialized status of the online state
+// pruning to allow resuming it after a restart.
+func WriteOnlinePruneStatus(db ethdb.KeyValueWriter, status []byte) {
+	if err := db.Put(onlinePruneStatusKey, status); err != nil {
+		log.Crit("Failed to store online prune status", "err", err)
+	}
+}
+
+// DeleteOnlinePruneStatus deletes the serialized status of the online state
+// pruning.
+func DeleteOnlinePruneStatus(db ethdb.KeyValueWriter) {
+	if err := db.Delete(onlinePruneStatusKey); err != nil {
+		log.Crit("Failed to remove online prune status", "err", err)
+	}
+}
@@ -34,6 +34,10 @@ import (
 )
 
 var (
+	// ErrHistoryPruned is returned if the user attempts to read ancient items
+	// which were deleted from the tail of the freezer.
+	ErrHistoryPruned = errors.New("pruned history unavailable")
+
 	// errReadOnly is returned if the freezer is opened in read only mode. All the
 	// mutations are disallowed.
 	errReadOnly = errors.New("read only")
@@ -184,6 +188,9 @@ func (f *Freezer) HasAncient(kind string, number uint64) (bool, error) {
 // Ancient retrieves an ancient binary blob from the append-only immutable files.
 func (f *Freezer) Ancient(kind string, number uint64) ([]byte, error) {
 	if table := f.tables[kind]; table != nil {
+		if number < atomic.LoadUint64(&f.tail) {
+			return nil, ErrHistoryPruned
+		}
 		return table.Retrieve(number)
 	}
 	return nil, errUnknownTable
@@ -196,6 +203,9 @@ func (f *Freezer) Ancient(kind string, number uint64) ([]byte, error) {
 //   return as many items as fit into maxByteSize.
 func (f *Freezer) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
 	if table := f.tables[kind]; table != nil {
+		if start < atomic.LoadUint64(&f.tail) {
+			return nil, ErrHistoryPruned
+		}
 		return table.RetrieveItems(start, count, maxBytes)
 	}
 	return nil, errUnknownTable
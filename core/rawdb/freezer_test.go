@@ -121,6 +121,61 @@ func TestFreezerModifyRollback(t *testing.T) {
 	checkAncientCount(t, f2, "test", 0)
 }
 
+// This checks that the items deleted from the tail of the freezer are reported
+// as pruned, while the remaining ones keep their indices.
+func TestFreezerTruncateTailPruned(t *testing.T) {
+	t.Parallel()
+
+	f, dir := newFreezerForTesting(t, freezerTestTableDef)
+	_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := 0; i < 10; i++ {
+			if err := op.AppendRaw("test", uint64(i), getChunk(256, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.TruncateTail(5))
+
+	check := func(f *Freezer) {
+		t.Helper()
+
+		if _, err := f.Ancient("test", 4); err != ErrHistoryPruned {
+			t.Fatalf("Ancient(4) returned wrong error %v, want %v", err, ErrHistoryPruned)
+		}
+		if _, err := f.AncientRange("test", 2, 5, 0); err != ErrHistoryPruned {
+			t.Fatalf("AncientRange(2, 5) returned wrong error %v, want %v", err, ErrHistoryPruned)
+		}
+		if err := CheckHistoryAvailable(f, 4); err != ErrHistoryPruned {
+			t.Fatalf("CheckHistoryAvailable(4) returned wrong error %v, want %v", err, ErrHistoryPruned)
+		}
+		for _, n := range []uint64{0, 5, 9, 10} {
+			if err := CheckHistoryAvailable(f, n); err != nil {
+				t.Fatalf("CheckHistoryAvailable(%d) returned unexpected error %v", n, err)
+			}
+		}
+		for i := 5; i < 10; i++ {
+			v, err := f.Ancient("test", uint64(i))
+			require.NoError(t, err)
+			if !bytes.Equal(v, getChunk(256, i)) {
+				t.Fatalf("wrong value at %d: %x", i, v)
+			}
+		}
+		checkAncientCount(t, f, "test", 10)
+	}
+	check(f)
+	f.Close()
+
+	// Reopen and check that the tail is retained.
+	f2, err := NewFreezer(dir, "", false, 2049, freezerTestTableDef)
+	if err != nil {
+		t.Fatalf("can't reopen freezer: %v", err)
+	}
+	defer f2.Close()
+	check(f2)
+}
+
 // This test runs ModifyAncients and Ancient concurrently with each other.
 func TestFreezerConcurrentModifyRetrieve(t *testing.T) {
 	t.Parallel()
@@ -117,9 +117,15 @@ func NewDatabase(db ethdb.Database) Database {
 // is safe for concurrent use and retains a lot of collapsed RLP trie nodes in a
 // large memory cache.
 func NewDatabaseWithConfig(db ethdb.Database, config *trie.Config) Database {
+	return NewDatabaseWithNodeDB(db, trie.NewDatabaseWithConfig(db, config))
+}
+
+// NewDatabaseWithNodeDB creates a state database on top of an already opened
+// trie database, which allows sharing its in-memory state between users.
+func NewDatabaseWithNodeDB(db ethdb.Database, triedb *trie.Database) Database {
 	csc, _ := lru.New(codeSizeCacheSize)
 	return &cachingDB{
-		db:            trie.NewDatabaseWithConfig(db, config),
+		db:            triedb,
 		codeSizeCache: csc,
 		codeCache:     fastcache.New(codeCacheSize),
 	}
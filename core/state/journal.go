@@ -116,6 +116,17 @@ type (
 		account            *common.Address
 		prevcode, prevhash []byte
 	}
+	finaliseChange struct {
+		object      *stateObject
+		deleted     bool                   // whether the object was already deleted
+		dirty       Storage                // dirty slots moved into the pending ones
+		pending     Storage                // pending slots overwritten by the dirty ones
+		wasPending  bool                   // whether the object was already pending
+		wasDirty    bool                   // whether the object was already dirty
+		destructed  bool                   // whether the snapshot destruction was already marked
+		snapAccount []byte                 // snapshot account data dropped by the destruction
+		snapStorage map[common.Hash][]byte // snapshot storage data dropped by the destruction
+	}
 
 	// Changes to other state values.
 	refundChange struct {
@@ -205,6 +216,43 @@ func (ch codeChange) dirtied() *common.Address {
 	return ch.account
 }
 
+func (ch finaliseChange) revert(s *StateDB) {
+	obj := ch.object
+	obj.deleted = ch.deleted
+	for key := range ch.dirty {
+		if value, ok := ch.pending[key]; ok {
+			obj.pendingStorage[key] = value
+		} else {
+			delete(obj.pendingStorage, key)
+		}
+	}
+	if ch.dirty != nil {
+		obj.dirtyStorage = ch.dirty
+	}
+
+	if !ch.wasPending {
+		delete(s.stateObjectsPending, obj.address)
+	}
+	if !ch.wasDirty {
+		delete(s.stateObjectsDirty, obj.address)
+	}
+	if s.snap != nil {
+		if !ch.destructed {
+			delete(s.snapDestructs, obj.addrHash)
+		}
+		if ch.snapAccount != nil {
+			s.snapAccounts[obj.addrHash] = ch.snapAccount
+		}
+		if ch.snapStorage != nil {
+			s.snapStorage[obj.addrHash] = ch.snapStorage
+		}
+	}
+}
+
+func (ch finaliseChange) dirtied() *common.Address {
+	return nil
+}
+
 func (ch storageChange) revert(s *StateDB) {
 	s.getStateObject(*ch.account).setState(ch.key, ch.prevalue)
 }
@@ -0,0 +1,470 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// onlineBloomFilePrefix is the filename prefix of the state bloom used by
+	// the online pruning. It's intentionally distinct from the offline one so
+	// that the two procedures never pick up each other's filters.
+	onlineBloomFilePrefix = "onlinebloom"
+
+	// onlinePruneBatchItems is the number of stale trie nodes deleted at once,
+	// after which the sweeping progress is persisted.
+	onlinePruneBatchItems = ethdb.IdealBatchSize / common.HashLength
+)
+
+// ErrPruneInterrupted is returned if the online pruning is interrupted by the
+// shutdown. It is resumed in the next restart.
+var ErrPruneInterrupted = errors.New("pruning interrupted")
+
+// The phases of the online state pruning.
+const (
+	OnlinePrunePhaseGenerating = "generating" // Live state is being collected into the bloom
+	OnlinePrunePhaseSweeping   = "sweeping"   // Stale trie nodes are being deleted
+)
+
+// onlinePruneStatus is the persisted status of the online state pruning, used
+// to resume the procedure after a restart.
+type onlinePruneStatus struct {
+	Target    common.Hash // State root used as the base of the live state
+	BloomSize uint64      // Megabytes of memory allocated to the state bloom
+	Marker    []byte      // Database key the sweeping has reached
+	Deleted   uint64      // Number of stale trie nodes deleted so far
+	Size      uint64      // Storage size of the stale trie nodes deleted so far
+}
+
+// OnlinePruneProgress is the progress report of the online state pruning.
+type OnlinePruneProgress struct {
+	Running  bool               // Whether the pruning is still running
+	Phase    string             // Phase the pruning is in
+	Target   common.Hash        // State root used as the base of the live state
+	Started  time.Time          // Time the pruning was started or resumed
+	Deleted  uint64             // Number of stale trie nodes deleted so far
+	Size     common.StorageSize // Storage size of the stale trie nodes deleted so far
+	Progress float64            // Percentage of the database key space swept
+	Error    string             // Error aborting the pruning, if any
+}
+
+// OnlinePruner removes the stale state from the database while the chain is
+// still running on top of it. The workflow is similar to the offline pruner:
+//
+//   - collect the live state into a bloom filter: the entire state of the target
+//     and the differences of all the recent states compared to it
+//   - iterate the database, delete all trie nodes which are not in the bloom
+//
+// To allow the chain to keep committing state in the meantime, every trie node
+// persisted by the trie database is also added to the bloom filter, and stale
+// nodes are only deleted while node persistence is blocked.
+//
+// Contract codes stored with the prefixed scheme are never deleted, as they are
+// written by the state database directly, bypassing the trie database. The live
+// legacy ones, keyed by their bare hash, are retained via the bloom filter too.
+type OnlinePruner struct {
+	db        ethdb.Database
+	triedb    *trie.Database
+	bloom     *stateBloom
+	bloomPath string
+	status    *onlinePruneStatus
+	phase     string
+	started   time.Time
+	roots     []common.Hash // Recent states to retain besides the target
+	pinned    []common.Hash // Recent states pinned in memory until collected
+	lock      sync.RWMutex  // Lock protecting the status and phase
+}
+
+// NewOnlinePruner creates an online pruner, which will retain the state of the
+// given target root and everything persisted after it. The target state must
+// be fully available in the disk database.
+func NewOnlinePruner(db ethdb.Database, triedb *trie.Database, datadir string, target common.Hash, bloomSize uint64) (*OnlinePruner, error) {
+	if triedb.Scheme() != rawdb.HashScheme {
+		return nil, errors.New("online pruning is only supported by the hash-based scheme")
+	}
+	if rawdb.ReadOnlinePruneStatus(db) != nil {
+		return nil, errors.New("online pruning already in progress")
+	}
+	if !rawdb.HasTrieNode(db, target) {
+		return nil, fmt.Errorf("associated state[%x] is not present", target)
+	}
+	bloom, err := newStateBloomWithSize(bloomSize)
+	if err != nil {
+		return nil, err
+	}
+	pruner := &OnlinePruner{
+		db:        db,
+		triedb:    triedb,
+		bloom:     bloom,
+		bloomPath: onlineBloomFilterName(datadir, target),
+		status:    &onlinePruneStatus{Target: target, BloomSize: bloomSize},
+		phase:     OnlinePrunePhaseGenerating,
+		started:   time.Now(),
+	}
+	pruner.writeStatus()
+	triedb.SetGuard(pruner.guard)
+	return pruner, nil
+}
+
+// ResumeOnlinePruner recreates the online pruner interrupted by a shutdown or
+// crash, or returns nil if there's no pruning in progress. If the state bloom
+// was already committed, sweeping is continued from where it was left off,
+// otherwise the bloom is regenerated from scratch.
+func ResumeOnlinePruner(db ethdb.Database, triedb *trie.Database, datadir string) (*OnlinePruner, error) {
+	blob := rawdb.ReadOnlinePruneStatus(db)
+	if blob == nil {
+		return nil, nil
+	}
+	var status onlinePruneStatus
+	if err := rlp.DecodeBytes(blob, &status); err != nil {
+		return nil, err
+	}
+	pruner := &OnlinePruner{
+		db:        db,
+		triedb:    triedb,
+		bloomPath: onlineBloomFilterName(datadir, status.Target),
+		status:    &status,
+		started:   time.Now(),
+	}
+	if common.FileExist(pruner.bloomPath) {
+		bloom, err := NewStateBloomFromDisk(pruner.bloomPath)
+		if err != nil {
+			return nil, err
+		}
+		pruner.bloom, pruner.phase = bloom, OnlinePrunePhaseSweeping
+	} else {
+		bloom, err := newStateBloomWithSize(status.BloomSize)
+		if err != nil {
+			return nil, err
+		}
+		pruner.bloom, pruner.phase = bloom, OnlinePrunePhaseGenerating
+	}
+	triedb.SetGuard(pruner.guard)
+	log.Info("Resuming online state pruning", "target", status.Target, "phase", pruner.phase, "deleted", status.Deleted)
+	return pruner, nil
+}
+
+// Target returns the state root used as the base of the live state.
+func (p *OnlinePruner) Target() common.Hash {
+	return p.status.Target
+}
+
+// Progress returns the current progress of the pruning.
+func (p *OnlinePruner) Progress() OnlinePruneProgress {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	progress := OnlinePruneProgress{
+		Running: true,
+		Phase:   p.phase,
+		Target:  p.status.Target,
+		Started: p.started,
+		Deleted: p.status.Deleted,
+		Size:    common.StorageSize(p.status.Size),
+	}
+	if len(p.status.Marker) >= 8 {
+		progress.Progress = float64(binary.BigEndian.Uint64(p.status.Marker[:8])) / math.MaxUint64 * 100
+	}
+	return progress
+}
+
+// Retain sets the recent states which need to be retained besides the target.
+// The ones held in memory are pinned until they're collected into the live state,
+// otherwise their nodes flushed before the pruning started could be lost if they
+// got garbage collected in the meantime. It must be called before Run, whilst no
+// state is committed or dereferenced.
+func (p *OnlinePruner) Retain(roots []common.Hash) {
+	p.roots = roots
+	for _, root := range roots {
+		if p.triedb.Pin(root) {
+			p.pinned = append(p.pinned, root)
+		}
+	}
+}
+
+// unpin releases the recent states pinned in memory by Retain.
+func (p *OnlinePruner) unpin() {
+	for _, root := range p.pinned {
+		p.triedb.Dereference(root)
+	}
+	p.pinned = nil
+}
+
+// Run executes the pruning until completion or until the quit channel is
+// closed. The recent states set by Retain which are not available anymore are
+// silently skipped.
+func (p *OnlinePruner) Run(quit <-chan struct{}) error {
+	err := p.run(quit)
+	p.unpin()
+
+	switch {
+	case err == nil:
+		os.RemoveAll(p.bloomPath)
+		rawdb.DeleteOnlinePruneStatus(p.db)
+		p.triedb.SetGuard(nil)
+
+		log.Info("Online state pruning successful", "nodes", p.status.Deleted, "size", common.StorageSize(p.status.Size),
+			"elapsed", common.PrettyDuration(time.Since(p.started)))
+
+	case errors.Is(err, ErrPruneInterrupted):
+		// Leave everything as is, the pruning is resumed after the restart
+
+	default:
+		// Abort the pruning, but only drop the status if nothing was deleted
+		// yet. If sweeping was already started, it's resumed after restart.
+		p.triedb.SetGuard(nil)
+		if p.phase == OnlinePrunePhaseGenerating {
+			rawdb.DeleteOnlinePruneStatus(p.db)
+		}
+		log.Error("Online state pruning failed", "err", err)
+	}
+	return err
+}
+
+func (p *OnlinePruner) run(quit <-chan struct{}) error {
+	// Collect the entire target state into the bloom filter if it's not
+	// available yet, and commit it so that a crash can't lose it any more.
+	if p.phase == OnlinePrunePhaseGenerating {
+		start := time.Now()
+		if err := p.markState(common.Hash{}, p.status.Target, quit); err != nil {
+			return err
+		}
+		if err := extractGenesis(p.db, p.bloom); err != nil {
+			return err
+		}
+		log.Info("Collected target state for pruning", "root", p.status.Target, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	// Collect all the recent states which may differ from the target. These are
+	// usually held in memory, but some nodes might already be flushed to disk.
+	// If the bloom was committed previously, this also adds the nodes persisted
+	// after it was written.
+	for _, root := range p.roots {
+		if root == p.status.Target {
+			continue
+		}
+		if err := p.markState(p.status.Target, root, quit); err != nil {
+			if errors.Is(err, ErrPruneInterrupted) {
+				return err
+			}
+			// The state might have been garbage collected in the meantime
+			log.Debug("Skipping unavailable recent state", "root", root, "err", err)
+		}
+	}
+	p.unpin()
+
+	if p.phase == OnlinePrunePhaseGenerating {
+		log.Info("Writing online state bloom to disk", "name", p.bloomPath)
+		if err := p.bloom.Commit(p.bloomPath, p.bloomPath+stateBloomFileTempSuffix); err != nil {
+			return err
+		}
+		p.lock.Lock()
+		p.phase = OnlinePrunePhaseSweeping
+		p.lock.Unlock()
+	}
+	return p.sweep(quit)
+}
+
+// guard is the trie database callback adding all the newly persisted trie nodes
+// to the live state.
+func (p *OnlinePruner) guard(hash common.Hash) {
+	p.bloom.Put(hash.Bytes(), nil)
+}
+
+// live reports whether the trie node might belong to the live state.
+func (p *OnlinePruner) live(hash common.Hash) bool {
+	ok, _ := p.bloom.Contain(hash.Bytes())
+	return ok
+}
+
+// markState adds all the trie nodes of the state at root, including the storage
+// tries, and the contract code hashes into the bloom filter. If base is given,
+// the nodes shared with the state at base are skipped.
+func (p *OnlinePruner) markState(base, root common.Hash, quit <-chan struct{}) error {
+	return p.markTrie(common.Hash{}, base, root, quit, func(it trie.NodeIterator, baseTrie *trie.Trie) error {
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(it.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		if !bytes.Equal(acc.CodeHash, emptyCode) {
+			p.bloom.Put(acc.CodeHash, nil)
+		}
+		if acc.Root == emptyRoot {
+			return nil
+		}
+		var baseRoot common.Hash
+		if baseTrie != nil {
+			blob, err := baseTrie.TryGet(it.LeafKey())
+			if err != nil {
+				return err
+			}
+			if len(blob) > 0 {
+				var prev types.StateAccount
+				if err := rlp.DecodeBytes(blob, &prev); err != nil {
+					return err
+				}
+				baseRoot = prev.Root
+			}
+		}
+		if baseRoot == acc.Root {
+			return nil
+		}
+		return p.markTrie(common.BytesToHash(it.LeafKey()), baseRoot, acc.Root, quit, nil)
+	})
+}
+
+// markTrie adds all the nodes of the trie at root into the bloom filter, which
+// are not shared with the trie at base, if given. The leaf callback is invoked
+// for every new leaf.
+func (p *OnlinePruner) markTrie(owner, base, root common.Hash, quit <-chan struct{}, onleaf func(it trie.NodeIterator, base *trie.Trie) error) error {
+	t, err := trie.New(owner, root, p.triedb)
+	if err != nil {
+		return err
+	}
+	var (
+		baseTrie *trie.Trie
+		iter     = t.NodeIterator(nil)
+	)
+	if base != (common.Hash{}) && base != emptyRoot {
+		if baseTrie, err = trie.New(owner, base, p.triedb); err != nil {
+			return err
+		}
+		iter, _ = trie.NewDifferenceIterator(baseTrie.NodeIterator(nil), iter)
+	}
+	for iter.Next(true) {
+		select {
+		case <-quit:
+			return ErrPruneInterrupted
+		default:
+		}
+		// Embedded nodes don't have hash.
+		if hash := iter.Hash(); hash != (common.Hash{}) {
+			p.bloom.Put(hash.Bytes(), nil)
+		}
+		if iter.Leaf() && onleaf != nil {
+			if err := onleaf(iter, baseTrie); err != nil {
+				return err
+			}
+		}
+	}
+	return iter.Error()
+}
+
+// sweep iterates the database from the persisted marker and deletes all trie
+// nodes which are not part of the live state.
+func (p *OnlinePruner) sweep(quit <-chan struct{}) error {
+	var (
+		pending []common.Hash
+		sizes   []int
+		logged  = time.Now()
+		iter    = p.db.NewIterator(nil, p.status.Marker)
+	)
+	defer func() { iter.Release() }()
+
+	flush := func(marker []byte) error {
+		if err := p.triedb.DeleteStale(pending, p.live); err != nil {
+			return err
+		}
+		p.lock.Lock()
+		for i, hash := range pending {
+			// The bloom only grows, nodes not contained now weren't contained
+			// during the deletion either.
+			if !p.live(hash) {
+				p.status.Deleted++
+				p.status.Size += uint64(sizes[i])
+			}
+		}
+		p.status.Marker = common.CopyBytes(marker)
+		p.lock.Unlock()
+
+		p.writeStatus()
+		pending, sizes = pending[:0], sizes[:0]
+		return nil
+	}
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		if p.live(common.BytesToHash(key)) {
+			continue
+		}
+		pending = append(pending, common.BytesToHash(key))
+		sizes = append(sizes, len(key)+len(iter.Value()))
+
+		if len(pending) >= onlinePruneBatchItems {
+			if err := flush(key); err != nil {
+				return err
+			}
+			if time.Since(logged) > 8*time.Second {
+				progress := p.Progress()
+				log.Info("Pruning state data online", "nodes", progress.Deleted, "size", progress.Size,
+					"progress", fmt.Sprintf("%.2f%%", progress.Progress), "elapsed", common.PrettyDuration(time.Since(p.started)))
+				logged = time.Now()
+			}
+			// Recreate the iterator after every batch commit in order
+			// to allow the underlying compactor to delete the entries.
+			iter.Release()
+			iter = p.db.NewIterator(nil, key)
+
+			select {
+			case <-quit:
+				return ErrPruneInterrupted
+			default:
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return flush(p.status.Marker)
+	}
+	return nil
+}
+
+// writeStatus persists the current status of the pruning.
+func (p *OnlinePruner) writeStatus() {
+	p.lock.RLock()
+	blob, err := rlp.EncodeToBytes(p.status)
+	p.lock.RUnlock()
+
+	if err != nil {
+		log.Crit("Failed to encode online prune status", "err", err)
+	}
+	rawdb.WriteOnlinePruneStatus(p.db, blob)
+}
+
+func onlineBloomFilterName(datadir string, hash common.Hash) string {
+	return filepath.Join(datadir, fmt.Sprintf("%s.%s.%s", onlineBloomFilePrefix, hash.Hex(), stateBloomFileSuffix))
+}
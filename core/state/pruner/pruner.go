@@ -232,6 +232,11 @@ func prune(snaptree *snapshot.Tree, root common.Hash, maindb ethdb.Database, sta
 // specified state version. If user doesn't specify the state version, use
 // the bottom-most snapshot diff layer as the target.
 func (p *Pruner) Prune(root common.Hash) error {
+	// The online pruning must be finished first, otherwise it would delete the
+	// state retained by this one once resumed.
+	if rawdb.ReadOnlinePruneStatus(p.db) != nil {
+		return errors.New("online state pruning in progress, restart geth to finish it first")
+	}
 	// If the state bloom filter is already committed previously,
 	// reuse it for pruning instead of generating a new one. It's
 	// mandatory because a part of state may already be deleted,
@@ -734,7 +734,7 @@ func (t *Tree) Rebuild(root common.Hash) {
 // AccountIterator creates a new account iterator for the specified root hash and
 // seeks to a starting account hash.
 func (t *Tree) AccountIterator(root common.Hash, seek common.Hash) (AccountIterator, error) {
-	ok, err := t.generating()
+	ok, err := t.Generating()
 	if err != nil {
 		return nil, err
 	}
@@ -747,7 +747,7 @@ func (t *Tree) AccountIterator(root common.Hash, seek common.Hash) (AccountItera
 // StorageIterator creates a new storage iterator for the specified root hash and
 // account. The iterator will be move to the specific start position.
 func (t *Tree) StorageIterator(root common.Hash, account common.Hash, seek common.Hash) (StorageIterator, error) {
-	ok, err := t.generating()
+	ok, err := t.Generating()
 	if err != nil {
 		return nil, err
 	}
@@ -820,9 +820,9 @@ func (t *Tree) diskRoot() common.Hash {
 	return disklayer.Root()
 }
 
-// generating is an internal helper function which reports whether the snapshot
+// Generating is an external helper function which reports whether the snapshot
 // is still under the construction.
-func (t *Tree) generating() (bool, error) {
+func (t *Tree) Generating() (bool, error) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
@@ -107,6 +107,7 @@ type StateDB struct {
 	journal        *journal
 	validRevisions []revision
 	nextRevisionId int
+	retainJournal  bool // Whether the journal is retained across transactions
 
 	// Measurements gathered during execution for debugging purposes
 	AccountReads         time.Duration
@@ -790,6 +791,9 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 			// Thus, we can safely ignore it here
 			continue
 		}
+		if s.retainJournal {
+			s.journalFinalise(obj, deleteEmptyObjects)
+		}
 		if obj.suicided || (deleteEmptyObjects && obj.empty()) {
 			obj.deleted = true
 
@@ -816,10 +820,52 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 	if s.prefetcher != nil && len(addressesToPrefetch) > 0 {
 		s.prefetcher.prefetch(common.Hash{}, s.originalRoot, addressesToPrefetch)
 	}
+	if s.retainJournal {
+		s.journal.append(refundChange{prev: s.refund})
+		s.refund = 0
+		return
+	}
 	// Invalidate journal because reverting across transactions is not allowed.
 	s.clearJournalAndRefund()
 }
 
+// journalFinalise records the changes the finalisation is about to make to a
+// state object, so they can be reverted along with the transactions.
+func (s *StateDB) journalFinalise(obj *stateObject, deleteEmptyObjects bool) {
+	ch := finaliseChange{object: obj, deleted: obj.deleted}
+	if obj.suicided || (deleteEmptyObjects && obj.empty()) {
+		if s.snap != nil {
+			_, ch.destructed = s.snapDestructs[obj.addrHash]
+			ch.snapAccount = s.snapAccounts[obj.addrHash]
+			ch.snapStorage = s.snapStorage[obj.addrHash]
+		}
+	} else {
+		ch.dirty, ch.pending = obj.dirtyStorage, make(Storage)
+		for key := range obj.dirtyStorage {
+			if value, ok := obj.pendingStorage[key]; ok {
+				ch.pending[key] = value
+			}
+		}
+	}
+	_, ch.wasPending = s.stateObjectsPending[obj.address]
+	_, ch.wasDirty = s.stateObjectsDirty[obj.address]
+	s.journal.append(ch)
+}
+
+// RetainJournal sets whether the journal is retained when finalising the state,
+// which makes the snapshots revertible across transactions, e.g. to drop a group
+// of transactions at once. Disabling it clears the journal, as finalising the
+// state would have.
+//
+// Note, the intermediate roots can't be reverted, the journal may only be retained
+// while the transactions are finalised without computing them (since Byzantium).
+func (s *StateDB) RetainJournal(retain bool) {
+	s.retainJournal = retain
+	if !retain {
+		s.clearJournalAndRefund()
+	}
+}
+
 // IntermediateRoot computes the current root hash of the state trie.
 // It is called in between transactions to get the root hash that
 // goes into transaction receipts.
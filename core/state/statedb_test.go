@@ -695,6 +695,64 @@ func TestDeleteCreateRevert(t *testing.T) {
 	}
 }
 
+// Tests that the snapshots remain revertible across transactions while the journal
+// is retained, undoing the finalisation of the state between them too.
+func TestRetainJournal(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		account  = common.BytesToAddress([]byte("account"))
+		contract = common.BytesToAddress([]byte("contract"))
+		created  = common.BytesToAddress([]byte("created"))
+		slot     = common.HexToHash("0x01")
+	)
+	state.SetBalance(account, big.NewInt(1))
+	state.SetState(account, slot, common.HexToHash("0x01"))
+	state.SetBalance(contract, big.NewInt(1))
+	state.SetCode(contract, []byte{0x01})
+
+	root, _ := state.Commit(false)
+	state, _ = New(root, state.db, state.snaps)
+
+	state.RetainJournal(true)
+	id := state.Snapshot()
+
+	// Modify, create and destroy accounts over two transactions, the second one
+	// seeing the changes of the first as committed
+	state.SetState(account, slot, common.HexToHash("0x02"))
+	state.AddBalance(account, big.NewInt(1))
+	state.Suicide(contract)
+	state.SetBalance(created, big.NewInt(1))
+	state.Finalise(true)
+
+	if have := state.GetCommittedState(account, slot); have != common.HexToHash("0x02") {
+		t.Fatalf("committed slot mismatch: have %x, want 0x02", have)
+	}
+	if state.Exist(contract) {
+		t.Fatalf("self-destructed contract still alive")
+	}
+	state.SetState(account, slot, common.HexToHash("0x03"))
+	state.SetBalance(contract, big.NewInt(2))
+	state.Finalise(true)
+
+	// Revert both transactions and check that the original state is restored
+	state.RevertToSnapshot(id)
+	state.RetainJournal(false)
+
+	if have := state.GetState(account, slot); have != common.HexToHash("0x01") {
+		t.Errorf("slot mismatch: have %x, want 0x01", have)
+	}
+	if have := state.GetBalance(contract); have.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("contract balance mismatch: have %v, want 1", have)
+	}
+	if !bytes.Equal(state.GetCode(contract), []byte{0x01}) || state.Exist(created) {
+		t.Errorf("account existence not restored")
+	}
+	if have := state.IntermediateRoot(true); have != root {
+		t.Errorf("root mismatch: have %x, want %x", have, root)
+	}
+}
+
 // TestMissingTrieNodes tests that if the StateDB fails to load parts of the trie,
 // the Commit operation fails with an error
 // If we are missing trie nodes, we should not continue writing to the trie
@@ -0,0 +1,152 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ErrTxFiltered is returned if a transaction is rejected by one of the admission
+// filters of the transaction pool. The rejection reason is wrapped into it.
+var ErrTxFiltered = errors.New("transaction filtered")
+
+// TxFilter is an admission rule of the transaction pool, consulted for both the
+// local and the remote transactions before they enter the pool.
+type TxFilter interface {
+	// FilterTx returns nil if the transaction from the given sender is allowed
+	// into the pool, or the reason of its rejection otherwise.
+	FilterTx(tx *types.Transaction, from common.Address, local bool) error
+}
+
+// txListFilterConfig is the on-disk format of the allow and deny lists of a
+// TxListFilter.
+type txListFilterConfig struct {
+	AllowSenders    []common.Address `json:"allowSenders"`
+	DenySenders     []common.Address `json:"denySenders"`
+	AllowRecipients []common.Address `json:"allowRecipients"`
+	DenyRecipients  []common.Address `json:"denyRecipients"`
+}
+
+// addressSet is a set of addresses, nil if the corresponding list is not set.
+type addressSet map[common.Address]struct{}
+
+// newAddressSet creates a set from the given addresses, or nil if the list is
+// not set.
+func newAddressSet(addrs []common.Address) addressSet {
+	if addrs == nil {
+		return nil
+	}
+	set := make(addressSet, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = struct{}{}
+	}
+	return set
+}
+
+func (set addressSet) contains(addr common.Address) bool {
+	_, ok := set[addr]
+	return ok
+}
+
+// TxListFilter is a TxFilter admitting transactions based on the allow and deny
+// lists of their senders and recipients, loaded from a JSON file:
+//
+//	{
+//	  "allowSenders":    ["0x..."],
+//	  "denySenders":     ["0x..."],
+//	  "allowRecipients": ["0x..."],
+//	  "denyRecipients":  ["0x..."]
+//	}
+//
+// Any of the lists may be omitted. If an allow list is set, only the listed
+// addresses are admitted. Contract creations are not subject to the recipient
+// lists.
+type TxListFilter struct {
+	path string // Filesystem path to load the lists from
+
+	allowSenders    addressSet
+	denySenders     addressSet
+	allowRecipients addressSet
+	denyRecipients  addressSet
+	lock            sync.RWMutex
+}
+
+// NewTxListFilter creates a transaction filter with the lists loaded from the
+// given file.
+func NewTxListFilter(path string) (*TxListFilter, error) {
+	filter := &TxListFilter{path: path}
+	if err := filter.Reload(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// Reload replaces the allow and deny lists with the current contents of the file.
+// The lists are left unchanged if the file can't be loaded.
+func (f *TxListFilter) Reload() error {
+	blob, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	var config txListFilterConfig
+	if err := json.Unmarshal(blob, &config); err != nil {
+		return fmt.Errorf("invalid transaction filter list %s: %v", f.path, err)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.allowSenders = newAddressSet(config.AllowSenders)
+	f.denySenders = newAddressSet(config.DenySenders)
+	f.allowRecipients = newAddressSet(config.AllowRecipients)
+	f.denyRecipients = newAddressSet(config.DenyRecipients)
+
+	log.Info("Loaded transaction filter lists", "path", f.path,
+		"allowSenders", len(config.AllowSenders), "denySenders", len(config.DenySenders),
+		"allowRecipients", len(config.AllowRecipients), "denyRecipients", len(config.DenyRecipients))
+	return nil
+}
+
+// FilterTx implements TxFilter, checking the sender and the recipient of the
+// transaction against the lists.
+func (f *TxListFilter) FilterTx(tx *types.Transaction, from common.Address, local bool) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if f.allowSenders != nil && !f.allowSenders.contains(from) {
+		return fmt.Errorf("sender %x not allowed", from)
+	}
+	if f.denySenders.contains(from) {
+		return fmt.Errorf("sender %x denied", from)
+	}
+	if to := tx.To(); to != nil {
+		if f.allowRecipients != nil && !f.allowRecipients.contains(*to) {
+			return fmt.Errorf("recipient %x not allowed", *to)
+		}
+		if f.denyRecipients.contains(*to) {
+			return fmt.Errorf("recipient %x denied", *to)
+		}
+	}
+	return nil
+}
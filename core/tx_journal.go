@@ -17,9 +17,12 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"os"
+	"sort"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -178,3 +181,148 @@ func (journal *txJournal) close() error {
 	}
 	return err
 }
+
+// remoteTxJournalEntry is the journaled form of a remote transaction, retaining
+// the time it was first seen.
+type remoteTxJournalEntry struct {
+	Time uint64 // Unix time the transaction was first seen
+	Tx   *types.Transaction
+}
+
+// remoteTxJournal is a periodically regenerated dump of remote transactions with
+// the aim of allowing the pool to survive node restarts without waiting for the
+// transactions to be gossiped again. The dump is bounded by size and by the age
+// of the transactions.
+type remoteTxJournal struct {
+	path    string        // Filesystem path to store the transactions at
+	maxSize uint64        // Maximum size of the journal in bytes
+	maxAge  time.Duration // Maximum time since the journaled transactions were first seen
+}
+
+// newRemoteTxJournal creates a new remote transaction journal.
+func newRemoteTxJournal(path string, maxSize uint64, maxAge time.Duration) *remoteTxJournal {
+	return &remoteTxJournal{
+		path:    path,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+	}
+}
+
+// load parses a remote transaction journal dump from disk, loading the contents
+// not yet too old into the specified pool.
+func (journal *remoteTxJournal) load(add func([]*types.Transaction) []error) error {
+	// Skip the parsing if the journal file doesn't exist at all
+	if !common.FileExist(journal.path) {
+		return nil
+	}
+	input, err := os.Open(journal.path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	stream := rlp.NewStream(input, 0)
+	total, expired, dropped := 0, 0, 0
+
+	loadBatch := func(txs types.Transactions) {
+		for _, err := range add(txs) {
+			if err != nil {
+				log.Trace("Failed to add journaled remote transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	var (
+		failure error
+		batch   types.Transactions
+	)
+	for {
+		// Parse the next transaction and terminate on error
+		entry := new(remoteTxJournalEntry)
+		if err = stream.Decode(entry); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			if batch.Len() > 0 {
+				loadBatch(batch)
+			}
+			break
+		}
+		total++
+
+		// Drop the transactions which got too old while the node was down
+		seen := time.Unix(int64(entry.Time), 0)
+		if time.Since(seen) > journal.maxAge {
+			expired++
+			continue
+		}
+		entry.Tx.SetTime(seen)
+		if batch = append(batch, entry.Tx); batch.Len() > 1024 {
+			loadBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	log.Info("Loaded remote transaction journal", "transactions", total, "expired", expired, "dropped", dropped)
+
+	return failure
+}
+
+// rotate regenerates the remote transaction journal based on the current contents
+// of the transaction pool. The accounts with the best paying first transactions
+// are journaled first, until the size limit is reached. The contents are a snapshot
+// of the pool, so the journal can be written without holding the pool lock.
+func (journal *remoteTxJournal) rotate(all map[common.Address]types.Transactions) error {
+	accounts := make([]common.Address, 0, len(all))
+	for addr, txs := range all {
+		if len(txs) > 0 {
+			accounts = append(accounts, addr)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		if cmp := all[accounts[i]][0].GasTipCapCmp(all[accounts[j]][0]); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(accounts[i][:], accounts[j][:]) < 0
+	})
+	// Generate a new journal with the contents of the current pool
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	var (
+		journaled int
+		size      uint64
+	)
+	for _, addr := range accounts {
+		for _, tx := range all[addr] {
+			// Skip the rest of the account if the transaction is too old, the
+			// subsequent ones would be gapped anyway
+			if time.Since(tx.Time()) > journal.maxAge {
+				break
+			}
+			blob, err := rlp.EncodeToBytes(&remoteTxJournalEntry{Time: uint64(tx.Time().Unix()), Tx: tx})
+			if err != nil {
+				replacement.Close()
+				return err
+			}
+			if size+uint64(len(blob)) > journal.maxSize {
+				break
+			}
+			if _, err := replacement.Write(blob); err != nil {
+				replacement.Close()
+				return err
+			}
+			size += uint64(len(blob))
+			journaled++
+		}
+	}
+	replacement.Close()
+
+	// Replace the live journal with the newly generated one
+	if err = os.Rename(journal.path+".new", journal.path); err != nil {
+		return err
+	}
+	log.Info("Regenerated remote transaction journal", "transactions", journaled, "size", common.StorageSize(size))
+
+	return nil
+}
@@ -17,7 +17,9 @@
 package core
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sort"
@@ -85,6 +87,14 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrTxExpired is the reason of dropping a non-executable transaction which
+	// stayed in the queue longer than the configured lifetime.
+	ErrTxExpired = errors.New("transaction expired")
+
+	// ErrNonceGap is the reason of a transaction being queued if a transaction
+	// of the same account with a lower nonce is missing from the pool.
+	ErrNonceGap = errors.New("nonce gap")
 )
 
 var (
@@ -139,6 +149,62 @@ const (
 	TxStatusIncluded
 )
 
+// String implements fmt.Stringer.
+func (s TxStatus) String() string {
+	switch s {
+	case TxStatusUnknown:
+		return "unknown"
+	case TxStatusQueued:
+		return "queued"
+	case TxStatusPending:
+		return "pending"
+	case TxStatusIncluded:
+		return "included"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint(s))
+	}
+}
+
+// TxChangeKind is the kind of a status change of a transaction in the pool.
+type TxChangeKind uint8
+
+const (
+	TxChangeAdded    TxChangeKind = iota // Transaction entered the pool
+	TxChangePromoted                     // Transaction moved from the queue to the pending set
+	TxChangeDemoted                      // Transaction moved from the pending set back to the queue
+	TxChangeReplaced                     // Transaction was replaced by another one with the same nonce
+	TxChangeDropped                      // Transaction was removed from the pool
+	TxChangeIncluded                     // Transaction was removed from the pool after being included in a block
+)
+
+// String implements fmt.Stringer.
+func (k TxChangeKind) String() string {
+	switch k {
+	case TxChangeAdded:
+		return "added"
+	case TxChangePromoted:
+		return "promoted"
+	case TxChangeDemoted:
+		return "demoted"
+	case TxChangeReplaced:
+		return "replaced"
+	case TxChangeDropped:
+		return "dropped"
+	case TxChangeIncluded:
+		return "included"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(k))
+	}
+}
+
+// TxChange is a status change of a transaction in the pool.
+type TxChange struct {
+	Kind       TxChangeKind
+	Tx         *types.Transaction
+	ReplacedBy common.Hash // Hash of the replacing transaction, set for TxChangeReplaced
+	Reason     error       // Reason of the removal, set for TxChangeDropped
+}
+
 // blockChain provides the state of blockchain and current gas limit to do
 // some pre checks in tx pool and event subscribers.
 type blockChain interface {
@@ -156,6 +222,13 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	RemoteJournal     string        // Journal of remote transactions to survive node restarts (disabled if empty)
+	RemoteJournalSize uint64        // Maximum size of the remote transaction journal in bytes
+	RemoteJournalAge  time.Duration // Maximum time since the journaled remote transactions were first seen
+
+	FilterList string     // File of sender and recipient allow and deny lists to admit transactions by (disabled if empty)
+	Filters    []TxFilter `toml:"-"` // Admission filters consulted for every transaction entering the pool
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -173,6 +246,9 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	RemoteJournalSize: 64 * 1024 * 1024,
+	RemoteJournalAge:  3 * time.Hour,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -192,6 +268,14 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.RemoteJournal != "" && conf.RemoteJournalSize < 1 {
+		log.Warn("Sanitizing invalid txpool remote journal size", "provided", conf.RemoteJournalSize, "updated", DefaultTxPoolConfig.RemoteJournalSize)
+		conf.RemoteJournalSize = DefaultTxPoolConfig.RemoteJournalSize
+	}
+	if conf.RemoteJournal != "" && conf.RemoteJournalAge < 1 {
+		log.Warn("Sanitizing invalid txpool remote journal age", "provided", conf.RemoteJournalAge, "updated", DefaultTxPoolConfig.RemoteJournalAge)
+		conf.RemoteJournalAge = DefaultTxPoolConfig.RemoteJournalAge
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -237,6 +321,8 @@ type TxPool struct {
 	gasPrice    *big.Int
 	txFeed      event.Feed
 	scope       event.SubscriptionScope
+	changeFeed  event.Feed
+	changeScope event.SubscriptionScope
 	signer      types.Signer
 	mu          sync.RWMutex
 
@@ -251,6 +337,8 @@ type TxPool struct {
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
+	remoteJournal *remoteTxJournal // Journal of remote transactions to back up to disk
+
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
@@ -268,6 +356,9 @@ type TxPool struct {
 	initDoneCh      chan struct{}  // is closed once the pool is initialized (for tests)
 
 	changesSinceReorg int // A counter for how many drops we've performed in-between reorg.
+
+	changes  []*TxChange              // Status changes not yet sent to the change subscribers
+	included map[common.Hash]struct{} // Transactions included by the blocks of the running reset, tracked for the change subscribers
 }
 
 type txpoolResetRequest struct {
@@ -322,6 +413,15 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transaction journaling is enabled, load from disk. The remote
+	// transactions are validated against the current head like any new ones.
+	if config.RemoteJournal != "" {
+		pool.remoteJournal = newRemoteTxJournal(config.RemoteJournal, config.RemoteJournalSize, config.RemoteJournalAge)
+
+		if err := pool.remoteJournal.load(pool.AddRemotes); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -390,12 +490,15 @@ func (pool *TxPool) loop() {
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
+						pool.recordChange(TxChangeDropped, tx, common.Hash{}, ErrTxExpired)
 						pool.removeTx(tx.Hash(), true)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
+			changes := pool.takeChanges()
 			pool.mu.Unlock()
+			pool.sendChanges(changes)
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -406,6 +509,17 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.remoteJournal != nil {
+				// Snapshot the remote transactions under the lock, but write
+				// the potentially large journal without blocking the pool
+				pool.mu.Lock()
+				remotes := pool.remote()
+				pool.mu.Unlock()
+
+				if err := pool.remoteJournal.rotate(remotes); err != nil {
+					log.Warn("Failed to rotate remote tx journal", "err", err)
+				}
+			}
 		}
 	}
 }
@@ -414,6 +528,7 @@ func (pool *TxPool) loop() {
 func (pool *TxPool) Stop() {
 	// Unsubscribe all subscriptions registered from txpool
 	pool.scope.Close()
+	pool.changeScope.Close()
 
 	// Unsubscribe subscriptions registered from blockchain
 	pool.chainHeadSub.Unsubscribe()
@@ -422,6 +537,15 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.remoteJournal != nil {
+		pool.mu.Lock()
+		remotes := pool.remote()
+		pool.mu.Unlock()
+
+		if err := pool.remoteJournal.rotate(remotes); err != nil {
+			log.Warn("Failed to rotate remote tx journal", "err", err)
+		}
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -431,6 +555,85 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscripti
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeTxChangesEvent registers a subscription of TxChangesEvent and starts
+// sending the status changes of the pooled transactions to the given channel.
+// The changes are only tracked while there are subscribers.
+func (pool *TxPool) SubscribeTxChangesEvent(ch chan<- TxChangesEvent) event.Subscription {
+	return pool.changeScope.Track(pool.changeFeed.Subscribe(ch))
+}
+
+// recordChange records a status change of a transaction if anyone is subscribed
+// to the changes.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordChange(kind TxChangeKind, tx *types.Transaction, replacedBy common.Hash, reason error) {
+	if pool.changeScope.Count() == 0 {
+		return
+	}
+	pool.changes = append(pool.changes, &TxChange{
+		Kind:       kind,
+		Tx:         tx,
+		ReplacedBy: replacedBy,
+		Reason:     reason,
+	})
+}
+
+// recordStale records the removal of a transaction whose nonce was used up: it
+// was included if a block of the running reset contains it, dropped otherwise.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordStale(tx *types.Transaction) {
+	if _, ok := pool.included[tx.Hash()]; ok {
+		pool.recordChange(TxChangeIncluded, tx, common.Hash{}, nil)
+		return
+	}
+	pool.recordChange(TxChangeDropped, tx, common.Hash{}, ErrNonceTooLow)
+}
+
+// trackIncluded marks the given transactions as included by the blocks of the
+// running reset, if anyone is subscribed to the changes.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) trackIncluded(txs types.Transactions) {
+	if pool.changeScope.Count() == 0 {
+		return
+	}
+	if pool.included == nil {
+		pool.included = make(map[common.Hash]struct{}, len(txs))
+	}
+	for _, tx := range txs {
+		pool.included[tx.Hash()] = struct{}{}
+	}
+}
+
+// takeChanges retrieves and clears the recorded status changes.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) takeChanges() []*TxChange {
+	changes := pool.changes
+	pool.changes = nil
+	return changes
+}
+
+// sendChanges notifies the subscribers of the given status changes. It must be
+// called without holding the pool lock, the subscribers may block.
+func (pool *TxPool) sendChanges(changes []*TxChange) {
+	if len(changes) > 0 {
+		pool.changeFeed.Send(TxChangesEvent{changes})
+	}
+}
+
+// unpayableReason returns the reason of dropping a transaction which became too
+// costly for its sender or exceeds the block gas limit.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) unpayableReason(tx *types.Transaction) error {
+	if tx.Gas() > pool.currentMaxGas {
+		return ErrGasLimit
+	}
+	return ErrInsufficientFunds
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -443,7 +646,6 @@ func (pool *TxPool) GasPrice() *big.Int {
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasPrice(price *big.Int) {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
 
 	old := pool.gasPrice
 	pool.gasPrice = price
@@ -452,10 +654,14 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 		// pool.priced is sorted by GasFeeCap, so we have to iterate through pool.all instead
 		drop := pool.all.RemotesBelowTip(price)
 		for _, tx := range drop {
+			pool.recordChange(TxChangeDropped, tx, common.Hash{}, ErrUnderpriced)
 			pool.removeTx(tx.Hash(), false)
 		}
 		pool.priced.Removed(len(drop))
 	}
+	changes := pool.takeChanges()
+	pool.mu.Unlock()
+	pool.sendChanges(changes)
 
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
@@ -526,6 +732,54 @@ func (pool *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.
 	return pending, queued
 }
 
+// ContentFiltered retrieves a page of the transactions in the pool accepted by the
+// given filter, optionally restricted to a single sender. The pending transactions
+// come first and the queued ones after, each sorted by sender and nonce. Only the
+// page is copied out of the pool, and the returned flag reports whether there are
+// more matching transactions beyond it.
+func (pool *TxPool) ContentFiltered(from *common.Address, filter func(*types.Transaction) bool, offset, limit int) (types.Transactions, types.Transactions, bool) {
+	// Flattening caches the sorted lists, so the full lock is needed
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var (
+		pages   [2]types.Transactions
+		skipped int
+	)
+	for i, content := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		var accounts []common.Address
+		if from != nil {
+			if _, ok := content[*from]; ok {
+				accounts = append(accounts, *from)
+			}
+		} else {
+			accounts = make([]common.Address, 0, len(content))
+			for addr := range content {
+				accounts = append(accounts, addr)
+			}
+			sort.Slice(accounts, func(i, j int) bool {
+				return bytes.Compare(accounts[i][:], accounts[j][:]) < 0
+			})
+		}
+		for _, addr := range accounts {
+			for _, tx := range content[addr].txs.flatten() {
+				if !filter(tx) {
+					continue
+				}
+				if skipped < offset {
+					skipped++
+					continue
+				}
+				if len(pages[0])+len(pages[1]) == limit {
+					return pages[0], pages[1], true
+				}
+				pages[i] = append(pages[i], tx)
+			}
+		}
+	}
+	return pages[0], pages[1], false
+}
+
 // Pending retrieves all currently processable transactions, grouped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -581,6 +835,24 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remote retrieves all currently known remote transactions, grouped by origin
+// account and sorted by nonce. The returned transaction set is a copy and can be
+// freely modified by calling code.
+func (pool *TxPool) remote() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr, pending := range pool.pending {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], pending.Flatten()...)
+		}
+	}
+	for addr, queued := range pool.queue {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], queued.Flatten()...)
+		}
+	}
+	return txs
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -621,6 +893,12 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
+	// Consult the admission filters, both for the local and remote transactions
+	for _, filter := range pool.config.Filters {
+		if err := filter.FilterTx(tx, from, local); err != nil {
+			return fmt.Errorf("%w: %v", ErrTxFiltered, err)
+		}
+	}
 	// Drop non-local transactions under our own minimal accepted gas price or tip
 	if !local && tx.GasTipCapIntCmp(pool.gasPrice) < 0 {
 		return ErrUnderpriced
@@ -704,6 +982,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
+			pool.recordChange(TxChangeDropped, tx, common.Hash{}, ErrUnderpriced)
 			pool.removeTx(tx.Hash(), false)
 		}
 	}
@@ -721,7 +1000,9 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.recordChange(TxChangeReplaced, old, hash, nil)
 		}
+		pool.recordChange(TxChangeAdded, tx, common.Hash{}, nil)
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
 		pool.journalTx(from, tx)
@@ -747,6 +1028,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		localGauge.Inc(1)
 	}
 	pool.journalTx(from, tx)
+	pool.recordChange(TxChangeAdded, tx, common.Hash{}, nil)
 
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replaced, nil
@@ -772,6 +1054,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.recordChange(TxChangeReplaced, old, hash, nil)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -821,6 +1104,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.recordChange(TxChangeDropped, tx, common.Hash{}, ErrReplaceUnderpriced)
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -828,6 +1112,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.recordChange(TxChangeReplaced, old, hash, nil)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
@@ -975,6 +1260,70 @@ func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 	return status
 }
 
+// StatusWithReasons returns the status (unknown/pending/queued) of a batch of
+// transactions identified by their hashes, along with the reason why the pooled
+// ones can't be included into the next block, if any: a nonce gap in front of a
+// queued transaction, insufficient balance to pay for all the transactions of
+// the account up to it, or a fee cap below the pending base fee.
+func (pool *TxPool) StatusWithReasons(hashes []common.Hash) ([]TxStatus, []error) {
+	status := pool.Status(hashes)
+	reasons := make([]error, len(hashes))
+
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	for i, hash := range hashes {
+		if status[i] == TxStatusUnknown {
+			continue
+		}
+		// The transaction may have been included between the status lookup
+		// and obtaining the lock, in which case it's unknown by now
+		tx := pool.all.Get(hash)
+		if tx == nil {
+			status[i] = TxStatusUnknown
+			continue
+		}
+		from, _ := types.Sender(pool.signer, tx) // already validated
+		reasons[i] = pool.blockingReason(from, tx, status[i] == TxStatusQueued)
+	}
+	return status, reasons
+}
+
+// blockingReason returns the reason why a pooled transaction can't be included
+// into the next block, or nil if it's includable.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) blockingReason(from common.Address, tx *types.Transaction, queued bool) error {
+	// Queued transactions wait for the missing nonces in front of them
+	if list := pool.queue[from]; queued && list != nil {
+		for nonce := pool.pendingNonces.get(from); nonce < tx.Nonce(); nonce++ {
+			if list.txs.Get(nonce) == nil {
+				return ErrNonceGap
+			}
+		}
+	}
+	// All the transactions of the account up to this one must be affordable
+	cost := new(big.Int)
+	for _, list := range []*txList{pool.pending[from], pool.queue[from]} {
+		if list == nil {
+			continue
+		}
+		for nonce, ptx := range list.txs.items {
+			if nonce <= tx.Nonce() {
+				cost.Add(cost, ptx.Cost())
+			}
+		}
+	}
+	if pool.currentState.GetBalance(from).Cmp(cost) < 0 {
+		return ErrInsufficientFunds
+	}
+	// The fee cap must cover the base fee of the next block
+	if baseFee := pool.priced.urgent.baseFee; baseFee != nil && tx.GasFeeCapIntCmp(baseFee) < 0 {
+		return ErrFeeCapTooLow
+	}
+	return nil
+}
+
 // Get returns a transaction if it is contained in the pool and nil otherwise.
 func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all.Get(hash)
@@ -986,6 +1335,37 @@ func (pool *TxPool) Has(hash common.Hash) bool {
 	return pool.all.Get(hash) != nil
 }
 
+// Refilter removes all the transactions rejected by the admission filters, e.g.
+// after the rules of the filters changed.
+func (pool *TxPool) Refilter() {
+	pool.mu.Lock()
+
+	var filtered []*types.Transaction
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+		from, _ := types.Sender(pool.signer, tx) // already validated during insertion
+		for _, filter := range pool.config.Filters {
+			if err := filter.FilterTx(tx, from, local); err != nil {
+				log.Trace("Removing filtered transaction", "hash", hash, "err", err)
+				pool.recordChange(TxChangeDropped, tx, common.Hash{}, fmt.Errorf("%w: %v", ErrTxFiltered, err))
+				filtered = append(filtered, tx)
+				break
+			}
+		}
+		return true
+	}, true, true)
+
+	for _, tx := range filtered {
+		pool.removeTx(tx.Hash(), true)
+	}
+	changes := pool.takeChanges()
+	pool.mu.Unlock()
+	pool.sendChanges(changes)
+
+	if len(filtered) > 0 {
+		log.Info("Removed filtered transactions", "count", len(filtered))
+	}
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
@@ -1015,6 +1395,7 @@ func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
 			for _, tx := range invalids {
 				// Internal shuffle shouldn't touch the lookup set.
 				pool.enqueueTx(tx.Hash(), tx, false, false)
+				pool.recordChange(TxChangeDemoted, tx, common.Hash{}, nil)
 			}
 			// Update the account nonce if needed
 			pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1196,8 +1577,13 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
+	pool.included = nil
+	changes := pool.takeChanges()
 	pool.mu.Unlock()
 
+	// Notify subsystems of the transaction status changes
+	pool.sendChanges(changes)
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr, _ := types.Sender(pool.signer, tx)
@@ -1278,8 +1664,14 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 					}
 				}
 				reinject = types.TxDifference(discarded, included)
+				pool.trackIncluded(included)
 			}
 		}
+	} else if oldHead != nil && pool.changeScope.Count() > 0 {
+		// Regular chain progression, the included transactions are the new head's
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			pool.trackIncluded(block.Transactions())
+		}
 	}
 	// Initialize the internal state to the current head
 	if newHead == nil {
@@ -1324,6 +1716,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range forwards {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.recordStale(tx)
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1331,6 +1724,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.recordChange(TxChangeDropped, tx, common.Hash{}, pool.unpayableReason(tx))
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
@@ -1341,6 +1735,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			hash := tx.Hash()
 			if pool.promoteTx(addr, hash, tx) {
 				promoted = append(promoted, tx)
+				pool.recordChange(TxChangePromoted, tx, common.Hash{}, nil)
 			}
 		}
 		log.Trace("Promoted queued transactions", "count", len(promoted))
@@ -1353,6 +1748,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
+				pool.recordChange(TxChangeDropped, tx, common.Hash{}, ErrTxPoolOverflow)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
@@ -1415,6 +1811,7 @@ func (pool *TxPool) truncatePending() {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
+						pool.recordChange(TxChangeDropped, tx, common.Hash{}, ErrTxPoolOverflow)
 
 						// Update the account nonce to the dropped transaction
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
@@ -1442,6 +1839,7 @@ func (pool *TxPool) truncatePending() {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
 					pool.all.Remove(hash)
+					pool.recordChange(TxChangeDropped, tx, common.Hash{}, ErrTxPoolOverflow)
 
 					// Update the account nonce to the dropped transaction
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1488,6 +1886,7 @@ func (pool *TxPool) truncateQueue() {
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
+				pool.recordChange(TxChangeDropped, tx, common.Hash{}, ErrTxPoolOverflow)
 				pool.removeTx(tx.Hash(), true)
 			}
 			drop -= size
@@ -1497,6 +1896,7 @@ func (pool *TxPool) truncateQueue() {
 		// Otherwise drop only last few transactions
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+			pool.recordChange(TxChangeDropped, txs[i], common.Hash{}, ErrTxPoolOverflow)
 			pool.removeTx(txs[i].Hash(), true)
 			drop--
 			queuedRateLimitMeter.Mark(1)
@@ -1521,6 +1921,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range olds {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.recordStale(tx)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1529,6 +1930,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
+			pool.recordChange(TxChangeDropped, tx, common.Hash{}, pool.unpayableReason(tx))
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
@@ -1538,6 +1940,7 @@ func (pool *TxPool) demoteUnexecutables() {
 
 			// Internal shuffle shouldn't touch the lookup set.
 			pool.enqueueTx(hash, tx, false, false)
+			pool.recordChange(TxChangeDemoted, tx, common.Hash{}, nil)
 		}
 		pendingGauge.Dec(int64(len(olds) + len(drops) + len(invalids)))
 		if pool.locals.contains(addr) {
@@ -1552,6 +1955,7 @@ func (pool *TxPool) demoteUnexecutables() {
 
 				// Internal shuffle shouldn't touch the lookup set.
 				pool.enqueueTx(hash, tx, false, false)
+				pool.recordChange(TxChangeDemoted, tx, common.Hash{}, nil)
 			}
 			pendingGauge.Dec(int64(len(gapped)))
 			// This might happen in a reorg, so log it to the metering
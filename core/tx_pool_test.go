@@ -17,12 +17,15 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -2346,6 +2349,303 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions are journaled on shutdown within the size and
+// age limits, and revalidated when loaded on startup.
+func TestTransactionRemoteJournaling(t *testing.T) {
+	t.Parallel()
+
+	journal := filepath.Join(t.TempDir(), "remotes.rlp")
+
+	// Create the original pool to inject transaction into the journal
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.RemoteJournal = journal
+	config.RemoteJournalSize = 1024 * 1024
+	config.RemoteJournalAge = time.Hour
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	// Create a fresh and a stale remote account
+	fresh, _ := crypto.GenerateKey()
+	stale, _ := crypto.GenerateKey()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(fresh.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(stale.PublicKey), big.NewInt(1000000000))
+
+	old := pricedTransaction(0, 100000, big.NewInt(1), stale)
+	old.SetTime(time.Now().Add(-2 * config.RemoteJournalAge))
+
+	for _, tx := range []*types.Transaction{pricedTransaction(0, 100000, big.NewInt(1), fresh), pricedTransaction(1, 100000, big.NewInt(1), fresh), old} {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add remote transaction: %v", err)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+	// Terminate the old pool, bump the fresh nonce, create a new pool and ensure
+	// the still valid and recent transactions survive
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(fresh.PublicKey), 1)
+	blockchain = &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	<-pool.requestReset(nil, nil)
+
+	pending, queued := pool.Stats()
+	if pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	if queued != 0 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	pool.Stop()
+
+	// Restart with a journal too small for any transaction and ensure nothing
+	// survives
+	config.RemoteJournalSize = 1
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	pool.Stop()
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	<-pool.requestReset(nil, nil)
+
+	if pending, queued := pool.Stats(); pending+queued != 0 {
+		t.Fatalf("transactions mismatched: have %d, want %d", pending+queued, 0)
+	}
+	pool.Stop()
+}
+
+// Tests that the transactions rejected by the admission filters don't enter the
+// pool, and that the pooled ones are dropped if the filters change.
+func TestTransactionFiltering(t *testing.T) {
+	t.Parallel()
+
+	var (
+		allowed, _ = crypto.GenerateKey()
+		denied, _  = crypto.GenerateKey()
+		contract   = common.Address{0xde, 0xad}
+		list       = filepath.Join(t.TempDir(), "filter.json")
+	)
+	write := func(config string) {
+		if err := os.WriteFile(list, []byte(config), 0644); err != nil {
+			t.Fatalf("failed to write filter list: %v", err)
+		}
+	}
+	write(fmt.Sprintf(`{"allowSenders": ["%s", "%s"], "denySenders": ["%s"], "denyRecipients": ["%s"]}`,
+		crypto.PubkeyToAddress(allowed.PublicKey).Hex(), crypto.PubkeyToAddress(denied.PublicKey).Hex(),
+		crypto.PubkeyToAddress(denied.PublicKey).Hex(), contract.Hex()))
+
+	filter, err := NewTxListFilter(list)
+	if err != nil {
+		t.Fatalf("failed to load filter list: %v", err)
+	}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Filters = []TxFilter{filter}
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	stranger, _ := crypto.GenerateKey()
+	for _, key := range []*ecdsa.PrivateKey{allowed, denied, stranger} {
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	}
+	// Ensure the filters reject transactions on both the local and remote paths
+	if err := pool.AddLocal(transaction(0, 100000, denied)); !errors.Is(err, ErrTxFiltered) {
+		t.Errorf("denied local sender error mismatch: have %v, want %v", err, ErrTxFiltered)
+	}
+	if err := pool.addRemoteSync(transaction(0, 100000, stranger)); !errors.Is(err, ErrTxFiltered) {
+		t.Errorf("unlisted remote sender error mismatch: have %v, want %v", err, ErrTxFiltered)
+	}
+	tx, _ := types.SignTx(types.NewTransaction(0, contract, big.NewInt(100), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, allowed)
+	if err := pool.addRemoteSync(tx); !errors.Is(err, ErrTxFiltered) {
+		t.Errorf("denied recipient error mismatch: have %v, want %v", err, ErrTxFiltered)
+	}
+	if err := pool.addRemoteSync(transaction(0, 100000, allowed)); err != nil {
+		t.Fatalf("failed to add allowed transaction: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	// Deny the previously allowed sender and ensure its transaction is dropped
+	write(fmt.Sprintf(`{"denySenders": ["%s"]}`, crypto.PubkeyToAddress(allowed.PublicKey).Hex()))
+	if err := filter.Reload(); err != nil {
+		t.Fatalf("failed to reload filter list: %v", err)
+	}
+	pool.Refilter()
+
+	if pending, queued := pool.Stats(); pending+queued != 0 {
+		t.Fatalf("transactions mismatched: have %d, want %d", pending+queued, 0)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	if err := pool.addRemoteSync(transaction(0, 100000, stranger)); err != nil {
+		t.Fatalf("failed to add transaction of no longer unlisted sender: %v", err)
+	}
+}
+
+// Tests that the status changes of the pooled transactions are reported to the
+// change subscribers along with the replacements and the reasons of the drops.
+func TestTransactionChangeEvents(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	changes := make(chan TxChangesEvent, 32)
+	sub := pool.SubscribeTxChangesEvent(changes)
+	defer sub.Unsubscribe()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	var (
+		tx0         = transaction(0, 100000, key)
+		tx1         = transaction(1, 100000, key)
+		replacement = pricedTransaction(0, 100000, big.NewInt(1000), key)
+	)
+	// Add two executable transactions and replace the first one
+	if err := pool.addRemoteSync(tx0); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(tx1); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(replacement); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	// Make the replacement unaffordable, demoting the subsequent transaction
+	pool.mu.Lock()
+	pool.currentState.SetBalance(from, big.NewInt(1000000))
+	pool.mu.Unlock()
+	<-pool.requestReset(nil, nil)
+
+	// Mine the demoted transaction, dropping it as stale
+	testSetNonce(pool, from, 2)
+	<-pool.requestReset(nil, nil)
+
+	want := []TxChange{
+		{Kind: TxChangeAdded, Tx: tx0},
+		{Kind: TxChangePromoted, Tx: tx0},
+		{Kind: TxChangeAdded, Tx: tx1},
+		{Kind: TxChangePromoted, Tx: tx1},
+		{Kind: TxChangeReplaced, Tx: tx0, ReplacedBy: replacement.Hash()},
+		{Kind: TxChangeAdded, Tx: replacement},
+		{Kind: TxChangeDropped, Tx: replacement, Reason: ErrInsufficientFunds},
+		{Kind: TxChangeDemoted, Tx: tx1},
+		{Kind: TxChangeDropped, Tx: tx1, Reason: ErrNonceTooLow},
+	}
+	var have []*TxChange
+	for len(have) < len(want) {
+		select {
+		case ev := <-changes:
+			have = append(have, ev.Changes...)
+		case <-time.After(time.Second):
+			t.Fatalf("change #%d not fired", len(have))
+		}
+	}
+	if len(have) != len(want) {
+		t.Fatalf("change count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i, change := range have {
+		if change.Kind != want[i].Kind || change.Tx.Hash() != want[i].Tx.Hash() || change.ReplacedBy != want[i].ReplacedBy || change.Reason != want[i].Reason {
+			t.Errorf("change %d mismatch: have %v %x (replaced by %x, reason %v), want %v %x (replaced by %x, reason %v)", i,
+				change.Kind, change.Tx.Hash(), change.ReplacedBy, change.Reason, want[i].Kind, want[i].Tx.Hash(), want[i].ReplacedBy, want[i].Reason)
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// minedTestChain is a test chain serving a single mined block besides the head.
+type minedTestChain struct {
+	*testBlockChain
+	block *types.Block
+}
+
+func (bc *minedTestChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if bc.block != nil && bc.block.Hash() == hash {
+		return bc.block
+	}
+	return bc.testBlockChain.GetBlock(hash, number)
+}
+
+// Tests that the transactions removed from the pool after being mined are reported
+// as included, while the ones whose nonce was used up by others are dropped.
+func TestTransactionChangeEventsIncluded(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	chain := &minedTestChain{testBlockChain: &testBlockChain{10000000, statedb, new(event.Feed)}}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, chain)
+	defer pool.Stop()
+	<-pool.initDoneCh
+
+	changes := make(chan TxChangesEvent, 32)
+	sub := pool.SubscribeTxChangesEvent(changes)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	tx0, tx1 := transaction(0, 100000, key), transaction(1, 100000, key)
+	if err := pool.addRemoteSync(tx0); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(tx1); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// Mine the first transaction on top of the current head
+	head := chain.CurrentBlock().Header()
+	chain.block = types.NewBlock(&types.Header{ParentHash: head.Hash(), Number: big.NewInt(1), GasLimit: head.GasLimit, BaseFee: big.NewInt(params.InitialBaseFee)},
+		types.Transactions{tx0}, nil, nil, trie.NewStackTrie(nil))
+	testSetNonce(pool, from, 1)
+	<-pool.requestReset(head, chain.block.Header())
+
+	// Use up the nonce of the second one without mining it
+	testSetNonce(pool, from, 2)
+	<-pool.requestReset(nil, nil)
+
+	want := []TxChange{
+		{Kind: TxChangeAdded, Tx: tx0},
+		{Kind: TxChangePromoted, Tx: tx0},
+		{Kind: TxChangeAdded, Tx: tx1},
+		{Kind: TxChangePromoted, Tx: tx1},
+		{Kind: TxChangeIncluded, Tx: tx0},
+		{Kind: TxChangeDropped, Tx: tx1, Reason: ErrNonceTooLow},
+	}
+	var have []*TxChange
+	for len(have) < len(want) {
+		select {
+		case ev := <-changes:
+			have = append(have, ev.Changes...)
+		case <-time.After(time.Second):
+			t.Fatalf("change #%d not fired", len(have))
+		}
+	}
+	if len(have) != len(want) {
+		t.Fatalf("change count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i, change := range have {
+		if change.Kind != want[i].Kind || change.Tx.Hash() != want[i].Tx.Hash() || change.Reason != want[i].Reason {
+			t.Errorf("change %d mismatch: have %v %x (reason %v), want %v %x (reason %v)", i,
+				change.Kind, change.Tx.Hash(), change.Reason, want[i].Kind, want[i].Tx.Hash(), want[i].Reason)
+		}
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -2402,6 +2702,130 @@ func TestTransactionStatusCheck(t *testing.T) {
 	}
 }
 
+// Tests that the status retrievals report the reasons why the transactions are
+// not includable into the next block.
+func TestTransactionStatusReasons(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the status retrievals with
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	// Create the test accounts to check various transaction statuses with
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	txs := types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(1), keys[0]), // Includable
+		pricedTransaction(0, 100000, big.NewInt(1), keys[1]), // Includable
+		pricedTransaction(2, 100000, big.NewInt(1), keys[1]), // Nonce gap
+		pricedTransaction(0, 100000, big.NewInt(5), keys[2]), // Includable
+		pricedTransaction(1, 100000, big.NewInt(5), keys[2]), // Insufficient funds for both
+	}
+	pool.AddRemotesSync(txs)
+
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	hashes = append(hashes, common.Hash{})
+
+	check := func(status []TxStatus, reasons []error, wantStatus []TxStatus, wantReasons []error) {
+		t.Helper()
+		for i := range hashes {
+			if status[i] != wantStatus[i] {
+				t.Errorf("transaction %d: status mismatch: have %v, want %v", i, status[i], wantStatus[i])
+			}
+			if reasons[i] != wantReasons[i] {
+				t.Errorf("transaction %d: reason mismatch: have %v, want %v", i, reasons[i], wantReasons[i])
+			}
+		}
+	}
+	status, reasons := pool.StatusWithReasons(hashes)
+	check(status, reasons,
+		[]TxStatus{TxStatusPending, TxStatusPending, TxStatusQueued, TxStatusPending, TxStatusPending, TxStatusUnknown},
+		[]error{nil, nil, ErrNonceGap, nil, ErrInsufficientFunds, nil})
+
+	// Raise the base fee above the cheap transactions
+	pool.mu.Lock()
+	pool.priced.SetBaseFee(big.NewInt(2))
+	pool.mu.Unlock()
+
+	status, reasons = pool.StatusWithReasons(hashes)
+	check(status, reasons,
+		[]TxStatus{TxStatusPending, TxStatusPending, TxStatusQueued, TxStatusPending, TxStatusPending, TxStatusUnknown},
+		[]error{ErrFeeCapTooLow, ErrFeeCapTooLow, ErrNonceGap, nil, ErrInsufficientFunds, nil})
+}
+
+// Tests that the filtered pool content is paged in sender and nonce order, the
+// pending transactions first.
+func TestTransactionContentFiltered(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 2)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	if bytes.Compare(crypto.PubkeyToAddress(keys[0].PublicKey).Bytes(), crypto.PubkeyToAddress(keys[1].PublicKey).Bytes()) > 0 {
+		keys[0], keys[1] = keys[1], keys[0]
+	}
+	pool.AddRemotesSync(types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(1), keys[0]),
+		pricedTransaction(1, 100000, big.NewInt(2), keys[0]),
+		pricedTransaction(0, 100000, big.NewInt(1), keys[1]),
+		pricedTransaction(2, 100000, big.NewInt(2), keys[1]), // Queued
+	})
+	all := func(*types.Transaction) bool { return true }
+
+	describe := func(txs types.Transactions) []uint64 {
+		nonces := []uint64{}
+		for _, tx := range txs {
+			nonces = append(nonces, tx.Nonce())
+		}
+		return nonces
+	}
+	tests := []struct {
+		from    *common.Address
+		filter  func(*types.Transaction) bool
+		offset  int
+		limit   int
+		pending []uint64
+		queued  []uint64
+		more    bool
+	}{
+		{nil, all, 0, 10, []uint64{0, 1, 0}, []uint64{2}, false},
+		{nil, all, 0, 2, []uint64{0, 1}, []uint64{}, true},
+		{nil, all, 2, 2, []uint64{0}, []uint64{2}, false},
+		{nil, all, 3, 1, []uint64{}, []uint64{2}, false},
+		{nil, func(tx *types.Transaction) bool { return tx.GasPrice().Cmp(big.NewInt(2)) == 0 }, 0, 10, []uint64{1}, []uint64{2}, false},
+		{&common.Address{}, all, 0, 10, []uint64{}, []uint64{}, false},
+	}
+	for i, tt := range tests {
+		pending, queued, more := pool.ContentFiltered(tt.from, tt.filter, tt.offset, tt.limit)
+		if have := describe(pending); !reflect.DeepEqual(have, tt.pending) {
+			t.Errorf("test %d: pending mismatch: have %v, want %v", i, have, tt.pending)
+		}
+		if have := describe(queued); !reflect.DeepEqual(have, tt.queued) {
+			t.Errorf("test %d: queued mismatch: have %v, want %v", i, have, tt.queued)
+		}
+		if more != tt.more {
+			t.Errorf("test %d: more flag mismatch: have %v, want %v", i, more, tt.more)
+		}
+	}
+}
+
 // Test the transaction slots consumption is computed correctly
 func TestTransactionSlotCount(t *testing.T) {
 	t.Parallel()
@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
@@ -67,6 +68,44 @@ func (api *PublicEthereumAPI) Hashrate() hexutil.Uint64 {
 	return hexutil.Uint64(api.e.Miner().Hashrate())
 }
 
+// SendBundleArgs represents the arguments of a transaction bundle submission.
+type SendBundleArgs struct {
+	Txs          []hexutil.Bytes `json:"txs"`
+	BlockNumber  hexutil.Uint64  `json:"blockNumber"`
+	MinTimestamp *hexutil.Uint64 `json:"minTimestamp"`
+	MaxTimestamp *hexutil.Uint64 `json:"maxTimestamp"`
+}
+
+// SendBundle submits an ordered group of signed transactions to the miner, to be
+// included together, contiguously and all-or-nothing at the top of the target
+// block, if its timestamp is in the given range. It returns the bundle hash.
+func (api *PublicEthereumAPI) SendBundle(args SendBundleArgs) (common.Hash, error) {
+	var (
+		signer = types.LatestSigner(api.e.blockchain.Config())
+		bundle = &miner.Bundle{BlockNumber: uint64(args.BlockNumber)}
+	)
+	for i, input := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(input); err != nil {
+			return common.Hash{}, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		if _, err := types.Sender(signer, tx); err != nil {
+			return common.Hash{}, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		bundle.Txs = append(bundle.Txs, tx)
+	}
+	if args.MinTimestamp != nil {
+		bundle.MinTimestamp = uint64(*args.MinTimestamp)
+	}
+	if args.MaxTimestamp != nil {
+		bundle.MaxTimestamp = uint64(*args.MaxTimestamp)
+	}
+	if err := api.e.Miner().SendBundle(bundle); err != nil {
+		return common.Hash{}, err
+	}
+	return bundle.Hash(), nil
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -159,6 +198,19 @@ func NewPrivateAdminAPI(eth *Ethereum) *PrivateAdminAPI {
 	return &PrivateAdminAPI{eth: eth}
 }
 
+// ReloadTxFilter reloads the allow and deny lists of the transaction pool from
+// the configured file, and drops the pooled transactions they reject.
+func (api *PrivateAdminAPI) ReloadTxFilter() (bool, error) {
+	if api.eth.txFilter == nil {
+		return false, errors.New("transaction filter list not configured")
+	}
+	if err := api.eth.txFilter.Reload(); err != nil {
+		return false, err
+	}
+	api.eth.txPool.Refilter()
+	return true, nil
+}
+
 // ExportChain exports the current blockchain into a local file,
 // or a range of blocks if first and last are non-nil
 func (api *PrivateAdminAPI) ExportChain(file string, first *uint64, last *uint64) (bool, error) {
@@ -611,3 +663,52 @@ func (api *PrivateDebugAPI) GetAccessibleState(from, to rpc.BlockNumber) (uint64
 	}
 	return 0, fmt.Errorf("No state found")
 }
+
+// PruneState starts pruning the stale state in the background while the node
+// keeps running. The bloom size is the megabytes of memory used for tracking
+// the live state, defaulting to 2048.
+func (api *PrivateDebugAPI) PruneState(bloomSize *uint64) error {
+	size := uint64(2048)
+	if bloomSize != nil {
+		size = *bloomSize
+	}
+	if size < 256 {
+		return fmt.Errorf("bloom filter size too small: %d MB, minimum 256 MB", size)
+	}
+	if !api.eth.Synced() {
+		return errors.New("state pruning is only available after the initial sync")
+	}
+	return api.eth.BlockChain().PruneState(size)
+}
+
+// PruneStateResult is the result of a PruneStateProgress API call.
+type PruneStateResult struct {
+	Running  bool           `json:"running"`
+	Phase    string         `json:"phase"`
+	Target   common.Hash    `json:"target"`
+	Started  hexutil.Uint64 `json:"started"`
+	Deleted  hexutil.Uint64 `json:"deleted"`
+	Size     hexutil.Uint64 `json:"size"`
+	Progress float64        `json:"progress"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// PruneStateProgress returns the progress of the running online state pruning,
+// or of the last finished one. Nil is returned if no pruning was executed since
+// the startup.
+func (api *PrivateDebugAPI) PruneStateProgress() *PruneStateResult {
+	progress := api.eth.BlockChain().PruneStateProgress()
+	if progress == nil {
+		return nil
+	}
+	return &PruneStateResult{
+		Running:  progress.Running,
+		Phase:    progress.Phase,
+		Target:   progress.Target,
+		Started:  hexutil.Uint64(progress.Started.Unix()),
+		Deleted:  hexutil.Uint64(progress.Deleted),
+		Size:     hexutil.Uint64(progress.Size),
+		Progress: progress.Progress,
+		Error:    progress.Error,
+	}
+}
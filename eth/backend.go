@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
@@ -42,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	bftproto "github.com/ethereum/go-ethereum/eth/protocols/bft"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -57,6 +59,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Config contains the configuration options of the ETH protocol.
@@ -69,10 +72,12 @@ type Ethereum struct {
 
 	// Handlers
 	txPool             *core.TxPool
+	txFilter           *core.TxListFilter // Reloadable allow and deny lists of the transaction pool, if configured
 	blockchain         *core.BlockChain
 	handler            *handler
 	ethDialCandidates  enode.Iterator
 	snapDialCandidates enode.Iterator
+	bftHandler         *bftproto.Handler // Relay of the BFT consensus messages, nil unless BFT is used
 	merger             *consensus.Merger
 
 	// DB interfaces
@@ -136,7 +141,23 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
-	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.OverrideArrowGlacier, config.OverrideTerminalTotalDifficulty)
+	scheme, err := rawdb.ParseStateScheme(config.StateScheme, chainDb)
+	if err != nil {
+		return nil, err
+	}
+	if scheme == rawdb.PathScheme {
+		if config.NoPruning {
+			return nil, errors.New("archive mode is not supported by the path-based state scheme")
+		}
+		if config.SyncMode == downloader.SnapSync {
+			log.Warn("Snap sync is not supported by the path-based state scheme, switching to full sync")
+			config.SyncMode = downloader.FullSync
+		}
+	}
+	config.StateScheme = scheme
+
+	triedb := trie.NewDatabaseWithConfig(chainDb, &trie.Config{Scheme: scheme})
+	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, triedb, config.Genesis, config.OverrideArrowGlacier, config.OverrideTerminalTotalDifficulty)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
 	}
@@ -194,6 +215,10 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			StateScheme:         config.StateScheme,
+			StateHistory:        config.StateHistory,
+			ChainHistory:        config.ChainHistory,
+			StatePruneDir:       stack.ResolvePath(""),
 		}
 	)
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
@@ -211,7 +236,19 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
-	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
+	if config.TxPool.RemoteJournal != "" {
+		config.TxPool.RemoteJournal = stack.ResolvePath(config.TxPool.RemoteJournal)
+	}
+	poolConfig := config.TxPool
+	if poolConfig.FilterList != "" {
+		filter, err := core.NewTxListFilter(stack.ResolvePath(poolConfig.FilterList))
+		if err != nil {
+			return nil, err
+		}
+		eth.txFilter = filter
+		poolConfig.Filters = append(append([]core.TxFilter{}, poolConfig.Filters...), filter)
+	}
+	eth.txPool = core.NewTxPool(poolConfig, chainConfig, eth.blockchain)
 
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit + cacheConfig.SnapshotLimit
@@ -233,6 +270,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}); err != nil {
 		return nil, err
 	}
+	if engine := eth.bftEngine(); engine != nil {
+		eth.bftHandler = bftproto.NewHandler(engine)
+	}
 
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
@@ -427,6 +467,16 @@ func (s *Ethereum) shouldPreserve(header *types.Header) bool {
 	return s.isLocalBlock(header)
 }
 
+// bftEngine returns the BFT consensus engine, or nil if the chain is not using it.
+func (s *Ethereum) bftEngine() *bft.BFT {
+	engine := s.engine
+	if cl, ok := engine.(*beacon.Beacon); ok {
+		engine = cl.InnerEngine()
+	}
+	b, _ := engine.(*bft.BFT)
+	return b
+}
+
 // SetEtherbase sets the mining reward address.
 func (s *Ethereum) SetEtherbase(etherbase common.Address) {
 	s.lock.Lock()
@@ -481,6 +531,14 @@ func (s *Ethereum) StartMining(threads int) error {
 			}
 			cli.Authorize(eb, wallet.SignData)
 		}
+		if engine := s.bftEngine(); engine != nil {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			if wallet == nil || err != nil {
+				log.Error("Etherbase account unavailable locally", "err", err)
+				return fmt.Errorf("validator missing: %v", err)
+			}
+			engine.Authorize(eb, wallet.SignData)
+		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
 		atomic.StoreUint32(&s.handler.acceptTxs, 1)
@@ -532,6 +590,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	if s.bftHandler != nil {
+		protos = append(protos, bftproto.MakeProtocols(s.bftHandler)...)
+	}
 	return protos
 }
 
@@ -556,6 +617,11 @@ func (s *Ethereum) Start() error {
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers)
+
+	// Start agreeing on blocks with the other validators if BFT is used
+	if engine := s.bftEngine(); engine != nil {
+		engine.Start(s.blockchain, s.bftHandler)
+	}
 	return nil
 }
 
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -39,21 +40,27 @@ import (
 // Register adds catalyst APIs to the full node.
 func Register(stack *node.Node, backend *eth.Ethereum) error {
 	log.Warn("Catalyst mode enabled", "protocol", "eth")
+	api := NewConsensusAPI(backend)
 	stack.RegisterAPIs([]rpc.API{
 		{
 			Namespace:     "engine",
 			Version:       "1.0",
-			Service:       NewConsensusAPI(backend),
+			Service:       api,
 			Public:        true,
 			Authenticated: true,
 		},
 		{
 			Namespace:     "engine",
 			Version:       "1.0",
-			Service:       NewConsensusAPI(backend),
+			Service:       api,
 			Public:        true,
 			Authenticated: false,
 		},
+		{
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   NewConsensusDebugAPI(api),
+		},
 	})
 	return nil
 }
@@ -200,21 +207,21 @@ func (api *ConsensusAPI) ForkchoiceUpdatedV1(update beacon.ForkchoiceStateV1, pa
 	// sealed by the beacon client. The payload will be requested later, and we
 	// might replace it arbitrarily many times in between.
 	if payloadAttributes != nil {
-		// Create an empty block first which can be used as a fallback
-		empty, err := api.eth.Miner().GetSealingBlockSync(update.HeadBlockHash, payloadAttributes.Timestamp, payloadAttributes.SuggestedFeeRecipient, payloadAttributes.Random, true)
-		if err != nil {
-			log.Error("Failed to create empty sealing payload", "err", err)
-			return valid(nil), beacon.InvalidPayloadAttributes.With(err)
+		// Create an empty block first which can be used as a fallback, the full
+		// block is built and refined in the background until it's retrieved.
+		args := &miner.BuildPayloadArgs{
+			Parent:       update.HeadBlockHash,
+			Timestamp:    payloadAttributes.Timestamp,
+			FeeRecipient: payloadAttributes.SuggestedFeeRecipient,
+			Random:       payloadAttributes.Random,
 		}
-		// Send a request to generate a full block in the background.
-		// The result can be obtained via the returned channel.
-		resCh, err := api.eth.Miner().GetSealingBlockAsync(update.HeadBlockHash, payloadAttributes.Timestamp, payloadAttributes.SuggestedFeeRecipient, payloadAttributes.Random, false)
+		payload, err := api.eth.Miner().BuildPayload(args)
 		if err != nil {
-			log.Error("Failed to create async sealing payload", "err", err)
+			log.Error("Failed to build payload", "err", err)
 			return valid(nil), beacon.InvalidPayloadAttributes.With(err)
 		}
 		id := computePayloadId(update.HeadBlockHash, payloadAttributes)
-		api.localBlocks.put(id, &payload{empty: empty, result: resCh})
+		api.localBlocks.put(id, payload)
 		return valid(&id), nil
 	}
 	return valid(nil), nil
@@ -247,6 +254,17 @@ func (api *ConsensusAPI) ExchangeTransitionConfigurationV1(config beacon.Transit
 
 // GetPayloadV1 returns a cached payload by id.
 func (api *ConsensusAPI) GetPayloadV1(payloadID beacon.PayloadID) (*beacon.ExecutableDataV1, error) {
+	log.Trace("Engine API request received", "method", "GetPayload", "id", payloadID)
+	data := api.localBlocks.get(payloadID)
+	if data == nil {
+		return nil, beacon.UnknownPayload
+	}
+	return data.ExecutionPayload, nil
+}
+
+// GetPayloadV2 returns a cached payload by id, along with the priority fees paid
+// to its fee recipient.
+func (api *ConsensusAPI) GetPayloadV2(payloadID beacon.PayloadID) (*beacon.ExecutionPayloadEnvelope, error) {
 	log.Trace("Engine API request received", "method", "GetPayload", "id", payloadID)
 	data := api.localBlocks.get(payloadID)
 	if data == nil {
@@ -364,3 +382,26 @@ func (api *ConsensusAPI) invalid(err error, latestValid *types.Block) beacon.Pay
 	errorMsg := err.Error()
 	return beacon.PayloadStatusV1{Status: beacon.INVALID, LatestValidHash: &currentHash, ValidationError: &errorMsg}
 }
+
+// ConsensusDebugAPI exposes the payloads being built for the beacon client to
+// the debug namespace.
+type ConsensusDebugAPI struct {
+	api *ConsensusAPI
+}
+
+// NewConsensusDebugAPI creates a new debug api for the payloads built by the
+// given consensus api.
+func NewConsensusDebugAPI(api *ConsensusAPI) *ConsensusDebugAPI {
+	return &ConsensusDebugAPI{api: api}
+}
+
+// PayloadValue returns the priority fees paid to the fee recipient by the best
+// version of a payload built so far. Unlike GetPayload, it doesn't end the
+// refinement of the payload.
+func (api *ConsensusDebugAPI) PayloadValue(payloadID beacon.PayloadID) (*hexutil.Big, error) {
+	value := api.api.localBlocks.value(payloadID)
+	if value == nil {
+		return nil, beacon.UnknownPayload
+	}
+	return (*hexutil.Big)(value), nil
+}
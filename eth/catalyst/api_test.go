@@ -184,6 +184,63 @@ func TestEth2PrepareAndGetPayload(t *testing.T) {
 	}
 }
 
+func TestEth2GetPayloadValue(t *testing.T) {
+	genesis, blocks := generatePreMergeChain(10)
+	// We need to properly set the terminal total difficulty
+	genesis.Config.TerminalTotalDifficulty.Sub(genesis.Config.TerminalTotalDifficulty, blocks[9].Difficulty())
+	n, ethservice := startEthService(t, genesis, blocks[:9])
+	defer n.Close()
+
+	var (
+		api   = NewConsensusAPI(ethservice)
+		debug = NewConsensusDebugAPI(api)
+	)
+	// Put the 10th block's tx in the pool and produce a new block
+	ethservice.TxPool().AddLocals(blocks[9].Transactions())
+	blockParams := beacon.PayloadAttributesV1{
+		Timestamp: blocks[8].Time() + 5,
+	}
+	fcState := beacon.ForkchoiceStateV1{
+		HeadBlockHash: blocks[8].Hash(),
+	}
+	if _, err := api.ForkchoiceUpdatedV1(fcState, &blockParams); err != nil {
+		t.Fatalf("error preparing payload, err=%v", err)
+	}
+	payloadID := computePayloadId(fcState.HeadBlockHash, &blockParams)
+	if _, err := debug.PayloadValue(payloadID); err != nil {
+		t.Fatalf("error getting payload value, err=%v", err)
+	}
+	envelope, err := api.GetPayloadV2(payloadID)
+	if err != nil {
+		t.Fatalf("error getting payload, err=%v", err)
+	}
+	if len(envelope.ExecutionPayload.Transactions) != blocks[9].Transactions().Len() {
+		t.Fatalf("invalid number of transactions %d != 1", len(envelope.ExecutionPayload.Transactions))
+	}
+	want := new(big.Int)
+	for _, tx := range blocks[9].Transactions() {
+		tip, _ := tx.EffectiveGasTip(envelope.ExecutionPayload.BaseFeePerGas)
+		want.Add(want, new(big.Int).Mul(tip, new(big.Int).SetUint64(tx.Gas())))
+	}
+	if envelope.BlockValue.ToInt().Cmp(want) != 0 {
+		t.Fatalf("invalid block value: have %v, want %v", envelope.BlockValue, want)
+	}
+	// The value of a retrieved payload stays the same
+	if value, _ := debug.PayloadValue(payloadID); value.ToInt().Cmp(want) != 0 {
+		t.Fatalf("invalid payload value: have %v, want %v", value, want)
+	}
+	// Test invalid payloadID
+	var invPayload beacon.PayloadID
+	copy(invPayload[:], payloadID[:])
+	invPayload[0] = ^invPayload[0]
+	if _, err := api.GetPayloadV2(invPayload); err == nil {
+		t.Fatal("expected error retrieving invalid payload")
+	}
+	if _, err := debug.PayloadValue(invPayload); err == nil {
+		t.Fatal("expected error retrieving invalid payload value")
+	}
+}
+
 func checkLogEvents(t *testing.T, logsCh <-chan []*types.Log, rmLogsCh <-chan core.RemovedLogsEvent, wantNew, wantRemoved int) {
 	t.Helper()
 
@@ -28,7 +28,11 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -169,6 +173,72 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// PendingBlockArgs are the options of a pending block subscription.
+type PendingBlockArgs struct {
+	FullTx   bool `json:"fullTx"`   // Include the transactions in full detail instead of their hashes
+	Receipts bool `json:"receipts"` // Include the receipts of the transactions
+}
+
+// PendingBlock creates a subscription that is triggered each time the miner
+// updates the pending block, i.e. on every recommit. The blocks are delivered
+// in the format of eth_getBlockByNumber("pending"), optionally extended with
+// the receipts of the transactions.
+func (api *PublicFilterAPI) PendingBlock(ctx context.Context, args *PendingBlockArgs) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if args == nil {
+		args = new(PendingBlockArgs)
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		blocks := make(chan core.PendingBlockEvent)
+		blocksSub := api.events.SubscribePendingBlocks(blocks)
+		defer blocksSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-blocks:
+				fields, err := marshalPendingBlock(ev, args, api.backend.ChainConfig())
+				if err != nil {
+					log.Warn("Failed to marshal pending block", "number", ev.Block.Number(), "err", err)
+					continue
+				}
+				notifier.Notify(rpcSub.ID, fields)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// marshalPendingBlock converts a pending block and its receipts into the RPC
+// representation requested by the subscriber.
+func marshalPendingBlock(ev core.PendingBlockEvent, args *PendingBlockArgs, config *params.ChainConfig) (map[string]interface{}, error) {
+	fields, err := ethapi.RPCMarshalBlock(ev.Block, true, args.FullTx, config)
+	if err != nil {
+		return nil, err
+	}
+	// Pending blocks need to nil out a few fields, like eth_getBlockByNumber
+	for _, field := range []string{"hash", "nonce", "miner"} {
+		fields[field] = nil
+	}
+	if args.Receipts {
+		receipts, err := ethapi.RPCMarshalReceipts(ev.Block, ev.Receipts, config)
+		if err != nil {
+			return nil, err
+		}
+		fields["receipts"] = receipts
+	}
+	return fields, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 //
@@ -52,6 +52,9 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// PendingBlocksSubscription queries each new version of the pending block
+	// along with its receipts
+	PendingBlocksSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -66,6 +69,8 @@ const (
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// pendingBlockChanSize is the size of channel listening to PendingBlockEvent.
+	pendingBlockChanSize = 10
 )
 
 type subscription struct {
@@ -76,6 +81,7 @@ type subscription struct {
 	logs      chan []*types.Log
 	hashes    chan []common.Hash
 	headers   chan *types.Header
+	blocks    chan core.PendingBlockEvent
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -88,20 +94,22 @@ type EventSystem struct {
 	lastHead  *types.Header
 
 	// Subscriptions
-	txsSub         event.Subscription // Subscription for new transaction event
-	logsSub        event.Subscription // Subscription for new log event
-	rmLogsSub      event.Subscription // Subscription for removed log event
-	pendingLogsSub event.Subscription // Subscription for pending log event
-	chainSub       event.Subscription // Subscription for new chain event
+	txsSub          event.Subscription // Subscription for new transaction event
+	logsSub         event.Subscription // Subscription for new log event
+	rmLogsSub       event.Subscription // Subscription for removed log event
+	pendingLogsSub  event.Subscription // Subscription for pending log event
+	chainSub        event.Subscription // Subscription for new chain event
+	pendingBlockSub event.Subscription // Subscription for pending block event
 
 	// Channels
-	install       chan *subscription         // install filter for event notification
-	uninstall     chan *subscription         // remove filter for event notification
-	txsCh         chan core.NewTxsEvent      // Channel to receive new transactions event
-	logsCh        chan []*types.Log          // Channel to receive new log event
-	pendingLogsCh chan []*types.Log          // Channel to receive new log event
-	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
-	chainCh       chan core.ChainEvent       // Channel to receive new chain event
+	install        chan *subscription          // install filter for event notification
+	uninstall      chan *subscription          // remove filter for event notification
+	txsCh          chan core.NewTxsEvent       // Channel to receive new transactions event
+	logsCh         chan []*types.Log           // Channel to receive new log event
+	pendingLogsCh  chan []*types.Log           // Channel to receive new log event
+	rmLogsCh       chan core.RemovedLogsEvent  // Channel to receive removed log event
+	chainCh        chan core.ChainEvent        // Channel to receive new chain event
+	pendingBlockCh chan core.PendingBlockEvent // Channel to receive new pending block event
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -112,15 +120,16 @@ type EventSystem struct {
 // or by stopping the given mux.
 func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 	m := &EventSystem{
-		backend:       backend,
-		lightMode:     lightMode,
-		install:       make(chan *subscription),
-		uninstall:     make(chan *subscription),
-		txsCh:         make(chan core.NewTxsEvent, txChanSize),
-		logsCh:        make(chan []*types.Log, logsChanSize),
-		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
-		pendingLogsCh: make(chan []*types.Log, logsChanSize),
-		chainCh:       make(chan core.ChainEvent, chainEvChanSize),
+		backend:        backend,
+		lightMode:      lightMode,
+		install:        make(chan *subscription),
+		uninstall:      make(chan *subscription),
+		txsCh:          make(chan core.NewTxsEvent, txChanSize),
+		logsCh:         make(chan []*types.Log, logsChanSize),
+		rmLogsCh:       make(chan core.RemovedLogsEvent, rmLogsChanSize),
+		pendingLogsCh:  make(chan []*types.Log, logsChanSize),
+		chainCh:        make(chan core.ChainEvent, chainEvChanSize),
+		pendingBlockCh: make(chan core.PendingBlockEvent, pendingBlockChanSize),
 	}
 
 	// Subscribe events
@@ -129,9 +138,10 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 	m.rmLogsSub = m.backend.SubscribeRemovedLogsEvent(m.rmLogsCh)
 	m.chainSub = m.backend.SubscribeChainEvent(m.chainCh)
 	m.pendingLogsSub = m.backend.SubscribePendingLogsEvent(m.pendingLogsCh)
+	m.pendingBlockSub = m.backend.SubscribePendingBlockEvent(m.pendingBlockCh)
 
 	// Make sure none of the subscriptions are empty
-	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil {
+	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil || m.pendingBlockSub == nil {
 		log.Crit("Subscribe for event system failed")
 	}
 
@@ -167,6 +177,7 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
+			case <-sub.f.blocks:
 			}
 		}
 
@@ -234,6 +245,7 @@ func (es *EventSystem) subscribeMinedPendingLogs(crit ethereum.FilterQuery, logs
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		blocks:    make(chan core.PendingBlockEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -251,6 +263,7 @@ func (es *EventSystem) subscribeLogs(crit ethereum.FilterQuery, logs chan []*typ
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		blocks:    make(chan core.PendingBlockEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -268,6 +281,7 @@ func (es *EventSystem) subscribePendingLogs(crit ethereum.FilterQuery, logs chan
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		blocks:    make(chan core.PendingBlockEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -284,6 +298,7 @@ func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscripti
 		logs:      make(chan []*types.Log),
 		hashes:    make(chan []common.Hash),
 		headers:   headers,
+		blocks:    make(chan core.PendingBlockEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -300,6 +315,24 @@ func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscript
 		logs:      make(chan []*types.Log),
 		hashes:    hashes,
 		headers:   make(chan *types.Header),
+		blocks:    make(chan core.PendingBlockEvent),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribePendingBlocks creates a subscription that writes each new version of
+// the pending block along with its receipts.
+func (es *EventSystem) SubscribePendingBlocks(blocks chan core.PendingBlockEvent) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       PendingBlocksSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		blocks:    blocks,
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -341,6 +374,12 @@ func (es *EventSystem) handleRemovedLogs(filters filterIndex, ev core.RemovedLog
 	}
 }
 
+func (es *EventSystem) handlePendingBlockEvent(filters filterIndex, ev core.PendingBlockEvent) {
+	for _, f := range filters[PendingBlocksSubscription] {
+		f.blocks <- ev
+	}
+}
+
 func (es *EventSystem) handleTxsEvent(filters filterIndex, ev core.NewTxsEvent) {
 	hashes := make([]common.Hash, 0, len(ev.Txs))
 	for _, tx := range ev.Txs {
@@ -448,6 +487,7 @@ func (es *EventSystem) eventLoop() {
 		es.rmLogsSub.Unsubscribe()
 		es.pendingLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
+		es.pendingBlockSub.Unsubscribe()
 	}()
 
 	index := make(filterIndex)
@@ -467,6 +507,8 @@ func (es *EventSystem) eventLoop() {
 			es.handlePendingLogs(index, ev)
 		case ev := <-es.chainCh:
 			es.handleChainEvent(index, ev)
+		case ev := <-es.pendingBlockCh:
+			es.handlePendingBlockEvent(index, ev)
 
 		case f := <-es.install:
 			if f.typ == MinedAndPendingLogsSubscription {
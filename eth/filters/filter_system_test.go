@@ -33,10 +33,12 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 var (
@@ -44,20 +46,25 @@ var (
 )
 
 type testBackend struct {
-	mux             *event.TypeMux
-	db              ethdb.Database
-	sections        uint64
-	txFeed          event.Feed
-	logsFeed        event.Feed
-	rmLogsFeed      event.Feed
-	pendingLogsFeed event.Feed
-	chainFeed       event.Feed
+	mux              *event.TypeMux
+	db               ethdb.Database
+	sections         uint64
+	txFeed           event.Feed
+	logsFeed         event.Feed
+	rmLogsFeed       event.Feed
+	pendingLogsFeed  event.Feed
+	pendingBlockFeed event.Feed
+	chainFeed        event.Feed
 }
 
 func (b *testBackend) ChainDb() ethdb.Database {
 	return b.db
 }
 
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
 func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
 	var (
 		hash common.Hash
@@ -122,6 +129,10 @@ func (b *testBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Su
 	return b.pendingLogsFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribePendingBlockEvent(ch chan<- core.PendingBlockEvent) event.Subscription {
+	return b.pendingBlockFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.chainFeed.Subscribe(ch)
 }
@@ -213,6 +224,52 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestPendingBlockSubscription tests if a pending block subscription returns the
+// pending blocks and their receipts as posted by the miner.
+func TestPendingBlockSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, deadline)
+
+		key, _ = crypto.GenerateKey()
+		signer = types.LatestSigner(params.TestChainConfig)
+		tx     = types.MustSignNewTx(key, signer, &types.LegacyTx{To: &common.Address{0x01}, Gas: params.TxGas, GasPrice: big.NewInt(params.InitialBaseFee)})
+
+		receipts = types.Receipts{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: params.TxGas, GasUsed: params.TxGas, TxHash: tx.Hash()}}
+		header   = &types.Header{Number: big.NewInt(1), GasLimit: params.GenesisGasLimit, BaseFee: big.NewInt(params.InitialBaseFee)}
+		block    = types.NewBlock(header, types.Transactions{tx}, nil, receipts, trie.NewStackTrie(nil))
+	)
+	blocks := make(chan core.PendingBlockEvent)
+	sub := api.events.SubscribePendingBlocks(blocks)
+	defer sub.Unsubscribe()
+
+	go backend.pendingBlockFeed.Send(core.PendingBlockEvent{Block: block, Receipts: receipts})
+
+	var ev core.PendingBlockEvent
+	select {
+	case ev = <-blocks:
+	case <-time.After(time.Second):
+		t.Fatal("pending block not delivered")
+	}
+	if ev.Block.Hash() != block.Hash() {
+		t.Fatalf("pending block mismatch: have %x, want %x", ev.Block.Hash(), block.Hash())
+	}
+	fields, err := marshalPendingBlock(ev, &PendingBlockArgs{FullTx: true, Receipts: true}, backend.ChainConfig())
+	if err != nil {
+		t.Fatalf("failed to marshal pending block: %v", err)
+	}
+	if fields["hash"] != nil {
+		t.Errorf("pending block hash not cleared: %v", fields["hash"])
+	}
+	marshalled := fields["receipts"].([]map[string]interface{})
+	if len(marshalled) != 1 || marshalled[0]["transactionHash"] != tx.Hash() || marshalled[0]["from"] != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("pending block receipts mismatch: %v", marshalled)
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,176 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// maxSeenMessages is the maximum number of message hashes to remember to avoid
+// processing and relaying a message multiple times.
+const maxSeenMessages = 16384
+
+// Backend defines the callback invoked on remote consensus messages.
+type Backend interface {
+	// HandleMessage is a callback to be invoked when a consensus message is
+	// received from a remote peer. The message is relayed to the other peers
+	// unless an error is returned.
+	HandleMessage(payload []byte) error
+}
+
+// Handler maintains the set of `bft` peers, relaying the consensus messages
+// between them and the local consensus engine.
+type Handler struct {
+	backend Backend
+	seen    *lru.Cache // Hashes of the messages already processed
+
+	peers map[string]*Peer // Currently connected `bft` peers
+	lock  sync.RWMutex     // Protects the peer set
+}
+
+// NewHandler creates a message relay for the given consensus backend.
+func NewHandler(backend Backend) *Handler {
+	seen, _ := lru.New(maxSeenMessages)
+	return &Handler{
+		backend: backend,
+		seen:    seen,
+		peers:   make(map[string]*Peer),
+	}
+}
+
+// MakeProtocols constructs the P2P protocol definitions for `bft`.
+func MakeProtocols(handler *Handler) []p2p.Protocol {
+	protocols := make([]p2p.Protocol, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version // Closure
+
+		protocols[i] = p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  protocolLengths[version],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return handler.RunPeer(NewPeer(version, p, rw))
+			},
+			NodeInfo: func() interface{} {
+				return nil
+			},
+			PeerInfo: func(id enode.ID) interface{} {
+				return nil
+			},
+		}
+	}
+	return protocols
+}
+
+// RunPeer registers a peer into the relay and processes its inbound messages
+// until the connection is torn down.
+func (h *Handler) RunPeer(peer *Peer) error {
+	h.lock.Lock()
+	if _, ok := h.peers[peer.ID()]; ok {
+		h.lock.Unlock()
+		peer.Close()
+		return p2p.DiscAlreadyConnected
+	}
+	h.peers[peer.ID()] = peer
+	h.lock.Unlock()
+
+	defer func() {
+		h.lock.Lock()
+		delete(h.peers, peer.ID())
+		h.lock.Unlock()
+
+		peer.Close()
+	}()
+	for {
+		if err := h.handleMessage(peer); err != nil {
+			peer.Log().Debug("Message handling failed in `bft`", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMessage is invoked whenever an inbound message is received from a
+// remote peer on the `bft` protocol. The remote connection is torn down upon
+// returning any error.
+func (h *Handler) handleMessage(peer *Peer) error {
+	// Read the next message from the remote peer, and ensure it's fully consumed
+	msg, err := peer.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("%w: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
+	}
+	defer msg.Discard()
+
+	if msg.Code != ConsensusMsg {
+		return fmt.Errorf("%w: %v", errInvalidMsgCode, msg.Code)
+	}
+	var payload []byte
+	if err := msg.Decode(&payload); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	hash := crypto.Keccak256Hash(payload)
+	peer.markMessage(hash)
+
+	// Process every message only once, and relay the ones accepted by the
+	// consensus engine. Invalid or stale messages are not penalized, as they
+	// may legitimately race with the local chain progress.
+	if ok, _ := h.seen.ContainsOrAdd(hash, struct{}{}); ok {
+		return nil
+	}
+	if err := h.backend.HandleMessage(payload); err != nil {
+		peer.Log().Trace("Dropped consensus message", "hash", hash, "err", err)
+		return nil
+	}
+	h.relay(hash, payload)
+	return nil
+}
+
+// Broadcast sends a local consensus message to all the connected peers.
+func (h *Handler) Broadcast(payload []byte) {
+	hash := crypto.Keccak256Hash(payload)
+	h.seen.Add(hash, struct{}{})
+	h.relay(hash, payload)
+}
+
+// relay queues a consensus message to all the peers not known to have it.
+func (h *Handler) relay(hash common.Hash, payload []byte) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	for _, peer := range h.peers {
+		if !peer.KnownMessage(hash) {
+			peer.AsyncSendMessage(hash, payload)
+		}
+	}
+}
+
+// PeerCount returns the number of connected `bft` peers.
+func (h *Handler) PeerCount() int {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return len(h.peers)
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// testBackend accepts every consensus message apart from the rejected ones.
+type testBackend struct {
+	rejected []byte
+	handled  [][]byte
+	lock     sync.Mutex
+}
+
+func (b *testBackend) HandleMessage(payload []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.handled = append(b.handled, payload)
+	if bytes.Equal(payload, b.rejected) {
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func (b *testBackend) count() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return len(b.handled)
+}
+
+// newTestPeer connects a fake peer to the handler, returning the remote side
+// of the connection.
+func newTestPeer(h *Handler, id string) *p2p.MsgPipeRW {
+	app, net := p2p.MsgPipe()
+	go h.RunPeer(NewFakePeer(BFT1, id, net))
+	return app
+}
+
+// expectMsg waits for a consensus message on the remote side of a peer.
+func expectMsg(t *testing.T, rw *p2p.MsgPipeRW, want []byte) {
+	t.Helper()
+
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	var payload []byte
+	if err := msg.Decode(&payload); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if !bytes.Equal(payload, want) {
+		t.Fatalf("message mismatch: have %x, want %x", payload, want)
+	}
+}
+
+// Tests that consensus messages are handled once and relayed to the peers not
+// known to have them, unless rejected by the backend.
+func TestRelay(t *testing.T) {
+	backend := &testBackend{rejected: []byte("invalid")}
+	handler := NewHandler(backend)
+
+	alice := newTestPeer(handler, "aaaaaaaaaaaaaaaa")
+	defer alice.Close()
+	bob := newTestPeer(handler, "bbbbbbbbbbbbbbbb")
+	defer bob.Close()
+
+	for handler.PeerCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// Send a rejected and a valid message from alice, only the latter is relayed
+	if err := p2p.Send(alice, ConsensusMsg, []byte("invalid")); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	if err := p2p.Send(alice, ConsensusMsg, []byte("valid")); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	expectMsg(t, bob, []byte("valid"))
+
+	// Send the same message back from bob, it must not be handled again
+	if err := p2p.Send(bob, ConsensusMsg, []byte("valid")); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	// Broadcast a local message, it must reach both peers
+	handler.Broadcast([]byte("local"))
+	expectMsg(t, alice, []byte("local"))
+	expectMsg(t, bob, []byte("local"))
+
+	if have := backend.count(); have != 2 {
+		t.Fatalf("handled message count mismatch: have %d, want %d", have, 2)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// maxKnownMessages is the maximum message hashes to keep in the known list
+	// before starting to randomly evict them.
+	maxKnownMessages = 4096
+
+	// maxQueuedMessages is the maximum number of consensus messages to queue up
+	// before dropping broadcasts.
+	maxQueuedMessages = 1024
+)
+
+// Peer is a collection of relevant information we have about a `bft` peer.
+type Peer struct {
+	id string // Unique ID for the peer, cached
+
+	*p2p.Peer                   // The embedded P2P package peer
+	rw        p2p.MsgReadWriter // Input/output streams for bft
+	version   uint              // Protocol version negotiated
+
+	known *lru.Cache    // Hashes of the messages known to be known by this peer
+	queue chan []byte   // Queue of messages to broadcast to the peer
+	term  chan struct{} // Termination channel to stop the broadcaster
+
+	logger log.Logger // Contextual logger with the peer id injected
+}
+
+// NewPeer create a wrapper for a network connection and negotiated  protocol
+// version.
+func NewPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	peer := newPeer(version, p.ID().String(), rw)
+	peer.Peer = p
+	return peer
+}
+
+// NewFakePeer create a fake bft peer without a backing p2p peer, for testing purposes.
+func NewFakePeer(version uint, id string, rw p2p.MsgReadWriter) *Peer {
+	return newPeer(version, id, rw)
+}
+
+// newPeer creates a peer and starts its broadcaster.
+func newPeer(version uint, id string, rw p2p.MsgReadWriter) *Peer {
+	known, _ := lru.New(maxKnownMessages)
+	peer := &Peer{
+		id:      id,
+		rw:      rw,
+		version: version,
+		known:   known,
+		queue:   make(chan []byte, maxQueuedMessages),
+		term:    make(chan struct{}),
+		logger:  log.New("peer", id[:8]),
+	}
+	go peer.broadcast()
+	return peer
+}
+
+// Close signals the broadcast goroutine to terminate. Only ever call this if
+// you created the peer yourself via NewPeer. Otherwise let whoever created it
+// clean it up!
+func (p *Peer) Close() {
+	close(p.term)
+}
+
+// ID retrieves the peer's unique identifier.
+func (p *Peer) ID() string {
+	return p.id
+}
+
+// Version retrieves the peer's negoatiated `bft` protocol version.
+func (p *Peer) Version() uint {
+	return p.version
+}
+
+// Log overrides the P2P logget with the higher level one containing only the id.
+func (p *Peer) Log() log.Logger {
+	return p.logger
+}
+
+// KnownMessage returns whether peer is known to already have a message.
+func (p *Peer) KnownMessage(hash common.Hash) bool {
+	return p.known.Contains(hash)
+}
+
+// markMessage marks a message as known for the peer, ensuring that it will never
+// be propagated to this particular peer.
+func (p *Peer) markMessage(hash common.Hash) {
+	p.known.Add(hash, struct{}{})
+}
+
+// AsyncSendMessage queues a consensus message for propagation to the remote
+// peer. If the peer's broadcast queue is full, the message is silently dropped.
+func (p *Peer) AsyncSendMessage(hash common.Hash, payload []byte) {
+	select {
+	case p.queue <- payload:
+		p.markMessage(hash)
+	default:
+		p.Log().Debug("Dropping consensus message propagation", "hash", hash)
+	}
+}
+
+// broadcast is a write loop that multiplexes consensus messages to the remote
+// peer. The goal is to have an async writer that does not lock up the consensus
+// engine.
+func (p *Peer) broadcast() {
+	for {
+		select {
+		case payload := <-p.queue:
+			if err := p2p.Send(p.rw, ConsensusMsg, payload); err != nil {
+				return
+			}
+		case <-p.term:
+			return
+		}
+	}
+}
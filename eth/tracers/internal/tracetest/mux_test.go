@@ -0,0 +1,123 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// runTracer executes the transaction of the given call tracer test case with
+// the named tracer attached and returns the produced result.
+func runTracer(t *testing.T, test *callTracerTest, name string, cfg json.RawMessage) json.RawMessage {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	var (
+		signer    = types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+		origin, _ = signer.Sender(tx)
+		txContext = vm.TxContext{
+			Origin:   origin,
+			GasPrice: tx.GasPrice(),
+		}
+		context = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Coinbase:    test.Context.Miner,
+			BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+			Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+			Difficulty:  (*big.Int)(test.Context.Difficulty),
+			GasLimit:    uint64(test.Context.GasLimit),
+		}
+		_, statedb = tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
+	)
+	tracer, err := tracers.New(name, new(tracers.Context), cfg)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", name, err)
+	}
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+	msg, err := tx.AsMessage(signer, nil)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// Tests that the mux tracer produces the same output for each of its children
+// as running them one by one would.
+func TestMuxTracer(t *testing.T) {
+	test := new(callTracerTest)
+	if blob, err := os.ReadFile(filepath.Join("testdata", "call_tracer", "deep_calls.json")); err != nil {
+		t.Fatalf("failed to read testcase: %v", err)
+	} else if err := json.Unmarshal(blob, test); err != nil {
+		t.Fatalf("failed to parse testcase: %v", err)
+	}
+	children := map[string]json.RawMessage{
+		"callTracer":     json.RawMessage(`{"withLog":true}`),
+		"prestateTracer": json.RawMessage(`{"diffMode":true}`),
+		"4byteTracer":    nil,
+	}
+	cfg, err := json.Marshal(children)
+	if err != nil {
+		t.Fatalf("failed to encode mux config: %v", err)
+	}
+	var have map[string]interface{}
+	if err := json.Unmarshal(runTracer(t, test, "muxTracer", cfg), &have); err != nil {
+		t.Fatalf("failed to unmarshal mux result: %v", err)
+	}
+	if len(have) != len(children) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(have), len(children))
+	}
+	for name, childCfg := range children {
+		var want interface{}
+		if err := json.Unmarshal(runTracer(t, test, name, childCfg), &want); err != nil {
+			t.Fatalf("failed to unmarshal %s result: %v", name, err)
+		}
+		if !reflect.DeepEqual(have[name], want) {
+			t.Errorf("%s result mismatch:\nhave %v\nwant %v", name, have[name], want)
+		}
+	}
+	// Nesting and empty configs are rejected
+	if _, err := tracers.New("muxTracer", nil, json.RawMessage(`{"muxTracer":{"callTracer":{}}}`)); err == nil {
+		t.Errorf("nested mux tracer should be rejected")
+	}
+	if _, err := tracers.New("muxTracer", nil, nil); err == nil {
+		t.Errorf("mux tracer without children should be rejected")
+	}
+}
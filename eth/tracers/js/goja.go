@@ -130,8 +130,9 @@ type jsTracer struct {
 // evaluates to an expression returning an object with certain methods.
 // The methods `result` and `fault` are required to be present.
 // The methods `step`, `enter`, and `exit` are optional, but note that
-// `enter` and `exit` always go together.
-func newJsTracer(code string, ctx *tracers.Context) (tracers.Tracer, error) {
+// `enter` and `exit` always go together. The optional method `setup` is
+// invoked once upon construction with the user supplied tracer config.
+func newJsTracer(code string, ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
 	if c, ok := assetTracers[code]; ok {
 		code = c
 	}
@@ -177,6 +178,16 @@ func newJsTracer(code string, ctx *tracers.Context) (tracers.Tracer, error) {
 		return nil, errors.New("trace object must expose either both or none of enter() and exit()")
 	}
 	t.traceFrame = hasEnter
+	// Setup is optional and will be called only if the tracer exposes it.
+	// The config is passed as a JSON string, the tracer has to parse it.
+	if setup, ok := goja.AssertFunction(obj.Get("setup")); ok {
+		if cfg == nil {
+			cfg = json.RawMessage("{}")
+		}
+		if _, err := setup(obj, vm.ToValue(string(cfg))); err != nil {
+			return nil, err
+		}
+	}
 	t.obj = obj
 	t.step = step
 	t.enter = enter
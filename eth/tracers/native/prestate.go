@@ -41,20 +41,53 @@ type account struct {
 	Storage map[common.Hash]common.Hash `json:"storage"`
 }
 
+// diffAccount is the representation of an account in the output of the
+// prestate tracer when running in diff mode. Only the fields that were
+// touched by the transaction are populated, the rest is omitted.
+type diffAccount struct {
+	Balance string                      `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    string                      `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// exists returns whether the account had any state before the transaction.
+func (a *account) exists() bool {
+	return a.Nonce > 0 || a.Code != "0x" || len(a.Storage) > 0 || a.Balance != "0x0"
+}
+
 type prestateTracer struct {
 	env       *vm.EVM
-	prestate  prestate
+	pre       prestate
+	post      map[common.Address]*diffAccount
 	create    bool
 	to        common.Address
 	gasLimit  uint64 // Amount of gas bought for the whole tx
+	config    prestateTracerConfig
 	interrupt uint32 // Atomic flag to signal execution interruption
 	reason    error  // Textual reason for the interruption
+	created   map[common.Address]bool
+	deleted   map[common.Address]bool
 }
 
-func newPrestateTracer(ctx *tracers.Context) tracers.Tracer {
-	// First callframe contains tx context info
-	// and is populated on start and end.
-	return &prestateTracer{prestate: prestate{}}
+type prestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // If true, this tracer will return state modifications
+}
+
+func newPrestateTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config prestateTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	return &prestateTracer{
+		pre:     prestate{},
+		post:    make(map[common.Address]*diffAccount),
+		config:  config,
+		created: make(map[common.Address]bool),
+		deleted: make(map[common.Address]bool),
+	}, nil
 }
 
 // CaptureStart implements the EVMLogger interface to initialize the tracing operation.
@@ -65,27 +98,39 @@ func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to commo
 
 	t.lookupAccount(from)
 	t.lookupAccount(to)
+	if t.config.DiffMode {
+		// The miner is credited with the fees at the end of the
+		// transaction, its balance therefore changes too.
+		t.lookupAccount(env.Context.Coinbase)
+	}
 
 	// The recipient balance includes the value transferred.
-	toBal := hexutil.MustDecodeBig(t.prestate[to].Balance)
+	toBal := hexutil.MustDecodeBig(t.pre[to].Balance)
 	toBal = new(big.Int).Sub(toBal, value)
-	t.prestate[to].Balance = hexutil.EncodeBig(toBal)
+	t.pre[to].Balance = hexutil.EncodeBig(toBal)
 
 	// The sender balance is after reducing: value and gasLimit.
 	// We need to re-add them to get the pre-tx balance.
-	fromBal := hexutil.MustDecodeBig(t.prestate[from].Balance)
+	fromBal := hexutil.MustDecodeBig(t.pre[from].Balance)
 	gasPrice := env.TxContext.GasPrice
 	consumedGas := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(t.gasLimit))
 	fromBal.Add(fromBal, new(big.Int).Add(value, consumedGas))
-	t.prestate[from].Balance = hexutil.EncodeBig(fromBal)
-	t.prestate[from].Nonce--
+	t.pre[from].Balance = hexutil.EncodeBig(fromBal)
+	t.pre[from].Nonce--
+
+	if create {
+		t.created[to] = true
+	}
 }
 
 // CaptureEnd is called after the call finishes to finalize the tracing.
 func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	if t.config.DiffMode {
+		return
+	}
 	if t.create {
 		// Exclude created contract.
-		delete(t.prestate, t.to)
+		delete(t.pre, t.to)
 	}
 }
 
@@ -101,20 +146,27 @@ func (t *prestateTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64,
 	case stackLen >= 1 && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT):
 		addr := common.Address(stackData[stackLen-1].Bytes20())
 		t.lookupAccount(addr)
+		if op == vm.SELFDESTRUCT {
+			t.deleted[scope.Contract.Address()] = true
+		}
 	case stackLen >= 5 && (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE):
 		addr := common.Address(stackData[stackLen-2].Bytes20())
 		t.lookupAccount(addr)
 	case op == vm.CREATE:
 		addr := scope.Contract.Address()
 		nonce := t.env.StateDB.GetNonce(addr)
-		t.lookupAccount(crypto.CreateAddress(addr, nonce))
+		addr = crypto.CreateAddress(addr, nonce)
+		t.lookupAccount(addr)
+		t.created[addr] = true
 	case stackLen >= 4 && op == vm.CREATE2:
 		offset := stackData[stackLen-2]
 		size := stackData[stackLen-3]
 		init := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
 		inithash := crypto.Keccak256(init)
 		salt := stackData[stackLen-4]
-		t.lookupAccount(crypto.CreateAddress2(scope.Contract.Address(), salt.Bytes32(), inithash))
+		addr := crypto.CreateAddress2(scope.Contract.Address(), salt.Bytes32(), inithash)
+		t.lookupAccount(addr)
+		t.created[addr] = true
 	}
 }
 
@@ -135,12 +187,96 @@ func (t *prestateTracer) CaptureTxStart(gasLimit uint64) {
 	t.gasLimit = gasLimit
 }
 
-func (t *prestateTracer) CaptureTxEnd(restGas uint64) {}
+func (t *prestateTracer) CaptureTxEnd(restGas uint64) {
+	if !t.config.DiffMode {
+		return
+	}
+	for addr, state := range t.pre {
+		// The deleted account's state is pruned from `post` but kept in `pre`
+		if _, ok := t.deleted[addr]; ok {
+			continue
+		}
+		var (
+			modified    = false
+			postAccount = &diffAccount{Storage: make(map[common.Hash]common.Hash)}
+			newBalance  = bigToHex(t.env.StateDB.GetBalance(addr))
+			newNonce    = t.env.StateDB.GetNonce(addr)
+			newCode     = bytesToHex(t.env.StateDB.GetCode(addr))
+		)
+		if newBalance != state.Balance {
+			modified = true
+			postAccount.Balance = newBalance
+		}
+		if newNonce != state.Nonce {
+			modified = true
+			postAccount.Nonce = newNonce
+		}
+		if newCode != state.Code {
+			modified = true
+			postAccount.Code = newCode
+		}
+		for key, val := range state.Storage {
+			// Omit empty slots from the pre state
+			if val == (common.Hash{}) {
+				delete(state.Storage, key)
+			}
+			newVal := t.env.StateDB.GetState(addr, key)
+			if val == newVal {
+				// Omit unchanged slots
+				delete(state.Storage, key)
+			} else {
+				modified = true
+				if newVal != (common.Hash{}) {
+					postAccount.Storage[key] = newVal
+				}
+			}
+		}
+		if modified {
+			t.post[addr] = postAccount
+		} else {
+			// If the state is not modified, no need to include it in the pre state
+			delete(t.pre, addr)
+		}
+	}
+	// The contract deployed by the transaction itself is already initialized
+	// (e.g. nonce bumped) when it's first looked up, drop its pre state.
+	if t.create {
+		delete(t.pre, t.to)
+	}
+	// The newly created contracts' prestate was empty, so delete them
+	for addr := range t.created {
+		// The created contract may have existed in the statedb before the creating tx
+		if s := t.pre[addr]; s != nil && !s.exists() {
+			delete(t.pre, addr)
+		}
+	}
+}
 
-// GetResult returns the json-encoded nested list of call traces, and any
-// error arising from the encoding or forceful termination (via `Stop`).
+// GetResult returns the json-encoded prestate of the accounts touched by the
+// transaction (or, in diff mode, its pre and post states), and any error
+// arising from the encoding or forceful termination (via `Stop`).
 func (t *prestateTracer) GetResult() (json.RawMessage, error) {
-	res, err := json.Marshal(t.prestate)
+	var res []byte
+	var err error
+	if t.config.DiffMode {
+		pre := make(map[common.Address]*diffAccount, len(t.pre))
+		for addr, state := range t.pre {
+			acc := &diffAccount{Nonce: state.Nonce, Storage: state.Storage}
+			if state.Balance != "0x0" {
+				acc.Balance = state.Balance
+			}
+			if state.Code != "0x" {
+				acc.Code = state.Code
+			}
+			pre[addr] = acc
+		}
+		res, err = json.Marshal(struct {
+			Pre  map[common.Address]*diffAccount `json:"pre"`
+			Post map[common.Address]*diffAccount `json:"post"`
+		}{pre, t.post})
+	} else {
+		res, err = json.Marshal(t.pre)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -156,10 +292,10 @@ func (t *prestateTracer) Stop(err error) {
 // lookupAccount fetches details of an account and adds it to the prestate
 // if it doesn't exist there.
 func (t *prestateTracer) lookupAccount(addr common.Address) {
-	if _, ok := t.prestate[addr]; ok {
+	if _, ok := t.pre[addr]; ok {
 		return
 	}
-	t.prestate[addr] = &account{
+	t.pre[addr] = &account{
 		Balance: bigToHex(t.env.StateDB.GetBalance(addr)),
 		Nonce:   t.env.StateDB.GetNonce(addr),
 		Code:    bytesToHex(t.env.StateDB.GetCode(addr)),
@@ -171,8 +307,8 @@ func (t *prestateTracer) lookupAccount(addr common.Address) {
 // it to the prestate of the given contract. It assumes `lookupAccount`
 // has been performed on the contract before.
 func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
-	if _, ok := t.prestate[addr].Storage[key]; ok {
+	if _, ok := t.pre[addr].Storage[key]; ok {
 		return
 	}
-	t.prestate[addr].Storage[key] = t.env.StateDB.GetState(addr, key)
+	t.pre[addr].Storage[key] = t.env.StateDB.GetState(addr, key)
 }
@@ -31,11 +31,15 @@ func init() {
 	register("noopTracerNative", newNoopTracer)
 }
 ```
+
+Constructors receive the tracer-specific configuration as a raw JSON message
+supplied by the user via `tracerConfig`. It is up to each tracer to decode it
+into its own config struct, an empty message means the defaults apply.
 */
 package native
 
 import (
-	"errors"
+	"encoding/json"
 
 	"github.com/ethereum/go-ethereum/eth/tracers"
 )
@@ -46,7 +50,7 @@ func init() {
 }
 
 // ctorFn is the constructor signature of a native tracer.
-type ctorFn = func(*tracers.Context) tracers.Tracer
+type ctorFn = func(*tracers.Context, json.RawMessage) (tracers.Tracer, error)
 
 /*
 ctors is a map of package-local tracer constructors.
@@ -71,12 +75,12 @@ func register(name string, ctor ctorFn) {
 }
 
 // lookup returns a tracer, if one can be matched to the given name.
-func lookup(name string, ctx *tracers.Context) (tracers.Tracer, error) {
+func lookup(name string, ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
 	if ctors == nil {
 		ctors = make(map[string]ctorFn)
 	}
 	if ctor, ok := ctors[name]; ok {
-		return ctor(ctx), nil
+		return ctor(ctx, cfg)
 	}
-	return nil, errors.New("no tracer found")
+	return nil, tracers.ErrTracerNotFound
 }
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
@@ -185,11 +186,16 @@ var (
 	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
 	testBalance = big.NewInt(2e15)
+
+	// revertAddr holds a contract reverting every call with the reason "no reason"
+	revertAddr = common.HexToAddress("0x0000000000000000000000000000000000000bad")
+	revertCode = common.FromHex("0x6308c379a060e01b60005260206004526009602452686e6f20726561736f6e60b81b60445260646000fd")
+	revertData = common.FromHex("0x08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000" + "96e6f20726561736f6e0000000000000000000000000000000000000000000000")
 )
 
 var genesis = &core.Genesis{
 	Config:    params.AllEthashProtocolChanges,
-	Alloc:     core.GenesisAlloc{testAddr: {Balance: testBalance}},
+	Alloc:     core.GenesisAlloc{testAddr: {Balance: testBalance}, revertAddr: {Code: revertCode, Balance: common.Big0}},
 	ExtraData: []byte("test genesis"),
 	Timestamp: 9000,
 	BaseFee:   big.NewInt(params.InitialBaseFee),
@@ -285,6 +291,9 @@ func TestEthClient(t *testing.T) {
 		"CallContract": {
 			func(t *testing.T) { testCallContract(t, client) },
 		},
+		"CallContractRevert": {
+			func(t *testing.T) { testCallContractRevert(t, client) },
+		},
 		"CallContractAtHash": {
 			func(t *testing.T) { testCallContractAtHash(t, client) },
 		},
@@ -294,6 +303,9 @@ func TestEthClient(t *testing.T) {
 		"TransactionSender": {
 			func(t *testing.T) { testTransactionSender(t, client) },
 		},
+		"BlockReceipts": {
+			func(t *testing.T) { testBlockReceipts(t, chain, client) },
+		},
 	}
 
 	t.Parallel()
@@ -564,6 +576,40 @@ func testCallContract(t *testing.T, client *rpc.Client) {
 	}
 }
 
+func testCallContractRevert(t *testing.T, client *rpc.Client) {
+	ec := NewClient(client)
+
+	msg := ethereum.CallMsg{
+		From: testAddr,
+		To:   &revertAddr,
+	}
+	check := func(method string, err error) {
+		t.Helper()
+
+		data, ok := bind.RevertData(err)
+		if !ok {
+			t.Fatalf("%s: missing revert data from error: %v", method, err)
+		}
+		if !bytes.Equal(data, revertData) {
+			t.Fatalf("%s: revert data mismatch: have %x, want %x", method, data, revertData)
+		}
+		var revert *bind.RevertError
+		if err := bind.UnpackError(err, nil); !errors.As(err, &revert) {
+			t.Fatalf("%s: unpacked error type mismatch: have %T, want %T", method, err, revert)
+		} else if revert.Reason != "no reason" {
+			t.Fatalf("%s: revert reason mismatch: have %q, want %q", method, revert.Reason, "no reason")
+		}
+	}
+	_, err := ec.EstimateGas(context.Background(), msg)
+	check("EstimateGas", err)
+
+	_, err = ec.CallContract(context.Background(), msg, nil)
+	check("CallContract", err)
+
+	_, err = ec.PendingCallContract(context.Background(), msg)
+	check("PendingCallContract", err)
+}
+
 func testAtFunctions(t *testing.T, client *rpc.Client) {
 	ec := NewClient(client)
 
@@ -692,3 +738,40 @@ func sendTransaction(ec *Client) error {
 	}
 	return ec.SendTransaction(context.Background(), tx)
 }
+
+func testBlockReceipts(t *testing.T, chain []*types.Block, client *rpc.Client) {
+	ec := NewClient(client)
+	ctx := context.Background()
+
+	byNumber, err := ec.BlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byHash, err := ec.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(chain[2].Hash(), false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(byNumber, byHash) {
+		t.Fatalf("receipts mismatch between number and hash lookup")
+	}
+	txs := chain[2].Transactions()
+	if len(byNumber) != len(txs) {
+		t.Fatalf("receipt count mismatch: have %d, want %d", len(byNumber), len(txs))
+	}
+	for i, receipt := range byNumber {
+		want, err := ec.TransactionReceipt(ctx, txs[i].Hash())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(receipt, want) {
+			t.Fatalf("receipt %d mismatch: have %+v, want %+v", i, receipt, want)
+		}
+	}
+	// Blocks without transactions have no receipts, unknown ones are not found
+	if receipts, err := ec.BlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(1)); err != nil || len(receipts) != 0 {
+		t.Fatalf("unexpected receipts for empty block: %v, %v", receipts, err)
+	}
+	if _, err := ec.BlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(1000)); err != ethereum.NotFound {
+		t.Fatalf("error should be ethereum.NotFound, have %v", err)
+	}
+}
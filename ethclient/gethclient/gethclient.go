@@ -180,6 +180,24 @@ func (ec *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- co
 	return ec.c.EthSubscribe(ctx, ch, "newPendingTransactions")
 }
 
+// TxPoolChange is a status change of a transaction in the transaction pool.
+type TxPoolChange struct {
+	Kind       string         `json:"kind"` // One of added, promoted, demoted, replaced, included or dropped
+	Hash       common.Hash    `json:"hash"`
+	From       common.Address `json:"from"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"` // Set if the transaction was replaced
+	Reason     string         `json:"reason,omitempty"`     // Set if the transaction was dropped
+}
+
+// SubscribeTxPoolChanges subscribes to the status changes of the transactions in
+// the transaction pool, i.e. when a transaction is added, promoted to pending,
+// demoted back to the queue, replaced by another, included in a block or dropped
+// from the pool.
+func (ec *Client) SubscribeTxPoolChanges(ctx context.Context, ch chan<- *TxPoolChange) (*rpc.ClientSubscription, error) {
+	return ec.c.Subscribe(ctx, "txpool", ch, "changes")
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -125,6 +125,9 @@ func TestGethClient(t *testing.T) {
 		}, {
 			"TestSubscribePendingTxs",
 			func(t *testing.T) { testSubscribePendingTransactions(t, client) },
+		}, {
+			"TestSubscribeTxPoolChanges",
+			func(t *testing.T) { testSubscribeTxPoolChanges(t, client) },
 		}, {
 			"TestCallContract",
 			func(t *testing.T) { testCallContract(t, client) },
@@ -298,6 +301,59 @@ func testSubscribePendingTransactions(t *testing.T, client *rpc.Client) {
 	}
 }
 
+func testSubscribeTxPoolChanges(t *testing.T, client *rpc.Client) {
+	ec := New(client)
+	ethcl := ethclient.NewClient(client)
+	// Subscribe to the pool changes
+	ch := make(chan *TxPoolChange, 16)
+	sub, err := ec.SubscribeTxPoolChanges(context.Background(), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	chainID, err := ethcl.ChainID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := types.LatestSignerForChainID(chainID)
+	send := func(gasPrice int64) *types.Transaction {
+		tx, err := types.SignNewTx(testKey, signer, &types.LegacyTx{
+			Nonce:    1,
+			To:       &common.Address{1},
+			Value:    big.NewInt(1),
+			Gas:      22000,
+			GasPrice: big.NewInt(gasPrice),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ethcl.SendTransaction(context.Background(), tx); err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	// Send a transaction on top of the pending one and replace it afterwards
+	tx := send(1)
+	replacement := send(2)
+
+	want := []TxPoolChange{
+		{Kind: "added", Hash: tx.Hash()},
+		{Kind: "promoted", Hash: tx.Hash()},
+		{Kind: "replaced", Hash: tx.Hash(), ReplacedBy: &[]common.Hash{replacement.Hash()}[0]},
+		{Kind: "added", Hash: replacement.Hash()},
+	}
+	for i, w := range want {
+		have := <-ch
+		if have.Kind != w.Kind || have.Hash != w.Hash || have.From != testAddr || have.Nonce != 1 {
+			t.Fatalf("change %d mismatch: have %s %x from %x nonce %d, want %s %x", i, have.Kind, have.Hash, have.From, have.Nonce, w.Kind, w.Hash)
+		}
+		if (have.ReplacedBy == nil) != (w.ReplacedBy == nil) || (w.ReplacedBy != nil && *have.ReplacedBy != *w.ReplacedBy) {
+			t.Fatalf("change %d replacement mismatch: have %v, want %v", i, have.ReplacedBy, w.ReplacedBy)
+		}
+	}
+}
+
 func testCallContract(t *testing.T, client *rpc.Client) {
 	ec := New(client)
 	msg := ethereum.CallMsg{
@@ -0,0 +1,765 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+const (
+	// defaultFieldCost is the cost of resolving a field without an explicit
+	// weight in the cost model.
+	defaultFieldCost = 1
+
+	// defaultListSize is the assumed number of items returned by a list field
+	// without an explicit size in the cost model.
+	defaultListSize = 10
+
+	// maxCostDepth is the maximum nesting of selections and fragments walked by
+	// the cost estimation, deeper queries are rejected.
+	maxCostDepth = 64
+)
+
+// defaultFieldCosts are the weights of the fields costlier to resolve than an
+// access to the chain or state data.
+var defaultFieldCosts = map[string]uint64{
+	"Account.proof":     10,
+	"Transaction.trace": 100,
+}
+
+// defaultListSizes are the assumed number of items returned by list fields. For
+// the block ranges of Query.blocks and Query.logs, they are the number of items
+// per block in the range.
+var defaultListSizes = map[string]uint64{
+	"Query.blocks":         1,
+	"Query.logs":           10,
+	"Block.ommers":         2,
+	"Block.transactions":   100,
+	"Block.receipts":       100,
+	"Block.logs":           100,
+	"Transaction.logs":     10,
+	"Receipt.logs":         10,
+	"Pending.transactions": 1000,
+}
+
+var (
+	errCostInvalid        = errors.New("invalid query")
+	errCostSyntax         = errors.New("syntax error")
+	errCostNoOperation    = errors.New("no operation to estimate")
+	errCostDepthExceeded  = errors.New("query nesting too deep")
+	errCostUnknownElement = errors.New("unknown query element")
+)
+
+// costModel estimates the cost of resolving a query before executing it, so the
+// ones exceeding the budget of the node can be rejected up front.
+//
+// The cost of a field is its weight, plus the cost of its selections multiplied
+// by the number of items the field is assumed to return. Block ranges requested
+// from Query.blocks and Query.logs are multiplied by the size of the range.
+//
+// Queries are validated by the GraphQL engine before being estimated, so only
+// the documents it accepts are walked by the cost parser.
+type costModel struct {
+	exec    *graphql.Schema // Executable schema validating the queries
+	schema  *types.Schema
+	backend ethapi.Backend // Source of the chain head for open block ranges, may be nil
+
+	maxCost    uint64            // Maximum cost of a query, zero for no limit
+	fieldCosts map[string]uint64 // Weights of the fields by Type.field
+	listSizes  map[string]uint64 // Assumed number of items returned by list fields by Type.field
+}
+
+// newCostModel creates a cost model for the given schema, overriding the default
+// weights and list sizes with the given ones.
+func newCostModel(schema *graphql.Schema, backend ethapi.Backend, maxCost uint64, fieldCosts, listSizes map[string]uint64) *costModel {
+	model := &costModel{
+		exec:       schema,
+		schema:     schema.ASTSchema(),
+		backend:    backend,
+		maxCost:    maxCost,
+		fieldCosts: make(map[string]uint64),
+		listSizes:  make(map[string]uint64),
+	}
+	for field, cost := range defaultFieldCosts {
+		model.fieldCosts[field] = cost
+	}
+	for field, size := range defaultListSizes {
+		model.listSizes[field] = size
+	}
+	for field, cost := range fieldCosts {
+		model.fieldCosts[field] = cost
+	}
+	for field, size := range listSizes {
+		model.listSizes[field] = size
+	}
+	return model
+}
+
+// queryCost is the estimated cost of an operation.
+type queryCost struct {
+	kind string // Kind of the operation: query, mutation or subscription
+	cost uint64 // Estimated cost of resolving the operation
+}
+
+// enabled reports whether the model has a budget to enforce. Without one, costs
+// are neither checked nor reported.
+func (m *costModel) enabled() bool {
+	return m.maxCost > 0
+}
+
+// exceeds reports whether the cost is above the budget of the model.
+func (m *costModel) exceeds(cost *queryCost) bool {
+	return m.enabled() && cost.cost > m.maxCost
+}
+
+// extensions returns the cost report attached to responses.
+func (m *costModel) extensions(cost *queryCost) map[string]interface{} {
+	return map[string]interface{}{
+		"cost": map[string]interface{}{"estimated": cost.cost, "limit": m.maxCost},
+	}
+}
+
+// estimate validates and parses a query, estimating the cost of the requested
+// operation.
+func (m *costModel) estimate(query string, operationName string, variables map[string]interface{}) (*queryCost, error) {
+	if errs := m.exec.ValidateWithVariables(query, variables); len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %s", errCostInvalid, errs[0].Message)
+	}
+	doc, err := parseCostDocument(query)
+	if err != nil {
+		return nil, err
+	}
+	op, err := doc.operation(operationName)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := m.schema.EntryPoints[op.kind]
+	if !ok {
+		return nil, errCostNoOperation
+	}
+	est := &costEstimator{
+		model:     m,
+		fragments: doc.fragments,
+		variables: variables,
+		defaults:  op.defaults,
+		head:      math.MaxInt64,
+	}
+	if m.backend != nil {
+		if head := m.backend.CurrentHeader(); head != nil {
+			est.head = head.Number.Uint64()
+		}
+	}
+	cost, err := est.selectionsCost(root.TypeName(), op.selections, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &queryCost{kind: op.kind, cost: cost}, nil
+}
+
+// operationKind returns the kind of the requested operation of a query, or an
+// empty string if it can't be parsed.
+func operationKind(query string, operationName string) string {
+	doc, err := parseCostDocument(query)
+	if err != nil {
+		return ""
+	}
+	op, err := doc.operation(operationName)
+	if err != nil {
+		return ""
+	}
+	return op.kind
+}
+
+// costEstimator walks the selections of an operation, accumulating their cost.
+type costEstimator struct {
+	model     *costModel
+	fragments map[string]*costFragment
+	variables map[string]interface{} // Variables supplied with the request
+	defaults  map[string]interface{} // Default values of the operation variables
+	head      uint64                 // Number of the chain head, closing open block ranges
+}
+
+// selectionsCost returns the cost of resolving a selection set on the given type.
+func (e *costEstimator) selectionsCost(typeName string, sels []*costSelection, depth int) (uint64, error) {
+	if depth > maxCostDepth {
+		return 0, errCostDepthExceeded
+	}
+	var total uint64
+	for _, sel := range sels {
+		var (
+			cost uint64
+			err  error
+		)
+		switch {
+		case sel.spread != "":
+			frag, ok := e.fragments[sel.spread]
+			if !ok {
+				return 0, fmt.Errorf("%w: fragment %s", errCostUnknownElement, sel.spread)
+			}
+			cost, err = e.selectionsCost(frag.typeName, frag.selections, depth+1)
+		case sel.name == "":
+			inner := typeName
+			if sel.typeName != "" {
+				inner = sel.typeName
+			}
+			cost, err = e.selectionsCost(inner, sel.selections, depth+1)
+		default:
+			cost, err = e.fieldCost(typeName, sel, depth)
+		}
+		if err != nil {
+			return 0, err
+		}
+		total = addCost(total, cost)
+	}
+	return total, nil
+}
+
+// fieldCost returns the cost of resolving a field of the given type, along with
+// its selections for every item it returns.
+func (e *costEstimator) fieldCost(typeName string, sel *costSelection, depth int) (uint64, error) {
+	if sel.name == "__typename" {
+		return 0, nil
+	}
+	key := typeName + "." + sel.name
+
+	cost, ok := e.model.fieldCosts[key]
+	if !ok {
+		cost = defaultFieldCost
+	}
+	if len(sel.selections) == 0 {
+		return cost, nil
+	}
+	// Resolve the type of the field to weigh its selections
+	var (
+		fieldType string
+		list      bool
+	)
+	if obj, ok := e.model.schema.Types[typeName].(*types.ObjectTypeDefinition); ok {
+		if def := obj.Fields.Get(sel.name); def != nil {
+			fieldType, list = unwrapType(def.Type)
+		}
+	}
+	children, err := e.selectionsCost(fieldType, sel.selections, depth+1)
+	if err != nil {
+		return 0, err
+	}
+	return addCost(cost, mulCost(e.multiplier(key, sel, list), children)), nil
+}
+
+// multiplier returns the number of items a field is assumed to return.
+func (e *costEstimator) multiplier(key string, sel *costSelection, list bool) uint64 {
+	size, ok := e.model.listSizes[key]
+	if !ok {
+		size = defaultListSize
+	}
+	switch key {
+	case "Query.blocks":
+		from := e.blockArg(sel.args["from"], 0)
+		to := e.blockArg(sel.args["to"], e.head)
+		return mulCost(blockRange(from, to, e.head), size)
+
+	case "Query.logs":
+		filter, _ := e.value(sel.args["filter"]).(map[string]interface{})
+		from := e.blockArg(filter["fromBlock"], e.head)
+		to := e.blockArg(filter["toBlock"], e.head)
+		return mulCost(blockRange(from, to, e.head), size)
+	}
+	if list {
+		return size
+	}
+	return 1
+}
+
+// value resolves the variables referenced by an argument value.
+func (e *costEstimator) value(v interface{}) interface{} {
+	name, ok := v.(costVariable)
+	if !ok {
+		return v
+	}
+	if val, ok := e.variables[string(name)]; ok {
+		return val
+	}
+	return e.defaults[string(name)]
+}
+
+// blockArg interprets an argument value as a block number, returning the given
+// default if it's missing. Negative numbers denote the chain head.
+func (e *costEstimator) blockArg(v interface{}, def uint64) uint64 {
+	switch v := e.value(v).(type) {
+	case int64:
+		if v < 0 {
+			return e.head
+		}
+		return uint64(v)
+	case float64:
+		if v < 0 {
+			return e.head
+		}
+		if v >= math.MaxUint64 {
+			return math.MaxUint64
+		}
+		return uint64(v)
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return def
+		}
+		if n < 0 {
+			return e.head
+		}
+		return uint64(n)
+	}
+	return def
+}
+
+// blockRange returns the number of existing blocks in an inclusive range.
+func blockRange(from, to, head uint64) uint64 {
+	if to > head {
+		to = head
+	}
+	if from > to {
+		return 0
+	}
+	return addCost(to-from, 1)
+}
+
+// unwrapType returns the name of the type underlying a field type, and whether
+// the field returns a list.
+func unwrapType(t types.Type) (string, bool) {
+	var list bool
+	for {
+		switch inner := t.(type) {
+		case *types.NonNull:
+			t = inner.OfType
+		case *types.List:
+			list, t = true, inner.OfType
+		case types.NamedType:
+			return inner.TypeName(), list
+		default:
+			return "", list
+		}
+	}
+}
+
+// addCost adds two costs, saturating on overflow.
+func addCost(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+// mulCost multiplies two costs, saturating on overflow.
+func mulCost(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return lo
+}
+
+// costDocument is the subset of a parsed GraphQL document needed to estimate
+// the cost of its operations.
+type costDocument struct {
+	operations []*costOperation
+	fragments  map[string]*costFragment
+}
+
+// operation returns the operation of the document with the given name, or the
+// only one if no name is given.
+func (doc *costDocument) operation(name string) (*costOperation, error) {
+	var op *costOperation
+	for _, candidate := range doc.operations {
+		if name == "" || candidate.name == name {
+			if op != nil {
+				return nil, errCostNoOperation // ambiguous operation
+			}
+			op = candidate
+		}
+	}
+	if op == nil {
+		return nil, errCostNoOperation
+	}
+	return op, nil
+}
+
+// costOperation is an operation definition of a document.
+type costOperation struct {
+	kind       string                 // Kind of the operation: query, mutation or subscription
+	name       string                 // Name of the operation, empty if anonymous
+	defaults   map[string]interface{} // Default values of the variables
+	selections []*costSelection
+}
+
+// costFragment is a fragment definition of a document.
+type costFragment struct {
+	typeName   string // Type condition of the fragment
+	selections []*costSelection
+}
+
+// costSelection is a field, a fragment spread or an inline fragment.
+type costSelection struct {
+	name       string                 // Name of the field, empty for fragments
+	args       map[string]interface{} // Arguments of the field
+	spread     string                 // Name of the spread fragment
+	typeName   string                 // Type condition of inline fragments
+	selections []*costSelection
+}
+
+// costVariable is a reference to a variable in an argument value.
+type costVariable string
+
+// costParser is a recursive descent parser of GraphQL executable documents,
+// retaining only what's needed for the cost estimation. It tokenizes documents
+// the same way as the GraphQL engine, so they are read identically.
+type costParser struct {
+	sc   scanner.Scanner
+	tok  rune // Current token, as returned by the scanner
+	text string
+	err  error
+	deep int
+}
+
+// parseCostDocument parses a GraphQL executable document.
+func parseCostDocument(src string) (*costDocument, error) {
+	p := new(costParser)
+	p.sc.Init(strings.NewReader(src))
+	p.sc.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings
+	p.sc.Error = func(*scanner.Scanner, string) { p.fail() }
+	p.advance()
+
+	doc := &costDocument{fragments: make(map[string]*costFragment)}
+	for p.err == nil && p.tok != scanner.EOF {
+		switch {
+		case p.isPunct('{'):
+			doc.operations = append(doc.operations, &costOperation{kind: "query", selections: p.parseSelectionSet()})
+		case p.isName("query"), p.isName("mutation"), p.isName("subscription"):
+			doc.operations = append(doc.operations, p.parseOperation())
+		case p.isName("fragment"):
+			p.advance()
+			name := p.expectName()
+			if !p.isName("on") {
+				p.fail()
+				break
+			}
+			p.advance()
+			frag := &costFragment{typeName: p.expectName()}
+			p.parseDirectives()
+			frag.selections = p.parseSelectionSet()
+			doc.fragments[name] = frag
+		default:
+			p.fail()
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return doc, nil
+}
+
+// parseOperation parses an operation definition with an explicit kind.
+func (p *costParser) parseOperation() *costOperation {
+	op := &costOperation{kind: p.text, defaults: make(map[string]interface{})}
+	p.advance()
+	if p.tok == scanner.Ident {
+		op.name = p.text
+		p.advance()
+	}
+	p.parseDirectives()
+	if p.isPunct('(') {
+		p.advance()
+		for p.err == nil && !p.isPunct(')') {
+			p.expectPunct('$')
+			if p.tok == scanner.String { // description
+				p.advance()
+			}
+			name := p.expectName()
+			p.expectPunct(':')
+			p.parseType()
+			if p.isPunct('=') {
+				p.advance()
+				op.defaults[name] = p.parseValue()
+			}
+			p.parseDirectives()
+		}
+		p.expectPunct(')')
+	}
+	op.selections = p.parseSelectionSet()
+	return op
+}
+
+// parseType skips over a variable type.
+func (p *costParser) parseType() {
+	if p.isPunct('[') {
+		p.advance()
+		p.parseType()
+		p.expectPunct(']')
+	} else {
+		p.expectName()
+	}
+	if p.isPunct('!') {
+		p.advance()
+	}
+}
+
+// parseSelectionSet parses a selection set enclosed in braces.
+func (p *costParser) parseSelectionSet() []*costSelection {
+	if !p.enter() {
+		return nil
+	}
+	defer p.leave()
+
+	p.expectPunct('{')
+	var sels []*costSelection
+	for p.err == nil && !p.isPunct('}') {
+		sels = append(sels, p.parseSelection())
+	}
+	p.expectPunct('}')
+	return sels
+}
+
+// parseSelection parses a field, a fragment spread or an inline fragment.
+func (p *costParser) parseSelection() *costSelection {
+	sel := new(costSelection)
+	if p.isPunct('.') {
+		p.advance()
+		p.expectPunct('.')
+		p.expectPunct('.')
+		switch {
+		case p.isName("on"):
+			p.advance()
+			sel.typeName = p.expectName()
+			p.parseDirectives()
+			sel.selections = p.parseSelectionSet()
+		case p.tok == scanner.Ident:
+			sel.spread = p.expectName()
+			p.parseDirectives()
+		default:
+			p.parseDirectives()
+			sel.selections = p.parseSelectionSet()
+		}
+		return sel
+	}
+	sel.name = p.expectName()
+	if p.isPunct(':') { // aliased field
+		p.advance()
+		sel.name = p.expectName()
+	}
+	sel.args = p.parseArguments()
+	p.parseDirectives()
+	if p.isPunct('{') {
+		sel.selections = p.parseSelectionSet()
+	}
+	return sel
+}
+
+// parseArguments parses the optional arguments of a field or directive.
+func (p *costParser) parseArguments() map[string]interface{} {
+	if !p.isPunct('(') {
+		return nil
+	}
+	p.advance()
+	args := make(map[string]interface{})
+	for p.err == nil && !p.isPunct(')') {
+		name := p.expectName()
+		p.expectPunct(':')
+		args[name] = p.parseValue()
+	}
+	p.expectPunct(')')
+	return args
+}
+
+// parseDirectives skips over the optional directives of an element.
+func (p *costParser) parseDirectives() {
+	for p.err == nil && p.isPunct('@') {
+		p.advance()
+		p.expectName()
+		p.parseArguments()
+	}
+}
+
+// parseValue parses an argument value.
+func (p *costParser) parseValue() interface{} {
+	if !p.enter() {
+		return nil
+	}
+	defer p.leave()
+
+	switch {
+	case p.isPunct('$'):
+		p.advance()
+		return costVariable(p.expectName())
+
+	case p.isPunct('['):
+		p.advance()
+		var list []interface{}
+		for p.err == nil && !p.isPunct(']') {
+			list = append(list, p.parseValue())
+		}
+		p.expectPunct(']')
+		return list
+
+	case p.isPunct('{'):
+		p.advance()
+		obj := make(map[string]interface{})
+		for p.err == nil && !p.isPunct('}') {
+			name := p.expectName()
+			p.expectPunct(':')
+			obj[name] = p.parseValue()
+		}
+		p.expectPunct('}')
+		return obj
+
+	case p.isPunct('-'):
+		p.advance()
+		return p.parseLiteral("-")
+	}
+	return p.parseLiteral("")
+}
+
+// parseLiteral parses a scalar or enum value, prefixed with the given sign.
+func (p *costParser) parseLiteral(sign string) interface{} {
+	if p.err != nil {
+		return nil
+	}
+	tok, text := p.tok, sign+p.text
+	switch tok {
+	case scanner.Int:
+		p.advance()
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return text // out of range, kept as is for the executor to reject
+		}
+		return n
+
+	case scanner.Float:
+		p.advance()
+		f, _ := strconv.ParseFloat(text, 64)
+		return f
+
+	case scanner.String:
+		p.advance()
+		if str, err := strconv.Unquote(text); err == nil {
+			return str
+		}
+		return text
+
+	case scanner.Ident:
+		p.advance()
+		if sign == "" {
+			switch text {
+			case "true":
+				return true
+			case "false":
+				return false
+			case "null":
+				return nil
+			}
+		}
+		return text // enum value
+	}
+	p.fail()
+	return nil
+}
+
+// enter tracks the nesting of the parser, failing on documents nested too deep.
+func (p *costParser) enter() bool {
+	p.deep++
+	if p.deep > maxCostDepth {
+		if p.err == nil {
+			p.err = errCostDepthExceeded
+		}
+		return false
+	}
+	return true
+}
+
+// leave pops a nesting level of the parser.
+func (p *costParser) leave() {
+	p.deep--
+}
+
+// isPunct reports whether the current token is the given punctuator.
+func (p *costParser) isPunct(punct rune) bool {
+	return p.err == nil && p.tok == punct
+}
+
+// isName reports whether the current token is the given name.
+func (p *costParser) isName(name string) bool {
+	return p.err == nil && p.tok == scanner.Ident && p.text == name
+}
+
+// expectPunct consumes the given punctuator, failing if it's not the current token.
+func (p *costParser) expectPunct(punct rune) {
+	if !p.isPunct(punct) {
+		p.fail()
+		return
+	}
+	p.advance()
+}
+
+// expectName consumes a name, failing if the current token is not a name.
+func (p *costParser) expectName() string {
+	if p.err != nil || p.tok != scanner.Ident {
+		p.fail()
+		return ""
+	}
+	name := p.text
+	p.advance()
+	return name
+}
+
+// fail records a syntax error at the current position.
+func (p *costParser) fail() {
+	if p.err == nil {
+		p.err = fmt.Errorf("%w at offset %d", errCostSyntax, p.sc.Pos().Offset)
+	}
+	p.tok, p.text = scanner.EOF, ""
+}
+
+// advance scans the next token of the document, skipping over the insignificant
+// commas and the comments.
+func (p *costParser) advance() {
+	for p.err == nil {
+		p.tok = p.sc.Scan()
+		p.text = p.sc.TokenText()
+
+		switch p.tok {
+		case ',':
+			continue
+		case '#':
+			// Comments run until the end of the line
+			next := p.sc.Next()
+			for next != '\r' && next != '\n' && next != scanner.EOF {
+				next = p.sc.Next()
+			}
+			continue
+		}
+		return
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Tests that the cost of queries is estimated from the field weights, the list
+// sizes and the requested block ranges.
+func TestQueryCost(t *testing.T) {
+	s, err := graphql.ParseSchema(schema+tracingSchema+querySchema, new(Resolver))
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	tests := []struct {
+		query      string
+		operation  string
+		variables  map[string]interface{}
+		fieldCosts map[string]uint64
+		listSizes  map[string]uint64
+		cost       uint64
+		err        error
+	}{
+		{query: `{block{number}}`, cost: 2},
+		{query: `{block{__typename number hash}}`, cost: 3},
+		{query: `{block{transactions{hash}}}`, cost: 102},
+		{query: `{block{transactions{hash}}}`, fieldCosts: map[string]uint64{"Block.transactions": 5}, cost: 106},
+		{query: `{block{transactions{hash}}}`, listSizes: map[string]uint64{"Block.transactions": 3}, cost: 5},
+		{query: `{block{transactions{trace}}}`, cost: 10002},
+		{query: `{block{account(address:"0x00"){proof{accountProof}}}}`, cost: 13},
+		{query: `{blocks(from:1, to:10){number}}`, cost: 11},
+		{query: `{blocks(from:10, to:1){number}}`, cost: 1},
+		{query: `{blocks(from:"1", to:"10"){number}}`, cost: 11},
+		{query: `{logs(filter:{fromBlock:1, toBlock:4}){data}}`, cost: 41},
+		{query: `query($to: Long = 20) {blocks(from:11, to:$to){number}}`, cost: 11},
+		{query: `query($to: Long = 20) {blocks(from:11, to:$to){number}}`, variables: map[string]interface{}{"to": float64(15)}, cost: 6},
+		{query: `query($from: Long) {logs(filter:{fromBlock:$from, toBlock:4}){data}}`, variables: map[string]interface{}{"from": float64(3)}, cost: 21},
+		{query: `{blocks(from:0, to:10){number}}`, listSizes: map[string]uint64{"Query.blocks": math.MaxUint64}, cost: math.MaxUint64},
+		{query: `{block{...fields}} fragment fields on Block {number hash}`, cost: 3},
+		{query: `{block{... on Block {number}}}`, cost: 2},
+		{query: "# comment\n{block(hash:\"0x00\\u0041\") @include(if: true) {number}}", cost: 2},
+		{query: `query a {block{number}} query b {blocks(from:1, to:2){number}}`, operation: "b", cost: 3},
+		{query: `query a {block{number}} query b {block{number}}`, err: errCostNoOperation},
+		{query: `{block{number}}`, operation: "missing", err: errCostNoOperation},
+		{query: `{block{number`, err: errCostInvalid},
+		{query: `{block{bleh}}`, err: errCostInvalid},
+		{query: `{block{...missing}}`, err: errCostInvalid},
+		{query: `{block{...a}} fragment a on Block {...a}`, err: errCostInvalid},
+		{query: `query($to: Long!) {blocks(from:1, to:$to){number}}`, err: errCostInvalid},
+		{query: "{block" + strings.Repeat("{parent", maxCostDepth) + "{number}" + strings.Repeat("}", maxCostDepth+1), err: errCostDepthExceeded},
+	}
+	for i, tt := range tests {
+		model := newCostModel(s, nil, 0, tt.fieldCosts, tt.listSizes)
+		cost, err := model.estimate(tt.query, tt.operation, tt.variables)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err == nil && cost.cost != tt.cost {
+			t.Errorf("test %d: cost mismatch: have %d, want %d", i, cost.cost, tt.cost)
+		}
+	}
+}
+
+// Tests that the cost parser accepts every document the GraphQL engine does, by
+// running both against random mutations of valid queries.
+func TestCostParserAgreement(t *testing.T) {
+	s, err := graphql.ParseSchema(schema+tracingSchema+querySchema, new(Resolver))
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	seeds := []string{
+		`{block{number hash transactions{hash from{address} logs{data topics}}}}`,
+		`query q($from: Long = 1, $to: Long) {blocks(from:$from, to:$to){number ommers{hash}}}`,
+		`{logs(filter:{fromBlock:1, toBlock:4, addresses:["0x00"], topics:[["0x00"]]}){data index}}`,
+		`{block{...fields ... on Block {hash}}} fragment fields on Block @include(if: true) {number}`,
+		"# comment\n{block(hash:\"0x00\\u0041\"){account(address:\"0x00\"){balance}}}",
+		`{pending{transactionCount transactions{hash}} gasPrice chainID}`,
+	}
+	// Mutate the seeds with tokens and characters meaningful to both parsers
+	tokens := []string{"{", "}", "(", ")", "[", "]", ":", "$", "@", "...", "!", "=", ",", "\"", "\\", "#", "\n", " ", "on", "query", "fragment", "1", "-", "1.5e3", `"""`, "number", "parent{number}"}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		query := seeds[rng.Intn(len(seeds))]
+		for j := rng.Intn(3) + 1; j > 0; j-- {
+			pos := rng.Intn(len(query) + 1)
+			switch rng.Intn(3) {
+			case 0: // delete a span
+				end := pos + rng.Intn(4)
+				if end > len(query) {
+					end = len(query)
+				}
+				query = query[:pos] + query[end:]
+			case 1: // insert a token
+				query = query[:pos] + tokens[rng.Intn(len(tokens))] + query[pos:]
+			case 2: // duplicate a span
+				end := pos + rng.Intn(16)
+				if end > len(query) {
+					end = len(query)
+				}
+				query = query[:end] + query[pos:end] + query[end:]
+			}
+		}
+		if errs := s.Validate(query); len(errs) > 0 {
+			continue
+		}
+		if _, err := parseCostDocument(query); err != nil {
+			t.Errorf("cost parser rejected valid query %q: %v", query, err)
+		}
+	}
+}
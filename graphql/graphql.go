@@ -19,6 +19,7 @@ package graphql
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -32,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -74,6 +76,38 @@ func (b *Long) UnmarshalGraphQL(input interface{}) error {
 	return err
 }
 
+// JSON is an arbitrary JSON value.
+type JSON json.RawMessage
+
+// ImplementsGraphQLType returns true if JSON implements the provided GraphQL type.
+func (j JSON) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data. Values are taken
+// verbatim, with strings holding their JSON encoding.
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	if str, ok := input.(string); ok {
+		if !json.Valid([]byte(str)) {
+			return errors.New("invalid JSON string")
+		}
+		*j = JSON(str)
+		return nil
+	}
+	blob, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("unexpected type %T for JSON", input)
+	}
+	*j = JSON(blob)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, emitting the value verbatim.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if j == nil {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
 // Account represents an Ethereum account at a particular block.
 type Account struct {
 	backend       ethapi.Backend
@@ -135,6 +169,85 @@ func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash })
 	return state.GetState(a.address, args.Slot), nil
 }
 
+// Proof returns the EIP-1186 Merkle proof of the account and the given storage
+// slots.
+func (a *Account) Proof(ctx context.Context, args struct{ Slots *[]common.Hash }) (*AccountProof, error) {
+	var keys []string
+	if args.Slots != nil {
+		for _, slot := range *args.Slots {
+			keys = append(keys, slot.Hex())
+		}
+	}
+	result, err := ethapi.NewPublicBlockChainAPI(a.backend).GetProof(ctx, a.address, keys, a.blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountProof{result: result}, nil
+}
+
+// AccountProof represents the EIP-1186 Merkle proof of an account.
+type AccountProof struct {
+	result *ethapi.AccountResult
+}
+
+func (p *AccountProof) AccountProof(ctx context.Context) ([]hexutil.Bytes, error) {
+	return decodeProof(p.result.AccountProof)
+}
+
+func (p *AccountProof) Balance(ctx context.Context) hexutil.Big {
+	return *p.result.Balance
+}
+
+func (p *AccountProof) CodeHash(ctx context.Context) common.Hash {
+	return p.result.CodeHash
+}
+
+func (p *AccountProof) Nonce(ctx context.Context) hexutil.Uint64 {
+	return p.result.Nonce
+}
+
+func (p *AccountProof) StorageHash(ctx context.Context) common.Hash {
+	return p.result.StorageHash
+}
+
+func (p *AccountProof) StorageProof(ctx context.Context) []*StorageProof {
+	ret := make([]*StorageProof, 0, len(p.result.StorageProof))
+	for i := range p.result.StorageProof {
+		ret = append(ret, &StorageProof{result: &p.result.StorageProof[i]})
+	}
+	return ret
+}
+
+// StorageProof represents the EIP-1186 Merkle proof of a storage slot.
+type StorageProof struct {
+	result *ethapi.StorageResult
+}
+
+func (p *StorageProof) Key(ctx context.Context) common.Hash {
+	return common.HexToHash(p.result.Key)
+}
+
+func (p *StorageProof) Value(ctx context.Context) hexutil.Big {
+	return *p.result.Value
+}
+
+func (p *StorageProof) Proof(ctx context.Context) ([]hexutil.Bytes, error) {
+	return decodeProof(p.result.Proof)
+}
+
+// decodeProof converts the hex encoded trie nodes of a proof back to binary.
+func decodeProof(proof []string) ([]hexutil.Bytes, error) {
+	ret := make([]hexutil.Bytes, 0, len(proof))
+	for _, node := range proof {
+		blob, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, blob)
+	}
+	return ret, nil
+}
+
 // Log represents an individual log message. All arguments are mandatory.
 type Log struct {
 	backend     ethapi.Backend
@@ -465,6 +578,41 @@ func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
 	return &ret, nil
 }
 
+// Trace re-executes the transaction with the given tracer, returning its result.
+// Without a tracer, the struct logger is used. The configuration is the same as
+// the one accepted by debug_traceTransaction.
+func (t *Transaction) Trace(ctx context.Context, args struct {
+	Tracer *string
+	Config *JSON
+}) (*JSON, error) {
+	backend, ok := t.backend.(tracers.Backend)
+	if !ok {
+		return nil, errors.New("tracing not supported by the backend")
+	}
+	if _, err := t.resolve(ctx); err != nil || t.block == nil {
+		return nil, err // pending transactions can't be traced
+	}
+	config := new(tracers.TraceConfig)
+	if args.Config != nil {
+		if err := json.Unmarshal(*args.Config, config); err != nil {
+			return nil, fmt.Errorf("invalid trace config: %v", err)
+		}
+	}
+	if args.Tracer != nil {
+		config.Tracer = args.Tracer
+	}
+	result, err := tracers.NewAPI(backend).TraceTransaction(ctx, t.hash, config)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	ret := JSON(blob)
+	return &ret, nil
+}
+
 func (t *Transaction) Type(ctx context.Context) (*int32, error) {
 	tx, err := t.resolve(ctx)
 	if err != nil {
@@ -533,6 +681,73 @@ func (t *Transaction) RawReceipt(ctx context.Context) (hexutil.Bytes, error) {
 	return receipt.MarshalBinary()
 }
 
+// Receipt represents the receipt of a transaction included in a block.
+// All the fields are resolved through the wrapped transaction, whose block
+// caches the receipts of all its transactions.
+type Receipt struct {
+	transaction *Transaction
+}
+
+func (r *Receipt) Transaction(ctx context.Context) *Transaction {
+	return r.transaction
+}
+
+func (r *Receipt) Status(ctx context.Context) (*Long, error) {
+	return r.transaction.Status(ctx)
+}
+
+func (r *Receipt) GasUsed(ctx context.Context) (Long, error) {
+	receipt, err := r.transaction.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return 0, err
+	}
+	return Long(receipt.GasUsed), nil
+}
+
+func (r *Receipt) CumulativeGasUsed(ctx context.Context) (Long, error) {
+	receipt, err := r.transaction.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return 0, err
+	}
+	return Long(receipt.CumulativeGasUsed), nil
+}
+
+func (r *Receipt) EffectiveGasPrice(ctx context.Context) (*hexutil.Big, error) {
+	return r.transaction.EffectiveGasPrice(ctx)
+}
+
+func (r *Receipt) ContractAddress(ctx context.Context) (*common.Address, error) {
+	receipt, err := r.transaction.getReceipt(ctx)
+	if err != nil || receipt == nil || receipt.ContractAddress == (common.Address{}) {
+		return nil, err
+	}
+	return &receipt.ContractAddress, nil
+}
+
+func (r *Receipt) Logs(ctx context.Context) ([]*Log, error) {
+	logs, err := r.transaction.Logs(ctx)
+	if err != nil || logs == nil {
+		return []*Log{}, err
+	}
+	return *logs, nil
+}
+
+func (r *Receipt) LogsBloom(ctx context.Context) (hexutil.Bytes, error) {
+	receipt, err := r.transaction.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return hexutil.Bytes{}, err
+	}
+	return receipt.Bloom.Bytes(), nil
+}
+
+func (r *Receipt) Type(ctx context.Context) (*int32, error) {
+	return r.transaction.Type(ctx)
+}
+
+func (r *Receipt) Raw(ctx context.Context) (hexutil.Bytes, error) {
+	return r.transaction.RawReceipt(ctx)
+}
+
 type BlockType int
 
 // Block represents an Ethereum block.
@@ -886,6 +1101,18 @@ func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
 	return &ret, nil
 }
 
+func (b *Block) Receipts(ctx context.Context) (*[]*Receipt, error) {
+	txs, err := b.Transactions(ctx)
+	if err != nil || txs == nil {
+		return nil, err
+	}
+	ret := make([]*Receipt, 0, len(*txs))
+	for _, tx := range *txs {
+		ret = append(ret, &Receipt{transaction: tx})
+	}
+	return &ret, nil
+}
+
 func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
@@ -1138,6 +1365,7 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 // Resolver is the top-level object in the GraphQL hierarchy.
 type Resolver struct {
 	backend ethapi.Backend
+	events  *filters.EventSystem // Chain and pool events feeding the subscriptions
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -1197,6 +1425,10 @@ func (r *Resolver) Blocks(ctx context.Context, args struct {
 	}
 	ret := make([]*Block, 0, to-from+1)
 	for i := from; i <= to; i++ {
+		// Stop resolving the range if the query was aborted
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		numberOrHash := rpc.BlockNumberOrHashWithNumber(i)
 		block := &Block{
 			backend:      r.backend,
@@ -1389,3 +1621,133 @@ func (r *Resolver) Syncing() (*SyncState, error) {
 	// Otherwise gather the block sync stats
 	return &SyncState{progress}, nil
 }
+
+// NewHeads streams the blocks added to the canonical chain, until the
+// subscription is cancelled.
+func (r *Resolver) NewHeads(ctx context.Context) (<-chan *Block, error) {
+	var (
+		headers = make(chan *types.Header)
+		sub     = r.events.SubscribeNewHeads(headers)
+		blocks  = make(chan *Block)
+	)
+	go func() {
+		defer close(blocks)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case header := <-headers:
+				hash := header.Hash()
+				numberOrHash := rpc.BlockNumberOrHashWithHash(hash, false)
+				block := &Block{
+					backend:      r.backend,
+					numberOrHash: &numberOrHash,
+					hash:         hash,
+					header:       header,
+				}
+				select {
+				case blocks <- block:
+				case <-ctx.Done():
+					return
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks, nil
+}
+
+// subscriptionResolver is the root resolver of the subscription schema. It only
+// differs from the query one by streaming the logs instead of filtering them.
+type subscriptionResolver struct {
+	*Resolver
+}
+
+// Logs streams the log entries matching the provided filter as they are included
+// in the canonical chain, until the subscription is cancelled. Logs removed by a
+// chain reorganisation are not reported.
+func (r *subscriptionResolver) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	matches := make(chan []*types.Log)
+	sub, err := r.events.SubscribeLogs(crit, matches)
+	if err != nil {
+		return nil, err
+	}
+	logs := make(chan *Log)
+	go func() {
+		defer close(logs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-matches:
+				for _, log := range batch {
+					if log.Removed {
+						continue
+					}
+					entry := &Log{
+						backend:     r.backend,
+						transaction: &Transaction{backend: r.backend, hash: log.TxHash},
+						log:         log,
+					}
+					select {
+					case logs <- entry:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
+
+// PendingTransactions streams the transactions added to the transaction pool,
+// until the subscription is cancelled.
+func (r *Resolver) PendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	var (
+		hashes = make(chan []common.Hash)
+		sub    = r.events.SubscribePendingTxs(hashes)
+		txs    = make(chan *Transaction)
+	)
+	go func() {
+		defer close(txs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-hashes:
+				for _, hash := range batch {
+					tx := &Transaction{
+						backend: r.backend,
+						hash:    hash,
+						tx:      r.backend.GetPoolTransaction(hash),
+					}
+					select {
+					case txs <- tx:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return txs, nil
+}
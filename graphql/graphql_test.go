@@ -17,15 +17,20 @@
 package graphql
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -33,9 +38,14 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,7 +60,7 @@ func TestBuildSchema(t *testing.T) {
 		t.Fatalf("could not create new node: %v", err)
 	}
 	// Make sure the schema can be parsed and matched up to the object model.
-	if err := newHandler(stack, nil, []string{}, []string{}); err != nil {
+	if err := newHandler(stack, nil, false, []string{}, []string{}); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
@@ -177,6 +187,290 @@ func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 			want: `{"data":{"block":{"number":1,"transactions":[{"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x64","hash":"0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b","type":0,"accessList":[],"index":0},{"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x32","hash":"0x19b35f8187b4e15fb59a9af469dca5dfa3cd363c11d372058c12f6482477b474","type":1,"accessList":[{"address":"0x0000000000000000000000000000000000000dad","storageKeys":["0x0000000000000000000000000000000000000000000000000000000000000000"]}],"index":1}]}}}`,
 			code: 200,
 		},
+		{
+			body: `{"query": "{block {receipts { transaction { hash } status gasUsed cumulativeGasUsed contractAddress type }}}"}`,
+			want: `{"data":{"block":{"receipts":[{"transaction":{"hash":"0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b"},"status":1,"gasUsed":25204,"cumulativeGasUsed":25204,"contractAddress":null,"type":0},{"transaction":{"hash":"0x19b35f8187b4e15fb59a9af469dca5dfa3cd363c11d372058c12f6482477b474"},"status":1,"gasUsed":27504,"cumulativeGasUsed":52708,"contractAddress":null,"type":1}]}}}`,
+			code: 200,
+		},
+	} {
+		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("could not post: %v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("could not read from response body: %v", err)
+		}
+		if have := string(bodyBytes); have != tt.want {
+			t.Errorf("testcase %d %s,\nhave:\n%v\nwant:\n%v", i, tt.body, have, tt.want)
+		}
+		if tt.code != resp.StatusCode {
+			t.Errorf("testcase %d %s,\nwrong statuscode, have: %v, want: %v", i, tt.body, resp.StatusCode, tt.code)
+		}
+	}
+}
+
+// Tests that account proofs verify against the state root, and that transactions
+// can be traced if enabled.
+func TestGraphQLProofAndTrace(t *testing.T) {
+	stack, err := node.New(&node.Config{
+		HTTPHost:       "127.0.0.1",
+		HTTPPort:       0,
+		GraphQLTracing: true,
+	})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	createGQLServiceWithTransactions(t, stack)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	post := func(query string, result interface{}) {
+		t.Helper()
+
+		body, _ := json.Marshal(map[string]string{"query": query})
+		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("could not post: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var res struct {
+			Data   json.RawMessage
+			Errors []struct{ Message string }
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+		if len(res.Errors) > 0 {
+			t.Fatalf("query failed: %v", res.Errors[0].Message)
+		}
+		if err := json.Unmarshal(res.Data, result); err != nil {
+			t.Fatalf("could not decode data: %v", err)
+		}
+	}
+	// Retrieve the proof of an account and a storage slot, and verify them
+	var proof struct {
+		Block struct {
+			StateRoot common.Hash
+			Account   struct {
+				Proof struct {
+					AccountProof []hexutil.Bytes
+					Balance      hexutil.Big
+					StorageProof []struct {
+						Key   common.Hash
+						Value hexutil.Big
+						Proof []hexutil.Bytes
+					}
+				}
+			}
+		}
+	}
+	post(`{block{stateRoot account(address:"0x0000000000000000000000000000000000000dad"){proof(slots:["0x0000000000000000000000000000000000000000000000000000000000000001"]){accountProof balance storageProof{key value proof}}}}}`, &proof)
+
+	db := memorydb.New()
+	for _, node := range proof.Block.Account.Proof.AccountProof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	dad := common.HexToAddress("0x0000000000000000000000000000000000000dad")
+	blob, err := trie.VerifyProof(proof.Block.StateRoot, crypto.Keccak256(dad[:]), db)
+	if err != nil {
+		t.Fatalf("failed to verify account proof: %v", err)
+	}
+	var account types.StateAccount
+	if err := rlp.DecodeBytes(blob, &account); err != nil {
+		t.Fatalf("failed to decode proven account: %v", err)
+	}
+	if have, want := account.Balance, proof.Block.Account.Proof.Balance.ToInt(); have.Cmp(want) != 0 || want.Cmp(big.NewInt(150)) != 0 {
+		t.Errorf("balance mismatch: proven %v, reported %v, want %v", have, want, 150)
+	}
+	if have := len(proof.Block.Account.Proof.StorageProof); have != 1 {
+		t.Fatalf("storage proof count mismatch: have %d, want %d", have, 1)
+	}
+	if key := proof.Block.Account.Proof.StorageProof[0].Key; key != common.BigToHash(common.Big1) {
+		t.Errorf("storage proof key mismatch: have %x, want %x", key, common.BigToHash(common.Big1))
+	}
+	// Trace the transactions of the block with the struct logger
+	var trace struct {
+		Block struct {
+			Transactions []struct {
+				Trace struct {
+					Failed     bool
+					StructLogs []struct {
+						Op string
+					}
+				}
+			}
+		}
+	}
+	post(`{block{transactions{trace(config:{disableStack:true})}}}`, &trace)
+	if have := len(trace.Block.Transactions); have != 2 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", have, 2)
+	}
+	for i, tx := range trace.Block.Transactions {
+		if tx.Trace.Failed {
+			t.Errorf("transaction %d: trace failed", i)
+		}
+		var ops []string
+		for _, log := range tx.Trace.StructLogs {
+			ops = append(ops, log.Op)
+		}
+		if have, want := strings.Join(ops, ","), "PC,PC,SLOAD,SLOAD,STOP"; have != want {
+			t.Errorf("transaction %d: opcode mismatch: have %s, want %s", i, have, want)
+		}
+	}
+}
+
+// Tests that transaction tracing is not exposed unless explicitly enabled.
+func TestGraphQLTraceDisabled(t *testing.T) {
+	stack := createNode(t, true, true)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	body := strings.NewReader(`{"query": "{block{transactions{trace(tracer:\"callTracer\")}}}"}`)
+	resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", body)
+	if err != nil {
+		t.Fatalf("could not post: %v", err)
+	}
+	defer resp.Body.Close()
+
+	blob, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read from response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status code mismatch: have %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if !strings.Contains(string(blob), `Cannot query field \"trace\"`) {
+		t.Errorf("unexpected response: %s", blob)
+	}
+}
+
+// Tests that new blocks are streamed to graphql-ws subscribers on the websocket endpoint.
+func TestGraphQLSubscription(t *testing.T) {
+	stack, err := node.New(&node.Config{
+		HTTPHost: "127.0.0.1",
+		HTTPPort: 0,
+		WSHost:   "127.0.0.1",
+		WSPort:   0,
+	})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	defer stack.Close()
+	backend := createGQLService(t, stack)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial(stack.WSEndpoint()+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+
+	send := func(msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("could not send message: %v", err)
+		}
+	}
+	expect := func(want string) {
+		t.Helper()
+		for {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("could not read message: %v", err)
+			}
+			if have := strings.TrimSpace(string(msg)); have != `{"type":"ka"}` {
+				if have != want {
+					t.Fatalf("message mismatch:\nhave:\n%v\nwant:\n%v", have, want)
+				}
+				return
+			}
+		}
+	}
+	send(`{"type":"connection_init","payload":{}}`)
+	expect(`{"type":"connection_ack"}`)
+
+	// Subscribe to new heads and logs, and wait for a query to ensure they're installed
+	send(`{"id":"1","type":"start","payload":{"query":"subscription { newHeads { number } }"}}`)
+	send(`{"id":"3","type":"start","payload":{"query":"subscription { logs(filter: {}) { index } }"}}`)
+	send(`{"id":"2","type":"start","payload":{"query":"{ block { number } logs(filter: {fromBlock: 10}) { index } }"}}`)
+	expect(`{"id":"2","type":"data","payload":{"data":{"block":{"number":10},"logs":[]}}}`)
+	expect(`{"id":"2","type":"complete"}`)
+
+	chain, _ := core.GenerateChain(params.AllEthashProtocolChanges, backend.BlockChain().CurrentBlock(),
+		ethash.NewFaker(), backend.ChainDb(), 1, func(i int, gen *core.BlockGen) {})
+	if _, err := backend.BlockChain().InsertChain(chain); err != nil {
+		t.Fatalf("could not import block: %v", err)
+	}
+	expect(`{"id":"1","type":"data","payload":{"data":{"newHeads":{"number":11}}}}`)
+
+	send(`{"id":"1","type":"stop"}`)
+	expect(`{"id":"1","type":"complete"}`)
+
+	// Fill up the subscription slots, the next subscription is rejected until
+	// one of them stops, while queries are still served
+	for i := 1; i < wsMaxSubscriptions; i++ {
+		send(fmt.Sprintf(`{"id":"sub%d","type":"start","payload":{"query":"subscription { newHeads { number } }"}}`, i))
+	}
+	send(`{"id":"4","type":"start","payload":{"query":"subscription { newHeads { number } }"}}`)
+	expect(fmt.Sprintf(`{"id":"4","type":"error","payload":{"message":"too many subscriptions, limit %d"}}`, wsMaxSubscriptions))
+	send(`{"id":"5","type":"start","payload":{"query":"{ block { number } }"}}`)
+	expect(`{"id":"5","type":"data","payload":{"data":{"block":{"number":11}}}}`)
+	expect(`{"id":"5","type":"complete"}`)
+
+	send(`{"id":"3","type":"stop"}`)
+	expect(`{"id":"3","type":"complete"}`)
+	send(`{"id":"4","type":"start","payload":{"query":"subscription { newHeads { number } }"}}`)
+	send(`{"id":"6","type":"start","payload":{"query":"subscription { newHeads { number } }"}}`)
+	expect(fmt.Sprintf(`{"id":"6","type":"error","payload":{"message":"too many subscriptions, limit %d"}}`, wsMaxSubscriptions))
+}
+
+// Tests that queries above the cost budget are rejected before execution, and
+// that the estimated cost is reported with the responses.
+func TestGraphQLQueryCost(t *testing.T) {
+	stack, err := node.New(&node.Config{
+		HTTPHost:       "127.0.0.1",
+		HTTPPort:       0,
+		GraphQLMaxCost: 50,
+	})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	defer stack.Close()
+	createGQLService(t, stack)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	for i, tt := range []struct {
+		body string
+		want string
+		code int
+	}{
+		{
+			body: `{"query": "{block{number}}"}`,
+			want: `{"data":{"block":{"number":10}},"extensions":{"cost":{"estimated":2,"limit":50}}}`,
+			code: 200,
+		},
+		{ // Open ranges are closed by the chain head
+			body: `{"query": "{blocks(from:0){number}}"}`,
+			want: `{"data":{"blocks":[{"number":0},{"number":1},{"number":2},{"number":3},{"number":4},{"number":5},{"number":6},{"number":7},{"number":8},{"number":9},{"number":10}]},"extensions":{"cost":{"estimated":12,"limit":50}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{blocks(from:0){transactions{hash}}}"}`,
+			want: `{"errors":[{"message":"query cost 1112 exceeds limit 50"}],"extensions":{"cost":{"estimated":1112,"limit":50}}}`,
+			code: 400,
+		},
+		{
+			body: `{"query": "{block{number"}`,
+			want: `{"errors":[{"message":"failed to estimate query cost: invalid query: syntax error: unexpected \"\", expecting Ident"}]}`,
+			code: 400,
+		},
 	} {
 		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
 		if err != nil {
@@ -196,6 +490,36 @@ func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 }
 
 // Tests that a graphQL request is not handled successfully when graphql is not enabled on the specified endpoint
+type slowResolver struct {
+	cancelled chan struct{}
+}
+
+func (r *slowResolver) Slow(ctx context.Context) int32 {
+	<-ctx.Done()
+	close(r.cancelled)
+	return 0
+}
+
+// Tests that queries running past the timeout are answered with an error and
+// cancelled before the request returns.
+func TestGraphQLTimeout(t *testing.T) {
+	resolver := &slowResolver{cancelled: make(chan struct{})}
+	s := graphql.MustParseSchema(`schema {query: Query} type Query {slow: Int!}`, resolver)
+	h := handler{Schema: s, Costs: newCostModel(s, nil, 0, nil, nil), Timeout: 50 * time.Millisecond}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{slow}"}`)))
+
+	select {
+	case <-resolver.cancelled:
+	default:
+		t.Fatalf("query still running after the request returned")
+	}
+	if have, want := rec.Body.String(), `{"errors":[{"message":"request timed out"}]}`; have != want {
+		t.Errorf("response mismatch: have %s, want %s", have, want)
+	}
+}
+
 func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	stack := createNode(t, false, false)
 	defer stack.Close()
@@ -232,7 +556,7 @@ func createNode(t *testing.T, gqlEnabled bool, txEnabled bool) *node.Node {
 	return stack
 }
 
-func createGQLService(t *testing.T, stack *node.Node) {
+func createGQLService(t *testing.T, stack *node.Node) *eth.Ethereum {
 	// create backend
 	ethConf := &ethconfig.Config{
 		Genesis: &core.Genesis{
@@ -263,10 +587,11 @@ func createGQLService(t *testing.T, stack *node.Node) {
 		t.Fatalf("could not create import blocks: %v", err)
 	}
 	// create gql service
-	err = New(stack, ethBackend.APIBackend, []string{}, []string{})
+	err = New(stack, ethBackend.APIBackend, false, []string{}, []string{})
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
+	return ethBackend
 }
 
 func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
@@ -348,7 +673,7 @@ func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
 		t.Fatalf("could not create import blocks: %v", err)
 	}
 	// create gql service
-	err = New(stack, ethBackend.APIBackend, []string{}, []string{})
+	err = New(stack, ethBackend.APIBackend, false, []string{}, []string{})
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
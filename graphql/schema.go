@@ -30,11 +30,9 @@ const schema string = `
     scalar BigInt
     # Long is a 64 bit unsigned integer.
     scalar Long
-
-    schema {
-        query: Query
-        mutation: Mutation
-    }
+    # JSON is an arbitrary JSON value. Input is accepted as either a JSON literal
+    # or as a string holding its encoding.
+    scalar JSON
 
     # Account is an Ethereum account at a particular block.
     type Account {
@@ -52,6 +50,37 @@ const schema string = `
         # Storage provides access to the storage of a contract account, indexed
         # by its 32 byte slot identifier.
         storage(slot: Bytes32!): Bytes32!
+        # Proof is the EIP-1186 Merkle proof of the account and the given
+        # storage slots.
+        proof(slots: [Bytes32!]): AccountProof!
+    }
+
+    # AccountProof is the EIP-1186 Merkle proof of an account.
+    type AccountProof {
+        # AccountProof is the list of RLP encoded trie nodes from the state root
+        # down to the account.
+        accountProof: [Bytes!]!
+        # Balance is the balance of the account, in wei.
+        balance: BigInt!
+        # CodeHash is the hash of the code of the account.
+        codeHash: Bytes32!
+        # Nonce is the nonce of the account.
+        nonce: Long!
+        # StorageHash is the root hash of the storage trie of the account.
+        storageHash: Bytes32!
+        # StorageProof is the list of proofs of the requested storage slots.
+        storageProof: [StorageProof!]!
+    }
+
+    # StorageProof is the EIP-1186 Merkle proof of a storage slot.
+    type StorageProof {
+        # Key is the storage slot.
+        key: Bytes32!
+        # Value is the value of the storage slot.
+        value: BigInt!
+        # Proof is the list of RLP encoded trie nodes from the storage root down
+        # to the slot.
+        proof: [Bytes!]!
     }
 
     # Log is an Ethereum event log.
@@ -149,6 +178,37 @@ const schema string = `
         rawReceipt: Bytes!
     }
 
+    # Receipt is the outcome of the execution of a transaction that was
+    # included in a block.
+    type Receipt {
+        # Transaction is the transaction this receipt was produced by.
+        transaction: Transaction!
+        # Status is the return status of the transaction. This will be 1 if the
+        # transaction succeeded, or 0 if it failed. Pre-Byzantium receipts carry
+        # an intermediate state root instead, in which case this field is null.
+        status: Long
+        # GasUsed is the amount of gas that was used processing the transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # the transaction.
+        cumulativeGasUsed: Long!
+        # EffectiveGasPrice is actual value per gas deducted from the sender's
+        # account.
+        effectiveGasPrice: BigInt
+        # ContractAddress is the address of the contract created by the
+        # transaction. If it was not a contract creation, this field is null.
+        contractAddress: Address
+        # Logs is a list of log entries emitted by the transaction.
+        logs: [Log!]!
+        # LogsBloom is a bloom filter of the logs emitted by the transaction.
+        logsBloom: Bytes!
+        # Type is the envelope type of the transaction.
+        type: Int
+        # Raw is the canonical encoding of the receipt. For post EIP-2718 typed
+        # transactions this is equivalent to TxType || ReceiptEncoding.
+        raw: Bytes!
+    }
+
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
     # to a single block.
     input BlockFilterCriteria {
@@ -233,6 +293,10 @@ const schema string = `
         # transactions are unavailable for this block, or if the index is out of
         # bounds, this field will be null.
         transactionAt(index: Int!): Transaction
+        # Receipts is the list of receipts of the transactions in this block,
+        # in transaction order. If transactions are unavailable for this block,
+        # this field will be null.
+        receipts: [Receipt!]
         # Logs returns a filtered set of logs from this block.
         logs(filter: BlockFilterCriteria!): [Log!]!
         # Account fetches an Ethereum account at the current block's state.
@@ -340,8 +404,6 @@ const schema string = `
         pending: Pending!
         # Transaction returns a transaction specified by its hash.
         transaction(hash: Bytes32!): Transaction
-        # Logs returns log entries matching the provided filter.
-        logs(filter: FilterCriteria!): [Log!]!
         # GasPrice returns the node's estimate of a gas price sufficient to
         # ensure a transaction is mined in a timely fashion.
         gasPrice: BigInt!
@@ -359,3 +421,51 @@ const schema string = `
         sendRawTransaction(data: Bytes!): Bytes32!
     }
 `
+
+// querySchema completes the schema served to queries and mutations. The logs
+// field has a subscription counterpart of the same name, so the two are served
+// from different schemas, resolved by different root types.
+const querySchema string = `
+    schema {
+        query: Query
+        mutation: Mutation
+    }
+
+    extend type Query {
+        # Logs returns log entries matching the provided filter.
+        logs(filter: FilterCriteria!): [Log!]!
+    }
+`
+
+// subscriptionSchema completes the schema served to subscriptions.
+const subscriptionSchema string = `
+    schema {
+        query: Query
+        mutation: Mutation
+        subscription: Subscription
+    }
+
+    type Subscription {
+        # NewHeads streams the blocks added to the canonical chain.
+        newHeads: Block!
+        # Logs streams the log entries matching the provided filter as they are
+        # included in the canonical chain. Logs removed by a chain
+        # reorganisation are not reported.
+        logs(filter: BlockFilterCriteria!): Log!
+        # PendingTransactions streams the transactions added to the transaction
+        # pool.
+        pendingTransactions: Transaction!
+    }
+`
+
+// tracingSchema extends the schema with transaction tracing, which re-executes
+// transactions with arbitrary tracers and is thus only exposed when enabled.
+const tracingSchema string = `
+    extend type Transaction {
+        # Trace re-executes the transaction with the given tracer, returning its
+        # result. Without a tracer the struct logger is used. The config takes the
+        # same options as debug_traceTransaction. This will be null if the
+        # transaction has not yet been mined.
+        trace(tracer: String, config: JSON): JSON
+    }
+`
@@ -17,16 +17,23 @@
 package graphql
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/errors"
 )
 
 type handler struct {
-	Schema *graphql.Schema
+	Schema  *graphql.Schema
+	Costs   *costModel    // Cost model rejecting queries above the budget
+	Timeout time.Duration // Maximum execution time of a query, zero for no limit
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -39,8 +46,56 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	// If there's a budget, estimate the cost of the query and reject it before
+	// execution if it's above
+	var cost *queryCost
+	if h.Costs.enabled() {
+		var err error
+		if cost, err = h.Costs.estimate(params.Query, params.OperationName, params.Variables); err != nil {
+			writeResponse(w, &graphql.Response{Errors: []*errors.QueryError{errors.Errorf("failed to estimate query cost: %v", err)}})
+			return
+		}
+		if h.Costs.exceeds(cost) {
+			writeResponse(w, &graphql.Response{
+				Errors:     []*errors.QueryError{errors.Errorf("query cost %d exceeds limit %d", cost.cost, h.Costs.maxCost)},
+				Extensions: h.Costs.extensions(cost),
+			})
+			return
+		}
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Respond with an error once the timeout elapses, cancelling the execution
+	// so the resolvers abort. The query itself is still awaited before returning,
+	// so no execution outlives its request.
+	var (
+		responded sync.Once
+		respond   = func(response *graphql.Response) {
+			responded.Do(func() {
+				if cost != nil {
+					response.Extensions = h.Costs.extensions(cost)
+				}
+				writeResponse(w, response)
+				if flusher, ok := w.(http.Flusher); ok {
+					flusher.Flush()
+				}
+			})
+		}
+	)
+	if h.Timeout > 0 {
+		timer := time.AfterFunc(h.Timeout, func() {
+			respond(&graphql.Response{Errors: []*errors.QueryError{errors.Errorf("request timed out")}})
+			cancel()
+		})
+		defer timer.Stop()
+	}
+	respond(h.Schema.Exec(ctx, params.Query, params.OperationName, params.Variables))
+}
 
-	response := h.Schema.Exec(r.Context(), params.Query, params.OperationName, params.Variables)
+// writeResponse writes a GraphQL response, with a bad request status if it contains
+// any errors.
+func writeResponse(w http.ResponseWriter, response *graphql.Response) {
 	responseJSON, err := json.Marshal(response)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -52,33 +107,59 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(responseJSON)
-
 }
 
-// New constructs a new GraphQL service instance.
-func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
+// New constructs a new GraphQL service instance. Light clients need the light
+// mode of the event system to stream logs, see filters.NewEventSystem.
+func New(stack *node.Node, backend ethapi.Backend, lightMode bool, cors, vhosts []string) error {
 	if backend == nil {
 		panic("missing backend")
 	}
 	// check if http server with given endpoint exists and enable graphQL on it
-	return newHandler(stack, backend, cors, vhosts)
+	return newHandler(stack, backend, lightMode, cors, vhosts)
 }
 
 // newHandler returns a new `http.Handler` that will answer GraphQL queries.
-// It additionally exports an interactive query browser on the / endpoint.
-func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
-	q := Resolver{backend}
-
-	s, err := graphql.ParseSchema(schema, &q)
+// It additionally exports an interactive query browser on the / endpoint, and
+// serves subscriptions over the graphql-ws protocol on the WebSocket endpoint.
+func newHandler(stack *node.Node, backend ethapi.Backend, lightMode bool, cors, vhosts []string) error {
+	q := Resolver{backend: backend}
+	if backend != nil {
+		q.events = filters.NewEventSystem(backend, lightMode)
+	}
+	config := stack.Config()
+	sdl := schema
+	if config.GraphQLTracing {
+		sdl += tracingSchema
+	}
+	queries, err := graphql.ParseSchema(sdl+querySchema, &q)
+	if err != nil {
+		return err
+	}
+	subscriptions, err := graphql.ParseSchema(sdl+subscriptionSchema, &subscriptionResolver{&q})
 	if err != nil {
 		return err
 	}
-	h := handler{Schema: s}
+	queryCosts := newCostModel(queries, backend, config.GraphQLMaxCost, config.GraphQLFieldCosts, config.GraphQLListSizes)
+	subscriptionCosts := newCostModel(subscriptions, backend, config.GraphQLMaxCost, config.GraphQLFieldCosts, config.GraphQLListSizes)
+
+	h := handler{Schema: queries, Costs: queryCosts, Timeout: config.GraphQLTimeout}
 	handler := node.NewHTTPHandlerStack(h, cors, vhosts, nil)
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
 	stack.RegisterHandler("GraphQL", "/graphql", handler)
 	stack.RegisterHandler("GraphQL", "/graphql/", handler)
 
+	ws := node.NewVHostHandler(vhosts, &wsHandler{
+		queries:           queries,
+		queryCosts:        queryCosts,
+		subscriptions:     subscriptions,
+		subscriptionCosts: subscriptionCosts,
+		timeout:           config.GraphQLTimeout,
+		upgrader:          newWSUpgrader(config.WSOrigins),
+	})
+	stack.RegisterWSHandler("GraphQL subscriptions", "/graphql", ws)
+	stack.RegisterWSHandler("GraphQL subscriptions", "/graphql/", ws)
+
 	return nil
 }
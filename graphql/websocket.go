@@ -0,0 +1,369 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	// wsProtocol is the websocket sub-protocol of the GraphQL over WebSocket
+	// transport, as defined by subscriptions-transport-ws.
+	wsProtocol = "graphql-ws"
+
+	wsReadBuffer       = 1024
+	wsWriteBuffer      = 1024
+	wsMessageSizeLimit = 15 * 1024 * 1024
+	wsWriteTimeout     = 10 * time.Second
+	wsKeepAlive        = 30 * time.Second
+
+	// wsMaxSubscriptions is the maximum number of subscriptions a connection
+	// may run concurrently, further ones are rejected until some stop.
+	wsMaxSubscriptions = 128
+)
+
+// Message types of the graphql-ws protocol.
+const (
+	gqlConnectionInit      = "connection_init"      // Client -> Server
+	gqlConnectionTerminate = "connection_terminate" // Client -> Server
+	gqlStart               = "start"                // Client -> Server
+	gqlStop                = "stop"                 // Client -> Server
+	gqlConnectionAck       = "connection_ack"       // Server -> Client
+	gqlConnectionError     = "connection_error"     // Server -> Client
+	gqlConnectionKeepAlive = "ka"                   // Server -> Client
+	gqlData                = "data"                 // Server -> Client
+	gqlError               = "error"                // Server -> Client
+	gqlComplete            = "complete"             // Server -> Client
+)
+
+// wsMessage is a message of the graphql-ws protocol.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsHandler serves GraphQL operations, subscriptions in particular, over
+// websocket connections speaking the graphql-ws protocol.
+type wsHandler struct {
+	queries           *graphql.Schema // Schema running the queries and mutations
+	queryCosts        *costModel      // Cost model rejecting queries and mutations above the budget
+	subscriptions     *graphql.Schema // Schema running the subscriptions
+	subscriptionCosts *costModel      // Cost model rejecting subscriptions above the budget
+	timeout           time.Duration   // Maximum execution time of queries and mutations
+	upgrader          websocket.Upgrader
+}
+
+// newWSUpgrader creates a graphql-ws upgrader accepting connections from the
+// given origins, following the same rules as the websocket RPC endpoint.
+func newWSUpgrader(origins []string) websocket.Upgrader {
+	return websocket.Upgrader{
+		ReadBufferSize:  wsReadBuffer,
+		WriteBufferSize: wsWriteBuffer,
+		Subprotocols:    []string{wsProtocol},
+		CheckOrigin:     wsOriginValidator(origins),
+	}
+}
+
+// wsOriginValidator returns a handshake validator accepting the allowed origins,
+// or localhost if none are configured. Requests without an origin are accepted,
+// as only browsers need to be protected against.
+func wsOriginValidator(allowedOrigins []string) func(*http.Request) bool {
+	origins := make(map[string]bool)
+	for _, origin := range allowedOrigins {
+		if origin != "" {
+			origins[strings.ToLower(origin)] = true
+		}
+	}
+	if len(origins) == 0 {
+		origins["http://localhost"] = true
+		if hostname, err := os.Hostname(); err == nil {
+			origins["http://"+strings.ToLower(hostname)] = true
+		}
+	}
+	return func(r *http.Request) bool {
+		if _, ok := r.Header["Origin"]; !ok {
+			return true
+		}
+		origin := strings.ToLower(r.Header.Get("Origin"))
+		if origins["*"] || origins[origin] {
+			return true
+		}
+		log.Warn("Rejected GraphQL WebSocket connection", "origin", origin)
+		return false
+	}
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL WebSocket upgrade failed", "err", err)
+		return
+	}
+	// Lift the deadlines of the HTTP server, the connection is long lived
+	conn.SetReadDeadline(time.Time{})
+	conn.SetReadLimit(wsMessageSizeLimit)
+
+	newWSConn(h, conn).run()
+}
+
+// wsConn is a graphql-ws connection, running any number of operations.
+type wsConn struct {
+	handler *wsHandler
+	conn    *websocket.Conn
+
+	ctx    context.Context    // Context of the connection, cancelled when it closes
+	cancel context.CancelFunc // Cancels all the running operations
+
+	ops     map[string]context.CancelFunc // Running operations by client chosen id
+	subs    map[string]struct{}           // Ids of the running operations which are subscriptions
+	opsLock sync.Mutex                    // Protects the operations and subscriptions maps
+	opsWg   sync.WaitGroup                // Waits for the running operations to finish
+
+	writeLock sync.Mutex // Serialises the writes to the connection
+}
+
+// newWSConn wraps an upgraded websocket connection.
+func newWSConn(h *wsHandler, conn *websocket.Conn) *wsConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &wsConn{
+		handler: h,
+		conn:    conn,
+		ctx:     ctx,
+		cancel:  cancel,
+		ops:     make(map[string]context.CancelFunc),
+		subs:    make(map[string]struct{}),
+	}
+}
+
+// run processes the messages of the client until the connection is terminated,
+// then tears down all the running operations.
+func (c *wsConn) run() {
+	defer func() {
+		c.cancel()
+		c.opsWg.Wait()
+		c.conn.Close()
+	}()
+	var initialised bool
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case gqlConnectionInit:
+			if !initialised {
+				initialised = true
+				c.write(&wsMessage{Type: gqlConnectionAck})
+				c.write(&wsMessage{Type: gqlConnectionKeepAlive})
+				go c.keepAlive()
+			}
+		case gqlStart:
+			if !initialised {
+				c.write(&wsMessage{Type: gqlConnectionError, Payload: errorPayload("connection not initialised")})
+				return
+			}
+			c.start(msg.ID, msg.Payload)
+
+		case gqlStop:
+			c.stop(msg.ID)
+
+		case gqlConnectionTerminate:
+			return
+
+		default:
+			c.write(&wsMessage{ID: msg.ID, Type: gqlError, Payload: errorPayload("unknown message type " + msg.Type)})
+		}
+	}
+}
+
+// start runs a GraphQL operation, streaming its results to the client until
+// it completes or it's stopped.
+func (c *wsConn) start(id string, payload json.RawMessage) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.Unmarshal(payload, &params); err != nil {
+		c.write(&wsMessage{ID: id, Type: gqlError, Payload: errorPayload(err.Error())})
+		return
+	}
+	// Subscriptions are run by their own schema, and are exempt from the timeout
+	var (
+		subscribe = operationKind(params.Query, params.OperationName) == "subscription"
+		costs     = c.handler.queryCosts
+		timeout   = c.handler.timeout
+	)
+	if subscribe {
+		costs, timeout = c.handler.subscriptionCosts, 0
+	}
+	// Reject operations above the budget before running them
+	var cost *queryCost
+	if costs.enabled() {
+		var err error
+		if cost, err = costs.estimate(params.Query, params.OperationName, params.Variables); err != nil {
+			c.write(&wsMessage{ID: id, Type: gqlError, Payload: errorPayload("failed to estimate query cost: " + err.Error())})
+			return
+		}
+		if costs.exceeds(cost) {
+			c.write(&wsMessage{ID: id, Type: gqlError, Payload: errorPayload(fmt.Sprintf("query cost %d exceeds limit %d", cost.cost, costs.maxCost))})
+			return
+		}
+	}
+	c.opsLock.Lock()
+	if _, ok := c.ops[id]; ok || id == "" {
+		c.opsLock.Unlock()
+		c.write(&wsMessage{ID: id, Type: gqlError, Payload: errorPayload("invalid operation id")})
+		return
+	}
+	if subscribe {
+		if len(c.subs) >= wsMaxSubscriptions {
+			c.opsLock.Unlock()
+			c.write(&wsMessage{ID: id, Type: gqlError, Payload: errorPayload(fmt.Sprintf("too many subscriptions, limit %d", wsMaxSubscriptions))})
+			return
+		}
+		c.subs[id] = struct{}{}
+	}
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(c.ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(c.ctx)
+	}
+	c.ops[id] = cancel
+	c.opsLock.Unlock()
+
+	var responses <-chan interface{}
+	if subscribe {
+		var err error
+		if responses, err = c.handler.subscriptions.Subscribe(ctx, params.Query, params.OperationName, params.Variables); err != nil {
+			c.finish(id)
+			c.write(&wsMessage{ID: id, Type: gqlError, Payload: errorPayload(err.Error())})
+			return
+		}
+	} else {
+		// Queries and mutations yield a single response, the query schema
+		// offering no subscriptions to run them through.
+		single := make(chan interface{}, 1)
+		c.opsWg.Add(1)
+		go func() {
+			defer c.opsWg.Done()
+			single <- c.handler.queries.Exec(ctx, params.Query, params.OperationName, params.Variables)
+			close(single)
+		}()
+		responses = single
+	}
+	c.opsWg.Add(1)
+	go func() {
+		defer c.opsWg.Done()
+
+		// Drain the responses even after a stop, so the executor can terminate
+		for resp := range responses {
+			if ctx.Err() != nil {
+				continue
+			}
+			if r, ok := resp.(*graphql.Response); ok && cost != nil {
+				r.Extensions = costs.extensions(cost)
+			}
+			blob, err := json.Marshal(resp)
+			if err != nil {
+				log.Warn("Failed to marshal GraphQL response", "err", err)
+				continue
+			}
+			c.write(&wsMessage{ID: id, Type: gqlData, Payload: blob})
+		}
+		// Report the completion, unless the client stopped the operation
+		timedOut := ctx.Err() == context.DeadlineExceeded
+		if c.finish(id) {
+			if timedOut {
+				c.write(&wsMessage{ID: id, Type: gqlError, Payload: errorPayload("request timed out")})
+			} else {
+				c.write(&wsMessage{ID: id, Type: gqlComplete})
+			}
+		}
+	}()
+}
+
+// stop cancels a running operation on request of the client.
+func (c *wsConn) stop(id string) {
+	if c.finish(id) {
+		c.write(&wsMessage{ID: id, Type: gqlComplete})
+	}
+}
+
+// finish cancels and forgets an operation, reporting whether it was running.
+func (c *wsConn) finish(id string) bool {
+	c.opsLock.Lock()
+	defer c.opsLock.Unlock()
+
+	cancel, ok := c.ops[id]
+	if ok {
+		cancel()
+		delete(c.ops, id)
+		delete(c.subs, id)
+	}
+	return ok
+}
+
+// keepAlive periodically notifies the client that the connection is alive.
+func (c *wsConn) keepAlive() {
+	ticker := time.NewTicker(wsKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.write(&wsMessage{Type: gqlConnectionKeepAlive})
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// write sends a message to the client. Failures close the connection, which
+// surfaces as a read error terminating it.
+func (c *wsConn) write(msg *wsMessage) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		log.Debug("Failed to write GraphQL WebSocket message", "err", err)
+		c.conn.Close()
+	}
+}
+
+// errorPayload creates the payload of an error message.
+func errorPayload(message string) json.RawMessage {
+	blob, _ := json.Marshal(map[string]string{"message": message})
+	return blob
+}
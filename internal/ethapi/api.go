@@ -208,6 +208,97 @@ func (s *PublicTxPoolAPI) ContentFrom(addr common.Address) map[string]map[string
 	return content
 }
 
+// maxTxPoolPageSize is the maximum number of transactions returned by a single
+// txpool_contentFiltered request.
+const maxTxPoolPageSize = 1000
+
+// TxPoolContentArgs are the filters and the page of the transactions requested
+// from the transaction pool. Unset filters match all transactions.
+type TxPoolContentArgs struct {
+	From   *common.Address `json:"from"`
+	To     *common.Address `json:"to"`
+	MinTip *hexutil.Big    `json:"minTip"` // Minimum effective tip at the current base fee
+	Offset hexutil.Uint    `json:"offset"`
+	Limit  hexutil.Uint    `json:"limit"` // Defaults to, and capped at maxTxPoolPageSize
+}
+
+// matches checks whether the transaction passes the filters.
+func (args *TxPoolContentArgs) matches(tx *types.Transaction, baseFee *big.Int) bool {
+	if args.To != nil && (tx.To() == nil || *tx.To() != *args.To) {
+		return false
+	}
+	if args.MinTip != nil {
+		tip, err := tx.EffectiveGasTip(baseFee)
+		if err != nil || tip.Cmp(args.MinTip.ToInt()) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// TxPoolContentPage is a page of the filtered pool content. The pending and the
+// queued transactions are paged together, in this order, each sorted by sender
+// and nonce.
+type TxPoolContentPage struct {
+	Pending []*RPCTransaction `json:"pending"`
+	Queued  []*RPCTransaction `json:"queued"`
+	Next    *hexutil.Uint     `json:"next"` // Offset of the next page, nil on the last page
+}
+
+// ContentFiltered returns a page of the transactions in the transaction pool
+// matching the given filters.
+func (s *PublicTxPoolAPI) ContentFiltered(args TxPoolContentArgs) *TxPoolContentPage {
+	limit := int(args.Limit)
+	if limit == 0 || limit > maxTxPoolPageSize {
+		limit = maxTxPoolPageSize
+	}
+	curHeader := s.b.CurrentHeader()
+	filter := func(tx *types.Transaction) bool {
+		return args.matches(tx, curHeader.BaseFee)
+	}
+	pending, queued, more := s.b.TxPoolContentFiltered(args.From, filter, int(args.Offset), limit)
+
+	page := &TxPoolContentPage{
+		Pending: make([]*RPCTransaction, 0, len(pending)),
+		Queued:  make([]*RPCTransaction, 0, len(queued)),
+	}
+	for _, tx := range pending {
+		page.Pending = append(page.Pending, newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig()))
+	}
+	for _, tx := range queued {
+		page.Queued = append(page.Queued, newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig()))
+	}
+	if more {
+		page.Next = new(hexutil.Uint)
+		*page.Next = args.Offset + hexutil.Uint(limit)
+	}
+	return page
+}
+
+// RPCTxStatus is the status of a transaction in the transaction pool.
+type RPCTxStatus struct {
+	Hash   common.Hash `json:"hash"`
+	Status string      `json:"status"`           // One of unknown, pending or queued
+	Reason string      `json:"reason,omitempty"` // Why the transaction isn't includable into the next block
+}
+
+// TransactionStatus returns the status of the transactions with the given hashes
+// in the transaction pool, along with the reason why they aren't includable into
+// the next block, e.g. a nonce gap, insufficient balance or a fee cap below the
+// base fee.
+func (s *PublicTxPoolAPI) TransactionStatus(hashes []common.Hash) []*RPCTxStatus {
+	status, reasons := s.b.TxPoolStatus(hashes)
+
+	result := make([]*RPCTxStatus, len(hashes))
+	for i, hash := range hashes {
+		result[i] = &RPCTxStatus{Hash: hash, Status: status[i].String()}
+		if reasons[i] != nil {
+			result[i].Reason = reasons[i].Error()
+		}
+	}
+	return result
+}
+
 // Status returns the number of pending and queued transaction in the pool.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
@@ -252,6 +343,72 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	return content
 }
 
+// RPCTxChange is a status change of a transaction in the transaction pool, as
+// delivered to the txpool_subscribe("changes") subscribers.
+type RPCTxChange struct {
+	Kind       string         `json:"kind"`
+	Hash       common.Hash    `json:"hash"`
+	From       common.Address `json:"from"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+}
+
+// newRPCTxChange returns the RPC representation of a transaction pool change.
+func newRPCTxChange(change *core.TxChange, signer types.Signer) *RPCTxChange {
+	from, _ := types.Sender(signer, change.Tx)
+	result := &RPCTxChange{
+		Kind:  change.Kind.String(),
+		Hash:  change.Tx.Hash(),
+		From:  from,
+		Nonce: hexutil.Uint64(change.Tx.Nonce()),
+	}
+	if change.Kind == core.TxChangeReplaced {
+		replacedBy := change.ReplacedBy
+		result.ReplacedBy = &replacedBy
+	}
+	if change.Reason != nil {
+		result.Reason = change.Reason.Error()
+	}
+	return result
+}
+
+// Changes creates a subscription that is triggered each time a transaction is
+// added to, promoted, demoted, replaced, included or dropped from the transaction
+// pool.
+func (s *PublicTxPoolAPI) Changes(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		var (
+			changes = make(chan core.TxChangesEvent, 128)
+			sub     = s.b.SubscribeTxChangesEvent(changes)
+			signer  = types.LatestSigner(s.b.ChainConfig())
+		)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-changes:
+				for _, change := range ev.Changes {
+					notifier.Notify(rpcSub.ID, newRPCTxChange(change, signer))
+				}
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -766,6 +923,24 @@ func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Ha
 	return nil, err
 }
 
+// GetBlockReceipts returns all the transaction receipts of the requested block,
+// in the same format as eth_getTransactionReceipt.
+func (s *PublicBlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		return nil, errors.New("receipts of the pending block are not available")
+	}
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		// When the block doesn't exist, the RPC method should return JSON null
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return RPCMarshalReceipts(block, receipts, s.b.ChainConfig())
+}
+
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index.
 func (s *PublicBlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
 	block, err := s.b.BlockByNumber(ctx, blockNr)
@@ -1595,13 +1770,45 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	// Derive the sender.
 	bigblock := new(big.Int).SetUint64(blockNumber)
 	signer := types.MakeSigner(s.b.ChainConfig(), bigblock)
+
+	// Assign the effective gas price paid
+	var baseFee *big.Int
+	if s.b.ChainConfig().IsLondon(bigblock) {
+		header, err := s.b.HeaderByHash(ctx, blockHash)
+		if err != nil {
+			return nil, err
+		}
+		baseFee = header.BaseFee
+	}
+	return marshalReceipt(receipt, blockHash, blockNumber, signer, tx, int(index), baseFee), nil
+}
+
+// RPCMarshalReceipts converts the receipts of the given block into the format of
+// eth_getTransactionReceipt.
+func RPCMarshalReceipts(block *types.Block, receipts types.Receipts, config *params.ChainConfig) ([]map[string]interface{}, error) {
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	signer := types.MakeSigner(config, block.Number())
+
+	result := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		result[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], i, block.BaseFee())
+	}
+	return result, nil
+}
+
+// marshalReceipt marshals a transaction receipt into a JSON object. The base
+// fee is that of the block containing the transaction, nil before London.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, txIndex int, baseFee *big.Int) map[string]interface{} {
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
-		"transactionIndex":  hexutil.Uint64(index),
+		"transactionHash":   tx.Hash(),
+		"transactionIndex":  hexutil.Uint64(txIndex),
 		"from":              from,
 		"to":                tx.To(),
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
@@ -1612,14 +1819,10 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 		"type":              hexutil.Uint(tx.Type()),
 	}
 	// Assign the effective gas price paid
-	if !s.b.ChainConfig().IsLondon(bigblock) {
+	if baseFee == nil {
 		fields["effectiveGasPrice"] = hexutil.Uint64(tx.GasPrice().Uint64())
 	} else {
-		header, err := s.b.HeaderByHash(ctx, blockHash)
-		if err != nil {
-			return nil, err
-		}
-		gasPrice := new(big.Int).Add(header.BaseFee, tx.EffectiveGasTipValue(header.BaseFee))
+		gasPrice := new(big.Int).Add(baseFee, tx.EffectiveGasTipValue(baseFee))
 		fields["effectiveGasPrice"] = hexutil.Uint64(gasPrice.Uint64())
 	}
 	// Assign receipt status or post state.
@@ -1635,7 +1838,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -81,7 +81,10 @@ type Backend interface {
 	Stats() (pending int, queued int)
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
 	TxPoolContentFrom(addr common.Address) (types.Transactions, types.Transactions)
+	TxPoolContentFiltered(from *common.Address, filter func(*types.Transaction) bool, offset, limit int) (types.Transactions, types.Transactions, bool)
+	TxPoolStatus(hashes []common.Hash) ([]core.TxStatus, []error)
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	SubscribeTxChangesEvent(chan<- core.TxChangesEvent) event.Subscription
 
 	// Filter API
 	BloomStatus() (uint64, uint64)
@@ -89,6 +92,7 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
+	SubscribePendingBlockEvent(ch chan<- core.PendingBlockEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 
 	ChainConfig() *params.ChainConfig
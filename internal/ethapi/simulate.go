@@ -0,0 +1,404 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks that can be simulated
+	// in a single request.
+	maxSimulateBlocks = 256
+
+	// simulateBlockTime is the default time difference between two
+	// consecutive simulated blocks, unless overridden.
+	simulateBlockTime = 12
+
+	// errCodeVMError is the JSON-RPC error code of a call that failed
+	// due to an EVM error other than a revert.
+	errCodeVMError = -32015
+)
+
+// SimulateBlock is a batch of calls to be executed sequentially within a
+// single simulated block, on top of the state left behind by the previous
+// blocks of the same request.
+type SimulateBlock struct {
+	BlockOverrides *BlockOverrides   `json:"blockOverrides"`
+	StateOverrides *StateOverride    `json:"stateOverrides"`
+	Calls          []TransactionArgs `json:"calls"`
+}
+
+// SimulateOpts is the wrapper for the eth_simulateV1 request parameters.
+type SimulateOpts struct {
+	BlockStateCalls []SimulateBlock `json:"blockStateCalls"`
+
+	// Validation enables the checks done on real inclusion of a transaction:
+	// nonces must match, the sender must be able to pay for gas and value,
+	// and the fee caps must cover the block's base fee.
+	Validation bool `json:"validation"`
+}
+
+// simCallError is the error of a single simulated call.
+type simCallError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+	Data    string `json:"data,omitempty"`
+}
+
+// simCallResult is the outcome of a single simulated call.
+type simCallResult struct {
+	ReturnValue hexutil.Bytes  `json:"returnData"`
+	Logs        []*types.Log   `json:"logs"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	Status      hexutil.Uint64 `json:"status"`
+	Error       *simCallError  `json:"error,omitempty"`
+}
+
+// simBlockResult is the outcome of a simulated block.
+type simBlockResult struct {
+	Number        hexutil.Uint64  `json:"number"`
+	Hash          common.Hash     `json:"hash"`
+	Timestamp     hexutil.Uint64  `json:"timestamp"`
+	GasLimit      hexutil.Uint64  `json:"gasLimit"`
+	GasUsed       hexutil.Uint64  `json:"gasUsed"`
+	FeeRecipient  common.Address  `json:"miner"`
+	BaseFeePerGas *hexutil.Big    `json:"baseFeePerGas,omitempty"`
+	Calls         []simCallResult `json:"calls"`
+}
+
+// simulator executes the blocks of a simulation request one after the other
+// on top of a single state.
+type simulator struct {
+	b          Backend
+	state      *state.StateDB
+	gasCap     uint64 // Remaining gas allowance of the whole request
+	validate   bool
+	timeout    time.Duration
+	prevHeader *types.Header
+
+	hashes   map[uint64]common.Hash // Hashes of the simulated blocks and the resolved ancestors
+	ancestor *types.Header          // Oldest ancestor of the simulated blocks resolved so far
+
+	evm     *vm.EVM    // EVM executing the current call, cancelled on timeout
+	evmLock sync.Mutex // Lock protecting the current EVM against concurrent cancellation
+}
+
+// SimulateV1 executes series of calls grouped in blocks on top of the state of
+// the given block. Every call sees the state changes made by the preceding ones,
+// so multi-step interactions (e.g. approve followed by a swap) can be previewed.
+// Each block can override the block header fields and the state before its
+// calls are executed.
+func (s *PublicBlockChainAPI) SimulateV1(ctx context.Context, opts SimulateOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]*simBlockResult, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, errors.New("empty input")
+	} else if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many blocks, max %d", maxSimulateBlocks)
+	}
+	if blockNrOrHash == nil {
+		n := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &n
+	}
+	state, base, err := s.b.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	gasCap := s.b.RPCGasCap()
+	if gasCap == 0 {
+		gasCap = math.MaxUint64
+	}
+	sim := &simulator{
+		b:          s.b,
+		state:      state,
+		gasCap:     gasCap,
+		validate:   opts.Validation,
+		timeout:    s.b.RPCEVMTimeout(),
+		prevHeader: base,
+		hashes:     map[uint64]common.Hash{base.Number.Uint64(): base.Hash()},
+		ancestor:   base,
+	}
+	return sim.execute(ctx, opts.BlockStateCalls)
+}
+
+// execute runs all the simulated blocks, aborting on the first error which
+// would have prevented the inclusion of a transaction.
+func (sim *simulator) execute(ctx context.Context, blocks []SimulateBlock) ([]*simBlockResult, error) {
+	defer func(start time.Time) { log.Debug("Executing simulation finished", "runtime", time.Since(start)) }(time.Now())
+
+	// Setup context so it may be cancelled when the simulation has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if sim.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, sim.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	// Wait for the context to be done and cancel the running EVM, if any. The
+	// EVMs created afterwards are cancelled right away.
+	go func() {
+		<-ctx.Done()
+
+		sim.evmLock.Lock()
+		defer sim.evmLock.Unlock()
+		if sim.evm != nil {
+			sim.evm.Cancel()
+		}
+	}()
+	results := make([]*simBlockResult, len(blocks))
+	for i, block := range blocks {
+		header, err := sim.makeHeader(block.BlockOverrides)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		if err := block.StateOverrides.Apply(sim.state); err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		result, err := sim.processBlock(ctx, header, block.Calls)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		results[i] = result
+		sim.prevHeader = header
+		sim.hashes[header.Number.Uint64()] = header.Hash()
+	}
+	return results, nil
+}
+
+// makeHeader assembles the header of the next simulated block, derived from
+// the previous one and the user supplied overrides.
+func (sim *simulator) makeHeader(overrides *BlockOverrides) (*types.Header, error) {
+	var (
+		parent = sim.prevHeader
+		config = sim.b.ChainConfig()
+		header = &types.Header{
+			ParentHash: parent.Hash(),
+			Coinbase:   parent.Coinbase,
+			Difficulty: new(big.Int).Set(parent.Difficulty),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + simulateBlockTime,
+			MixDigest:  parent.MixDigest,
+		}
+	)
+	if overrides != nil {
+		if overrides.Number != nil {
+			if overrides.Number.ToInt().Cmp(parent.Number) <= 0 {
+				return nil, fmt.Errorf("block number not increasing: %v <= %v", overrides.Number.ToInt(), parent.Number)
+			}
+			header.Number = new(big.Int).Set(overrides.Number.ToInt())
+		}
+		if overrides.Time != nil {
+			if !overrides.Time.ToInt().IsUint64() || overrides.Time.ToInt().Uint64() <= parent.Time {
+				return nil, fmt.Errorf("block timestamp not increasing: %v <= %d", overrides.Time.ToInt(), parent.Time)
+			}
+			header.Time = overrides.Time.ToInt().Uint64()
+		}
+		if overrides.Difficulty != nil {
+			header.Difficulty = new(big.Int).Set(overrides.Difficulty.ToInt())
+		}
+		if overrides.GasLimit != nil {
+			header.GasLimit = uint64(*overrides.GasLimit)
+		}
+		if overrides.Coinbase != nil {
+			header.Coinbase = *overrides.Coinbase
+		}
+		if overrides.Random != nil {
+			header.MixDigest = *overrides.Random
+		}
+	}
+	if config.IsLondon(header.Number) {
+		if parent.BaseFee == nil {
+			// Simulating across the fork block, start from the initial fee
+			header.BaseFee = misc.CalcBaseFee(config, &types.Header{Number: parent.Number, GasLimit: parent.GasLimit})
+		} else {
+			header.BaseFee = misc.CalcBaseFee(config, parent)
+		}
+	}
+	return header, nil
+}
+
+// processBlock executes the calls of a single simulated block.
+func (sim *simulator) processBlock(ctx context.Context, header *types.Header, calls []TransactionArgs) (*simBlockResult, error) {
+	var (
+		gp      = new(core.GasPool).AddGas(header.GasLimit)
+		gasUsed uint64
+		results = make([]simCallResult, len(calls))
+		rules   = sim.b.ChainConfig().Rules(header.Number, header.Difficulty.Sign() == 0)
+	)
+	for i, args := range calls {
+		msg, err := sim.makeMessage(&args, header, gp.Gas())
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		// Tag the logs with the hash of the call as an unsigned transaction,
+		// unique within the request since the sender nonce is filled in
+		txHash := args.toTransaction().Hash()
+		sim.state.Prepare(txHash, i)
+
+		evm, vmError, err := sim.b.GetEVM(ctx, msg, sim.state, header, &vm.Config{NoBaseFee: !sim.validate})
+		if err != nil {
+			return nil, err
+		}
+		// Resolve the block hashes against the simulated chain, not the local one
+		evm.Context.GetHash = sim.getHash(ctx)
+
+		sim.evmLock.Lock()
+		sim.evm = evm
+		if ctx.Err() != nil {
+			evm.Cancel()
+		}
+		sim.evmLock.Unlock()
+
+		result, err := core.ApplyMessage(evm, msg, gp)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		// If the timer caused an abort, return an appropriate error message
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", sim.timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w (supplied gas %d)", i, err, msg.Gas())
+		}
+		// Make the changes of the call visible to the next one
+		sim.state.Finalise(rules.IsEIP158)
+
+		gasUsed += result.UsedGas
+		sim.gasCap -= result.UsedGas
+		call := simCallResult{
+			ReturnValue: result.Return(),
+			Logs:        sim.state.GetLogs(txHash, common.Hash{}),
+			GasUsed:     hexutil.Uint64(result.UsedGas),
+			Status:      hexutil.Uint64(types.ReceiptStatusSuccessful),
+		}
+		if call.Logs == nil {
+			call.Logs = []*types.Log{}
+		}
+		if result.Failed() {
+			call.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if len(result.Revert()) > 0 {
+				revertErr := newRevertError(result)
+				call.ReturnValue = result.Revert()
+				call.Error = &simCallError{
+					Message: revertErr.Error(),
+					Code:    revertErr.ErrorCode(),
+					Data:    revertErr.reason,
+				}
+			} else {
+				call.Error = &simCallError{Message: result.Err.Error(), Code: errCodeVMError}
+			}
+		}
+		results[i] = call
+	}
+	// The block hash is only known once the gas used is filled in, tag the
+	// logs emitted during execution retroactively.
+	header.GasUsed = gasUsed
+	blockHash := header.Hash()
+	for _, call := range results {
+		for _, l := range call.Logs {
+			l.BlockHash = blockHash
+			l.BlockNumber = header.Number.Uint64()
+		}
+	}
+	res := &simBlockResult{
+		Number:       hexutil.Uint64(header.Number.Uint64()),
+		Hash:         blockHash,
+		Timestamp:    hexutil.Uint64(header.Time),
+		GasLimit:     hexutil.Uint64(header.GasLimit),
+		GasUsed:      hexutil.Uint64(gasUsed),
+		FeeRecipient: header.Coinbase,
+		Calls:        results,
+	}
+	if header.BaseFee != nil {
+		res.BaseFeePerGas = (*hexutil.Big)(header.BaseFee)
+	}
+	return res, nil
+}
+
+// makeMessage converts the call arguments into a message ready for execution.
+// The default gas allowance of a call is whatever is left in the block, capped
+// by the remaining global gas cap of the request.
+func (sim *simulator) makeMessage(args *TransactionArgs, header *types.Header, blockGasLeft uint64) (types.Message, error) {
+	gasCap := blockGasLeft
+	if sim.gasCap < gasCap {
+		gasCap = sim.gasCap
+	}
+	if gasCap == 0 {
+		return types.Message{}, errors.New("gas allowance exhausted")
+	}
+	// Calls not fitting into the block would fail on inclusion, reject them
+	// instead of capping their gas like the global limit
+	if args.Gas != nil && uint64(*args.Gas) > blockGasLeft {
+		return types.Message{}, fmt.Errorf("%w: have %d, block gas left %d", core.ErrGasLimitReached, uint64(*args.Gas), blockGasLeft)
+	}
+	if args.Gas == nil {
+		gas := hexutil.Uint64(gasCap)
+		args.Gas = &gas
+	}
+	if args.Nonce == nil {
+		nonce := hexutil.Uint64(sim.state.GetNonce(args.from()))
+		args.Nonce = &nonce
+	}
+	msg, err := args.ToMessage(gasCap, header.BaseFee)
+	if err != nil {
+		return types.Message{}, err
+	}
+	if !sim.validate {
+		return msg, nil
+	}
+	// Validation is requested, turn the call into a proper transaction
+	// message so nonce and balance checks are performed.
+	return types.NewMessage(msg.From(), msg.To(), uint64(*args.Nonce), msg.Value(), msg.Gas(), msg.GasPrice(), msg.GasFeeCap(), msg.GasTipCap(), msg.Data(), msg.AccessList(), false), nil
+}
+
+// getHash returns the BLOCKHASH resolver of the simulated blocks. The simulated
+// blocks resolve to their own hashes, the ones before them to the ancestors of
+// the base block. Block numbers skipped by the overrides resolve to zero.
+func (sim *simulator) getHash(ctx context.Context) vm.GetHashFunc {
+	return func(number uint64) common.Hash {
+		if hash, ok := sim.hashes[number]; ok {
+			return hash
+		}
+		for sim.ancestor.Number.Uint64() > number {
+			parent, err := sim.b.HeaderByHash(ctx, sim.ancestor.ParentHash)
+			if parent == nil || err != nil {
+				return common.Hash{}
+			}
+			sim.ancestor = parent
+			sim.hashes[parent.Number.Uint64()] = parent.Hash()
+		}
+		return sim.hashes[number]
+	}
+}
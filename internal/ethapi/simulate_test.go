@@ -0,0 +1,258 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	simKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	simAddr     = crypto.PubkeyToAddress(simKey.PublicKey)
+	simContract = common.HexToAddress("0xc0de")
+
+	// simBlockHashCode returns the BLOCKHASH of the number passed as calldata.
+	simBlockHashCode = hexutil.Bytes(common.FromHex("0x6000354060005260206000f3"))
+
+	// simBalanceCode returns the BALANCE of the address passed as calldata.
+	simBalanceCode = hexutil.Bytes(common.FromHex("0x6000353160005260206000f3"))
+
+	// simLogCode emits an empty LOG0.
+	simLogCode = hexutil.Bytes(common.FromHex("0x60006000a000"))
+)
+
+// simBackend is a Backend serving the calls of the simulator from a local chain.
+type simBackend struct {
+	Backend
+	chain  *core.BlockChain
+	gasCap uint64
+}
+
+func newSimBackend(t *testing.T, blocks int) *simBackend {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = &core.Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   core.GenesisAlloc{simAddr: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		engine = ethash.NewFaker()
+	)
+	gblock := genesis.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	generated, _ := core.GenerateChain(genesis.Config, gblock, engine, db, blocks, func(int, *core.BlockGen) {})
+	if _, err := chain.InsertChain(generated); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	t.Cleanup(chain.Stop)
+	return &simBackend{chain: chain}
+}
+
+func (b *simBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
+func (b *simBackend) RPCGasCap() uint64                { return b.gasCap }
+func (b *simBackend) RPCEVMTimeout() time.Duration     { return time.Second }
+
+func (b *simBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *simBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header := b.chain.CurrentHeader()
+	state, err := b.chain.StateAt(header.Root)
+	return state, header, err
+}
+
+func (b *simBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	context := core.NewEVMBlockContext(header, b.chain, nil)
+	return vm.NewEVM(context, core.NewEVMTxContext(msg), state, b.chain.Config(), *vmConfig), func() error { return nil }, nil
+}
+
+// simCall creates the arguments of a call from the funded account.
+func simCall(to common.Address, value int64, input []byte) TransactionArgs {
+	data := hexutil.Bytes(input)
+	return TransactionArgs{
+		From:  &simAddr,
+		To:    &to,
+		Value: (*hexutil.Big)(big.NewInt(value)),
+		Input: &data,
+	}
+}
+
+// simContracts overrides the test contracts into the state.
+func simContracts() *StateOverride {
+	return &StateOverride{
+		simContract:                   OverrideAccount{Code: &simBlockHashCode},
+		common.HexToAddress("0xba1a"): OverrideAccount{Code: &simBalanceCode},
+		common.HexToAddress("0x1095"): OverrideAccount{Code: &simLogCode},
+	}
+}
+
+// Tests that the simulated blocks are chained: the state changes, block numbers,
+// timestamps and block hashes of a block are visible to the following ones.
+func TestSimulateV1Chaining(t *testing.T) {
+	backend := newSimBackend(t, 4)
+	api := NewPublicBlockChainAPI(backend)
+
+	var (
+		recipient = common.HexToAddress("0xdead")
+		head      = backend.chain.CurrentHeader()
+		number    = func(n uint64) []byte { return common.BigToHash(new(big.Int).SetUint64(n)).Bytes() }
+	)
+	results, err := api.SimulateV1(context.Background(), SimulateOpts{
+		BlockStateCalls: []SimulateBlock{
+			{
+				StateOverrides: simContracts(),
+				Calls:          []TransactionArgs{simCall(recipient, 1000, nil)},
+			},
+			{
+				BlockOverrides: &BlockOverrides{Number: (*hexutil.Big)(big.NewInt(8))},
+				Calls: []TransactionArgs{
+					simCall(common.HexToAddress("0xba1a"), 0, common.LeftPadBytes(recipient.Bytes(), 32)),
+					simCall(simContract, 0, number(head.Number.Uint64()-1)),
+					simCall(simContract, 0, number(head.Number.Uint64()+1)),
+					simCall(simContract, 0, number(head.Number.Uint64()+2)),
+				},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("block count mismatch: have %d, want 2", len(results))
+	}
+	if have, want := uint64(results[0].Number), head.Number.Uint64()+1; have != want {
+		t.Errorf("block 0 number mismatch: have %d, want %d", have, want)
+	}
+	if have, want := uint64(results[0].Timestamp), head.Time+simulateBlockTime; have != want {
+		t.Errorf("block 0 timestamp mismatch: have %d, want %d", have, want)
+	}
+	if have, want := uint64(results[1].Number), uint64(8); have != want {
+		t.Errorf("block 1 number mismatch: have %d, want %d", have, want)
+	}
+	calls := results[1].Calls
+	if have := new(big.Int).SetBytes(calls[0].ReturnValue); have.Int64() != 1000 {
+		t.Errorf("transfer of block 0 not visible: balance %v, want 1000", have)
+	}
+	if have, want := common.BytesToHash(calls[1].ReturnValue), backend.chain.GetHeaderByNumber(head.Number.Uint64()-1).Hash(); have != want {
+		t.Errorf("ancestor block hash mismatch: have %x, want %x", have, want)
+	}
+	if have, want := common.BytesToHash(calls[2].ReturnValue), results[0].Hash; have != want {
+		t.Errorf("simulated block hash mismatch: have %x, want %x", have, want)
+	}
+	if have := common.BytesToHash(calls[3].ReturnValue); have != (common.Hash{}) {
+		t.Errorf("skipped block hash mismatch: have %x, want zero", have)
+	}
+}
+
+// Tests that the logs of identical calls are attributed to each of them.
+func TestSimulateV1Logs(t *testing.T) {
+	api := NewPublicBlockChainAPI(newSimBackend(t, 1))
+
+	emitter := common.HexToAddress("0x1095")
+	results, err := api.SimulateV1(context.Background(), SimulateOpts{
+		BlockStateCalls: []SimulateBlock{{
+			StateOverrides: simContracts(),
+			Calls:          []TransactionArgs{simCall(emitter, 0, nil), simCall(emitter, 0, nil)},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	calls := results[0].Calls
+	if len(calls[0].Logs) != 1 || len(calls[1].Logs) != 1 {
+		t.Fatalf("log count mismatch: have %d and %d, want 1 each", len(calls[0].Logs), len(calls[1].Logs))
+	}
+	if calls[0].Logs[0].TxHash == calls[1].Logs[0].TxHash {
+		t.Errorf("identical calls share transaction hash %x", calls[0].Logs[0].TxHash)
+	}
+	if calls[1].Logs[0].TxIndex != 1 || calls[1].Logs[0].BlockHash != results[0].Hash {
+		t.Errorf("log position mismatch: index %d, block %x", calls[1].Logs[0].TxIndex, calls[1].Logs[0].BlockHash)
+	}
+	for i, call := range calls {
+		if have, want := call.Logs[0].BlockNumber, uint64(results[0].Number); have != want {
+			t.Errorf("call %d: log block number mismatch: have %d, want %d", i, have, want)
+		}
+	}
+}
+
+// Tests that the requests exceeding the block, call or request limits are rejected.
+func TestSimulateV1Limits(t *testing.T) {
+	backend := newSimBackend(t, 1)
+	api := NewPublicBlockChainAPI(backend)
+
+	var (
+		recipient = common.HexToAddress("0xdead")
+		gasLimit  = hexutil.Uint64(params.TxGas)
+		tooMuch   = hexutil.Uint64(params.TxGas + 1)
+		transfer  = simCall(recipient, 1, nil)
+		overGas   = simCall(recipient, 1, nil)
+	)
+	overGas.Gas = &tooMuch
+
+	tests := []struct {
+		gasCap uint64
+		blocks []SimulateBlock
+		err    string
+	}{
+		{blocks: nil, err: "empty input"},
+		{blocks: make([]SimulateBlock, maxSimulateBlocks+1), err: "too many blocks"},
+		{
+			blocks: []SimulateBlock{{}, {BlockOverrides: &BlockOverrides{Number: (*hexutil.Big)(backend.chain.CurrentHeader().Number)}}},
+			err:    "block 1: block number not increasing",
+		},
+		{
+			blocks: []SimulateBlock{{BlockOverrides: &BlockOverrides{GasLimit: &gasLimit}, Calls: []TransactionArgs{overGas}}},
+			err:    "block 0: call 0: gas limit reached",
+		},
+		{
+			blocks: []SimulateBlock{{BlockOverrides: &BlockOverrides{GasLimit: &gasLimit}, Calls: []TransactionArgs{transfer, transfer}}},
+			err:    "block 0: call 1: gas allowance exhausted",
+		},
+		{
+			gasCap: params.TxGas,
+			blocks: []SimulateBlock{{Calls: []TransactionArgs{transfer}}, {Calls: []TransactionArgs{transfer}}},
+			err:    "block 1: call 0: gas allowance exhausted",
+		},
+	}
+	for i, tt := range tests {
+		backend.gasCap = tt.gasCap
+		_, err := api.SimulateV1(context.Background(), SimulateOpts{BlockStateCalls: tt.blocks}, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+		}
+	}
+}
@@ -227,10 +227,32 @@ func (b *LesApiBackend) TxPoolContentFrom(addr common.Address) (types.Transactio
 	return b.eth.txPool.ContentFrom(addr)
 }
 
+func (b *LesApiBackend) TxPoolContentFiltered(from *common.Address, filter func(*types.Transaction) bool, offset, limit int) (types.Transactions, types.Transactions, bool) {
+	return b.eth.txPool.ContentFiltered(from, filter, offset, limit)
+}
+
+func (b *LesApiBackend) TxPoolStatus(hashes []common.Hash) ([]core.TxStatus, []error) {
+	// The light pool only tracks the pending transactions
+	status := make([]core.TxStatus, len(hashes))
+	for i, hash := range hashes {
+		if b.eth.txPool.GetTransaction(hash) != nil {
+			status[i] = core.TxStatusPending
+		}
+	}
+	return status, make([]error, len(hashes))
+}
+
 func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.txPool.SubscribeNewTxsEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeTxChangesEvent(ch chan<- core.TxChangesEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.eth.blockchain.SubscribeChainEvent(ch)
 }
@@ -254,6 +276,13 @@ func (b *LesApiBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.
 	})
 }
 
+func (b *LesApiBackend) SubscribePendingBlockEvent(ch chan<- core.PendingBlockEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.eth.blockchain.SubscribeRemovedLogsEvent(ch)
 }
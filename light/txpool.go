@@ -17,9 +17,11 @@
 package light
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
@@ -505,6 +507,38 @@ func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common
 	return pending, queued
 }
 
+// ContentFiltered retrieves a page of the transactions in the pool accepted by the
+// given filter, optionally restricted to a single sender, sorted by sender and
+// nonce. The returned flag reports whether there are more matching transactions
+// beyond the page. There are no queued transactions in a light pool.
+func (pool *TxPool) ContentFiltered(from *common.Address, filter func(*types.Transaction) bool, offset, limit int) (types.Transactions, types.Transactions, bool) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	type senderTx struct {
+		from common.Address
+		tx   *types.Transaction
+	}
+	var matches []senderTx
+	for _, tx := range pool.pending {
+		account, _ := types.Sender(pool.signer, tx)
+		if (from == nil || account == *from) && filter(tx) {
+			matches = append(matches, senderTx{account, tx})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if c := bytes.Compare(matches[i].from[:], matches[j].from[:]); c != 0 {
+			return c < 0
+		}
+		return matches[i].tx.Nonce() < matches[j].tx.Nonce()
+	})
+	var pending types.Transactions
+	for i := offset; i < len(matches) && i < offset+limit; i++ {
+		pending = append(pending, matches[i].tx)
+	}
+	return pending, nil, offset+limit < len(matches)
+}
+
 // ContentFrom retrieves the data content of the transaction pool, returning the
 // pending as well as queued transactions of this address, grouped by nonce.
 func (pool *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
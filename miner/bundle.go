@@ -0,0 +1,264 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// maxBundles is the maximum number of bundles tracked by the bundle pool.
+	maxBundles = 1024
+
+	// maxBundlesPerSender is the maximum number of bundles tracked by the bundle
+	// pool for a single sender, the signer of the first bundle transaction.
+	maxBundlesPerSender = 16
+
+	// maxBundleFutureBlocks is the maximum number of blocks a bundle can target
+	// ahead of the current head.
+	maxBundleFutureBlocks = 16
+)
+
+var (
+	// ErrBundleEmpty is returned if a bundle without transactions is submitted.
+	ErrBundleEmpty = errors.New("empty bundle")
+
+	// ErrBundleStale is returned if a bundle targets an already mined block.
+	ErrBundleStale = errors.New("bundle target block already mined")
+
+	// ErrBundleTooFarFuture is returned if a bundle targets a block too far ahead
+	// of the current head.
+	ErrBundleTooFarFuture = errors.New("bundle target block too far in the future")
+
+	// ErrBundleTimestampRange is returned if the minimum timestamp of a bundle is
+	// above its maximum timestamp.
+	ErrBundleTimestampRange = errors.New("bundle minimum timestamp above maximum")
+
+	// ErrBundlePoolFull is returned if the bundle pool is full and the bundle
+	// doesn't target an earlier block or pay more than the tracked ones.
+	ErrBundlePoolFull = errors.New("bundle pool full")
+
+	// ErrBundleSenderLimit is returned if the sender of a bundle already has the
+	// maximum number of bundles tracked by the bundle pool.
+	ErrBundleSenderLimit = errors.New("too many bundles from sender")
+
+	// errBundleTxReverted is returned if a bundle transaction was included but
+	// its execution failed.
+	errBundleTxReverted = errors.New("bundle transaction reverted")
+)
+
+// Bundle is an ordered group of transactions which must be included into the
+// target block together, contiguously and all-or-nothing.
+type Bundle struct {
+	Txs          types.Transactions // Transactions to include in the given order
+	BlockNumber  uint64             // Number of the block to include the bundle in
+	MinTimestamp uint64             // Minimum timestamp of the block (0 = no limit)
+	MaxTimestamp uint64             // Maximum timestamp of the block (0 = no limit)
+}
+
+// Hash returns the hash of the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// price returns the lowest effective gas tip of the bundle transactions with the
+// given base fee, the tip the bundle is guaranteed to pay for every unit of gas.
+func (b *Bundle) price(baseFee *big.Int) *big.Int {
+	var price *big.Int
+	for _, tx := range b.Txs {
+		// A fee cap below the base fee yields a negative tip, ranking the
+		// bundle below all the includable ones
+		tip, _ := tx.EffectiveGasTip(baseFee)
+		if price == nil || tip.Cmp(price) < 0 {
+			price = tip
+		}
+	}
+	return price
+}
+
+// includable checks whether the bundle can be included into the block with the
+// given number and timestamp.
+func (b *Bundle) includable(number uint64, timestamp uint64) bool {
+	if b.BlockNumber != number {
+		return false
+	}
+	if b.MinTimestamp != 0 && timestamp < b.MinTimestamp {
+		return false
+	}
+	if b.MaxTimestamp != 0 && timestamp > b.MaxTimestamp {
+		return false
+	}
+	return true
+}
+
+// pooledBundle is a bundle tracked by the bundle pool, along with its sender.
+type pooledBundle struct {
+	*Bundle
+	hash   common.Hash
+	sender common.Address
+}
+
+// worse reports whether the bundle is less valuable than the other one: it
+// targets a later block, or the same one paying a lower tip with the given base
+// fee.
+func (b *pooledBundle) worse(other *Bundle, baseFee *big.Int) bool {
+	if b.BlockNumber != other.BlockNumber {
+		return b.BlockNumber > other.BlockNumber
+	}
+	return b.price(baseFee).Cmp(other.price(baseFee)) < 0
+}
+
+// bundlePool tracks the bundles submitted for inclusion into the upcoming blocks,
+// in the order of their submission. If the pool is full, the bundles targeting
+// the latest blocks and paying the lowest tips are evicted first.
+type bundlePool struct {
+	signer    types.Signer
+	maxTotal  int // Maximum number of bundles tracked
+	maxSender int // Maximum number of bundles tracked for a single sender
+
+	bundles []*pooledBundle
+	known   map[common.Hash]struct{}
+	senders map[common.Address]int
+	lock    sync.Mutex
+}
+
+// newBundlePool creates an empty bundle pool, recovering the bundle senders with
+// the given signer.
+func newBundlePool(signer types.Signer) *bundlePool {
+	return &bundlePool{
+		signer:    signer,
+		maxTotal:  maxBundles,
+		maxSender: maxBundlesPerSender,
+		known:     make(map[common.Hash]struct{}),
+		senders:   make(map[common.Address]int),
+	}
+}
+
+// add validates a bundle against the current chain head and inserts it into the
+// pool, evicting a less valuable bundle if the pool is full. The bundles are
+// valued with the base fee of the next block. Already known bundles are silently
+// ignored.
+func (p *bundlePool) add(bundle *Bundle, head uint64, baseFee *big.Int) error {
+	switch {
+	case len(bundle.Txs) == 0:
+		return ErrBundleEmpty
+	case bundle.BlockNumber <= head:
+		return ErrBundleStale
+	case bundle.BlockNumber > head+maxBundleFutureBlocks:
+		return ErrBundleTooFarFuture
+	case bundle.MaxTimestamp != 0 && bundle.MinTimestamp > bundle.MaxTimestamp:
+		return ErrBundleTimestampRange
+	}
+	sender, err := types.Sender(p.signer, bundle.Txs[0])
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.prune(head + 1)
+
+	hash := bundle.Hash()
+	if _, ok := p.known[hash]; ok {
+		return nil
+	}
+	if p.senders[sender] >= p.maxSender {
+		return ErrBundleSenderLimit
+	}
+	if len(p.bundles) >= p.maxTotal {
+		worst := 0
+		for i, pooled := range p.bundles {
+			if pooled.worse(p.bundles[worst].Bundle, baseFee) {
+				worst = i
+			}
+		}
+		if !p.bundles[worst].worse(bundle, baseFee) {
+			return ErrBundlePoolFull
+		}
+		p.remove(worst)
+	}
+	p.bundles = append(p.bundles, &pooledBundle{Bundle: bundle, hash: hash, sender: sender})
+	p.known[hash] = struct{}{}
+	p.senders[sender]++
+	return nil
+}
+
+// includable returns the bundles which can be included into the block with the
+// given number and timestamp. The bundles targeting earlier blocks are dropped.
+func (p *bundlePool) includable(number uint64, timestamp uint64) []*Bundle {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.prune(number)
+
+	var bundles []*Bundle
+	for _, pooled := range p.bundles {
+		if pooled.includable(number, timestamp) {
+			bundles = append(bundles, pooled.Bundle)
+		}
+	}
+	return bundles
+}
+
+// prune drops the bundles targeting blocks before the given number.
+//
+// Note, the lock must be held by the caller.
+func (p *bundlePool) prune(number uint64) {
+	bundles := p.bundles[:0]
+	for _, pooled := range p.bundles {
+		if pooled.BlockNumber >= number {
+			bundles = append(bundles, pooled)
+		} else {
+			p.forget(pooled)
+		}
+	}
+	for i := len(bundles); i < len(p.bundles); i++ {
+		p.bundles[i] = nil
+	}
+	p.bundles = bundles
+}
+
+// remove drops the bundle at the given index, retaining the submission order.
+//
+// Note, the lock must be held by the caller.
+func (p *bundlePool) remove(index int) {
+	p.forget(p.bundles[index])
+
+	copy(p.bundles[index:], p.bundles[index+1:])
+	p.bundles[len(p.bundles)-1] = nil
+	p.bundles = p.bundles[:len(p.bundles)-1]
+}
+
+// forget drops a bundle from the lookup sets.
+//
+// Note, the lock must be held by the caller.
+func (p *bundlePool) forget(pooled *pooledBundle) {
+	delete(p.known, pooled.hash)
+	if p.senders[pooled.sender]--; p.senders[pooled.sender] == 0 {
+		delete(p.senders, pooled.sender)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the bundle pool validates the submitted bundles and only returns
+// the ones includable into the requested block.
+func TestBundlePool(t *testing.T) {
+	pool := newBundlePool(types.LatestSigner(params.TestChainConfig))
+
+	tests := []struct {
+		bundle *Bundle
+		err    error
+	}{
+		{&Bundle{BlockNumber: 11}, ErrBundleEmpty},
+		{&Bundle{Txs: pendingTxs, BlockNumber: 10}, ErrBundleStale},
+		{&Bundle{Txs: pendingTxs, BlockNumber: 11 + maxBundleFutureBlocks}, ErrBundleTooFarFuture},
+		{&Bundle{Txs: pendingTxs, BlockNumber: 11, MinTimestamp: 2, MaxTimestamp: 1}, ErrBundleTimestampRange},
+		{&Bundle{Txs: pendingTxs, BlockNumber: 11, MinTimestamp: 100, MaxTimestamp: 200}, nil},
+		{&Bundle{Txs: newTxs, BlockNumber: 12}, nil},
+	}
+	for i, tt := range tests {
+		if err := pool.add(tt.bundle, 10, nil); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if bundles := pool.includable(11, 99); len(bundles) != 0 {
+		t.Errorf("bundle included before its minimum timestamp")
+	}
+	if bundles := pool.includable(11, 201); len(bundles) != 0 {
+		t.Errorf("bundle included after its maximum timestamp")
+	}
+	if bundles := pool.includable(11, 150); len(bundles) != 1 || bundles[0].Hash() != tests[4].bundle.Hash() {
+		t.Errorf("includable bundles mismatch: have %v, want %v", bundles, []*Bundle{tests[4].bundle})
+	}
+	// Retrieving the bundles of a later block should drop the stale ones
+	if bundles := pool.includable(12, 0); len(bundles) != 1 || bundles[0].Hash() != tests[5].bundle.Hash() {
+		t.Errorf("includable bundles mismatch: have %v, want %v", bundles, []*Bundle{tests[5].bundle})
+	}
+	if len(pool.bundles) != 1 || len(pool.known) != 1 {
+		t.Errorf("stale bundles not dropped: %d bundles, %d known", len(pool.bundles), len(pool.known))
+	}
+}
+
+// Tests that the bundle pool bounds the bundles of every sender, and evicts the
+// bundles targeting the latest blocks and paying the lowest tips when full.
+func TestBundlePoolLimits(t *testing.T) {
+	signer := types.LatestSigner(params.TestChainConfig)
+	transferCapped := func(key *ecdsa.PrivateKey, nonce uint64, tip int64, feeCap int64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   params.TestChainConfig.ChainID,
+			Nonce:     nonce,
+			To:        &testUserAddress,
+			Gas:       params.TxGas,
+			GasTipCap: big.NewInt(tip),
+			GasFeeCap: big.NewInt(feeCap),
+		})
+	}
+	transfer := func(key *ecdsa.PrivateKey, nonce uint64, tip int64) *types.Transaction {
+		return transferCapped(key, nonce, tip, params.InitialBaseFee+tip)
+	}
+	baseFee := big.NewInt(params.InitialBaseFee)
+
+	pool := newBundlePool(signer)
+	pool.maxTotal, pool.maxSender = 3, 2
+
+	// Fill the allowance of a single sender
+	for i := 0; i < 2; i++ {
+		if err := pool.add(&Bundle{Txs: types.Transactions{transfer(testBankKey, uint64(i), 10)}, BlockNumber: 12}, 10, baseFee); err != nil {
+			t.Fatalf("bundle %d: failed to add: %v", i, err)
+		}
+	}
+	if err := pool.add(&Bundle{Txs: types.Transactions{transfer(testBankKey, 2, 10)}, BlockNumber: 11}, 10, baseFee); err != ErrBundleSenderLimit {
+		t.Fatalf("sender limit error mismatch: have %v, want %v", err, ErrBundleSenderLimit)
+	}
+	// Fill the pool with another sender, and check that a less valuable bundle
+	// is rejected while a more valuable one evicts the worst
+	if err := pool.add(&Bundle{Txs: types.Transactions{transfer(testUserKey, 0, 5)}, BlockNumber: 12}, 10, baseFee); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	if err := pool.add(&Bundle{Txs: types.Transactions{transfer(testUserKey, 1, 1)}, BlockNumber: 13}, 10, baseFee); err != ErrBundlePoolFull {
+		t.Fatalf("later bundle error mismatch: have %v, want %v", err, ErrBundlePoolFull)
+	}
+	if err := pool.add(&Bundle{Txs: types.Transactions{transfer(testUserKey, 1, 5)}, BlockNumber: 12}, 10, baseFee); err != ErrBundlePoolFull {
+		t.Fatalf("equally priced bundle error mismatch: have %v, want %v", err, ErrBundlePoolFull)
+	}
+	if err := pool.add(&Bundle{Txs: types.Transactions{transferCapped(testUserKey, 1, 100, params.InitialBaseFee+5)}, BlockNumber: 12}, 10, baseFee); err != ErrBundlePoolFull {
+		t.Fatalf("fee capped bundle error mismatch: have %v, want %v", err, ErrBundlePoolFull)
+	}
+	if err := pool.add(&Bundle{Txs: types.Transactions{transfer(testUserKey, 1, 1)}, BlockNumber: 11}, 10, baseFee); err != nil {
+		t.Fatalf("failed to add earlier bundle: %v", err)
+	}
+	if len(pool.bundles) != 3 || pool.senders[testUserAddress] != 1 || pool.senders[testBankAddress] != 2 {
+		t.Fatalf("cheapest bundle not evicted: %d bundles, %v senders", len(pool.bundles), pool.senders)
+	}
+	// Check that the sender limit is released as the bundles get stale
+	if bundles := pool.includable(12, 0); len(bundles) != 2 {
+		t.Fatalf("includable bundle count mismatch: have %d, want 2", len(bundles))
+	}
+	if pool.senders[testUserAddress] != 0 || len(pool.known) != 2 {
+		t.Fatalf("stale bundles not dropped: %v senders, %d known", pool.senders, len(pool.known))
+	}
+}
+
+// Tests that the bundles are inserted atomically at the top of the blocks.
+func TestBundleInclusion(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	signer := types.LatestSigner(ethashChainConfig)
+	transfer := func(nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+		})
+	}
+	build := func(timestamp uint64) *types.Block {
+		t.Helper()
+
+		resCh, _ := w.getSealingBlock(b.chain.CurrentBlock().Hash(), timestamp, testBankAddress, common.Hash{}, false)
+		res := <-resCh
+		if res.err != nil {
+			t.Fatalf("failed to build block: %v", res.err)
+		}
+		return res.block
+	}
+	// Add a bundle failing on its last transaction and one ahead of the pool
+	// transactions of the same account
+	failing := &Bundle{Txs: types.Transactions{transfer(0), transfer(5)}, BlockNumber: 1}
+	if err := w.addBundle(failing); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	bundle := &Bundle{Txs: types.Transactions{transfer(0), transfer(1), transfer(2)}, BlockNumber: 1, MinTimestamp: 100}
+	if err := w.addBundle(bundle); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	// Before the minimum timestamp, only the pool transaction is included
+	block := build(99)
+	if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != pendingTxs[0].Hash() {
+		t.Fatalf("block transactions mismatch: have %d, want pool transaction only", len(block.Transactions()))
+	}
+	// After the minimum timestamp, the bundle replaces the pool transaction
+	block = build(100)
+	if len(block.Transactions()) != len(bundle.Txs) {
+		t.Fatalf("block transaction count mismatch: have %d, want %d", len(block.Transactions()), len(bundle.Txs))
+	}
+	for i, tx := range bundle.Txs {
+		if block.Transactions()[i].Hash() != tx.Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, block.Transactions()[i].Hash(), tx.Hash())
+		}
+	}
+}
@@ -54,6 +54,8 @@ type Config struct {
 	GasPrice   *big.Int       // Minimum gas price for mining a transaction
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
+
+	Ordering OrderingPolicy `toml:"-"` // Transaction ordering policy of the built blocks (default = PriceAndNonceOrdering)
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -202,6 +204,13 @@ func (miner *Miner) PendingBlock() *types.Block {
 	return miner.worker.pendingBlock()
 }
 
+// SendBundle adds a bundle of transactions to include together, contiguously and
+// all-or-nothing at the top of its target block, if the block's timestamp is in
+// the bundle's range.
+func (miner *Miner) SendBundle(bundle *Bundle) error {
+	return miner.worker.addBundle(bundle)
+}
+
 // PendingBlockAndReceipts returns the currently pending block and corresponding receipts.
 func (miner *Miner) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
 	return miner.worker.pendingBlockAndReceipts()
@@ -241,26 +250,26 @@ func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscript
 	return miner.worker.pendingLogsFeed.Subscribe(ch)
 }
 
-// GetSealingBlockAsync requests to generate a sealing block according to the
-// given parameters. Regardless of whether the generation is successful or not,
-// there is always a result that will be returned through the result channel.
-// The difference is that if the execution fails, the returned result is nil
-// and the concrete error is dropped silently.
-func (miner *Miner) GetSealingBlockAsync(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (chan *types.Block, error) {
-	resCh, _, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, noTxs)
-	if err != nil {
-		return nil, err
-	}
-	return resCh, nil
+// SubscribePendingBlock starts delivering each new version of the pending block
+// along with its receipts to the given channel.
+func (miner *Miner) SubscribePendingBlock(ch chan<- core.PendingBlockEvent) event.Subscription {
+	return miner.worker.pendingBlockFeed.Subscribe(ch)
 }
 
 // GetSealingBlockSync creates a sealing block according to the given parameters.
 // If the generation is failed or the underlying work is already closed, an error
 // will be returned.
 func (miner *Miner) GetSealingBlockSync(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (*types.Block, error) {
-	resCh, errCh, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, noTxs)
+	resCh, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, noTxs)
 	if err != nil {
 		return nil, err
 	}
-	return <-resCh, <-errCh
+	res := <-resCh
+	return res.block, res.err
+}
+
+// BuildPayload builds the payload according to the provided parameters. The
+// payload is refined in the background until it's resolved.
+func (miner *Miner) BuildPayload(args *BuildPayloadArgs) (*Payload, error) {
+	return miner.worker.buildPayload(args)
 }
@@ -0,0 +1,155 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// OrderingPolicy decides in which order the pending transactions are included
+// into the blocks built by the miner.
+type OrderingPolicy interface {
+	// Order creates the ordering of the given pending transactions for the block
+	// being built with the given header. The transactions of each account are
+	// sorted by nonce. Locals are the accounts whose transactions are expected
+	// to be favoured, they are nil if the transactions are all remote.
+	Order(header *types.Header, signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) TransactionOrdering
+}
+
+// TransactionOrdering yields the transactions to include into a single block.
+// It's used by a single goroutine, it doesn't need to be thread safe.
+type TransactionOrdering interface {
+	// Peek returns the next transaction to include, or nil if there are none
+	// left.
+	Peek() *types.Transaction
+
+	// Shift replaces the peeked transaction with the next one from the same
+	// account. It's called after the peeked transaction was included, or it
+	// failed for a reason specific to the transaction.
+	Shift()
+
+	// Pop removes the peeked transaction along with all the remaining ones from
+	// the same account. It's called if the account can't include anything else
+	// into the block.
+	Pop()
+
+	// Veto is consulted before the peeked transaction is executed, with the
+	// transactions already included in the block and their receipts. If true
+	// is returned, the account's remaining transactions are skipped.
+	Veto(from common.Address, tx *types.Transaction, included types.Transactions, receipts types.Receipts) bool
+}
+
+// PriceAndNonceOrdering is the default ordering policy of the miner. It includes
+// the transactions of the local accounts first, then the remote ones, both by
+// effective tip and nonce.
+type PriceAndNonceOrdering struct{}
+
+// Order implements OrderingPolicy, ordering the pending transactions by their
+// locality, effective tip and nonce.
+func (PriceAndNonceOrdering) Order(header *types.Header, signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) TransactionOrdering {
+	localTxs, remoteTxs := make(map[common.Address]types.Transactions), make(map[common.Address]types.Transactions)
+	for account, txs := range pending {
+		remoteTxs[account] = txs
+	}
+	for _, account := range locals {
+		if txs := remoteTxs[account]; len(txs) > 0 {
+			delete(remoteTxs, account)
+			localTxs[account] = txs
+		}
+	}
+	return NewLaneOrdering(
+		NewPriceAndNonceOrdering(signer, localTxs, header.BaseFee),
+		NewPriceAndNonceOrdering(signer, remoteTxs, header.BaseFee),
+	)
+}
+
+// priceAndNonceOrdering is a TransactionOrdering yielding transactions by their
+// effective tip and nonce, without vetoing any of them.
+type priceAndNonceOrdering struct {
+	*types.TransactionsByPriceAndNonce
+}
+
+// NewPriceAndNonceOrdering creates a transaction ordering yielding the given
+// transactions by their effective tip and nonce.
+func NewPriceAndNonceOrdering(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TransactionOrdering {
+	return &priceAndNonceOrdering{types.NewTransactionsByPriceAndNonce(signer, txs, baseFee)}
+}
+
+// Veto implements TransactionOrdering, accepting every transaction.
+func (o *priceAndNonceOrdering) Veto(from common.Address, tx *types.Transaction, included types.Transactions, receipts types.Receipts) bool {
+	return false
+}
+
+// laneOrdering is a TransactionOrdering yielding all the transactions of its
+// lanes in the order of the lanes.
+type laneOrdering struct {
+	lanes []TransactionOrdering
+}
+
+// NewLaneOrdering creates a transaction ordering which yields the transactions
+// of the given orderings one after the other, i.e. all the transactions of a
+// lane are included before any from the lanes following it.
+func NewLaneOrdering(lanes ...TransactionOrdering) TransactionOrdering {
+	return &laneOrdering{lanes: lanes}
+}
+
+// current returns the first lane still having transactions, or nil if all the
+// lanes are drained.
+func (o *laneOrdering) current() TransactionOrdering {
+	for len(o.lanes) > 0 {
+		if o.lanes[0].Peek() != nil {
+			return o.lanes[0]
+		}
+		o.lanes = o.lanes[1:]
+	}
+	return nil
+}
+
+// Peek implements TransactionOrdering, returning the next transaction of the
+// first non-empty lane.
+func (o *laneOrdering) Peek() *types.Transaction {
+	if lane := o.current(); lane != nil {
+		return lane.Peek()
+	}
+	return nil
+}
+
+// Shift implements TransactionOrdering, shifting the first non-empty lane.
+func (o *laneOrdering) Shift() {
+	if lane := o.current(); lane != nil {
+		lane.Shift()
+	}
+}
+
+// Pop implements TransactionOrdering, popping from the first non-empty lane.
+func (o *laneOrdering) Pop() {
+	if lane := o.current(); lane != nil {
+		lane.Pop()
+	}
+}
+
+// Veto implements TransactionOrdering, consulting the lane of the peeked
+// transaction.
+func (o *laneOrdering) Veto(from common.Address, tx *types.Transaction, included types.Transactions, receipts types.Receipts) bool {
+	if lane := o.current(); lane != nil {
+		return lane.Veto(from, tx, included, receipts)
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the default ordering yields the local transactions first, and the
+// lanes are drained one after the other.
+func TestPriceAndNonceOrdering(t *testing.T) {
+	var (
+		signer  = types.LatestSigner(params.TestChainConfig)
+		keys    = make([]*ecdsa.PrivateKey, 3)
+		addrs   = make([]common.Address, 3)
+		pending = make(map[common.Address]types.Transactions)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		for nonce := uint64(0); nonce < 2; nonce++ {
+			// The later accounts pay more, so they'd be first without lanes
+			tx := types.MustSignNewTx(keys[i], signer, &types.LegacyTx{
+				Nonce:    nonce,
+				To:       &common.Address{},
+				Gas:      params.TxGas,
+				GasPrice: big.NewInt(int64(i+1) * params.InitialBaseFee),
+			})
+			pending[addrs[i]] = append(pending[addrs[i]], tx)
+		}
+	}
+	header := &types.Header{BaseFee: big.NewInt(params.InitialBaseFee)}
+	txs := PriceAndNonceOrdering{}.Order(header, signer, pending, []common.Address{addrs[0]})
+
+	var order []common.Address
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		from, _ := types.Sender(signer, tx)
+		if txs.Veto(from, tx, nil, nil) {
+			t.Fatalf("default ordering vetoed transaction %x", tx.Hash())
+		}
+		order = append(order, from)
+		txs.Shift()
+	}
+	want := []common.Address{addrs[0], addrs[0], addrs[2], addrs[2], addrs[1], addrs[1]}
+	if len(order) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(order), len(want))
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("transaction %d: sender mismatch: have %x, want %x", i, order[i], want[i])
+		}
+	}
+}
+
+// quotaOrdering is an ordering policy allowing a single transaction for each
+// account in a block.
+type quotaOrdering struct{}
+
+func (quotaOrdering) Order(header *types.Header, signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) TransactionOrdering {
+	return &quotaTransactions{
+		TransactionOrdering: PriceAndNonceOrdering{}.Order(header, signer, pending, locals),
+		signer:              signer,
+	}
+}
+
+type quotaTransactions struct {
+	TransactionOrdering
+	signer types.Signer
+}
+
+func (o *quotaTransactions) Veto(from common.Address, tx *types.Transaction, included types.Transactions, receipts types.Receipts) bool {
+	for _, tx := range included {
+		if sender, _ := types.Sender(o.signer, tx); sender == from {
+			return true
+		}
+	}
+	return false
+}
+
+// Tests that the worker consults the configured ordering policy when filling
+// the blocks.
+func TestOrderingPolicyVeto(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	backend := newTestWorkerBackend(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	backend.txPool.AddLocals(pendingTxs)
+	backend.txPool.AddLocals(newTxs)
+
+	config := *testConfig
+	config.Ordering = quotaOrdering{}
+	w := newWorker(&config, ethashChainConfig, engine, backend, new(event.TypeMux), nil, false)
+	w.setEtherbase(testBankAddress)
+	defer w.close()
+
+	resCh, _ := w.getSealingBlock(backend.chain.CurrentBlock().Hash(), uint64(time.Now().Unix()), testBankAddress, common.Hash{}, false)
+	res := <-resCh
+	if res.err != nil {
+		t.Fatalf("failed to build block: %v", res.err)
+	}
+	block := res.block
+	if len(block.Transactions()) != 1 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(block.Transactions()), 1)
+	}
+	if block.Transactions()[0].Hash() != pendingTxs[0].Hash() {
+		t.Fatalf("transaction mismatch: have %x, want %x", block.Transactions()[0].Hash(), pendingTxs[0].Hash())
+	}
+}
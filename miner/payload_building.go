@@ -0,0 +1,178 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// payloadBuildTimeout is the maximum time a payload is refined for, about
+	// the length of a slot. The best version is kept afterwards.
+	payloadBuildTimeout = 12 * time.Second
+
+	// payloadResolveTimeout is the maximum time the resolution of a payload waits
+	// for the first full version before falling back to the empty one.
+	payloadResolveTimeout = 500 * time.Millisecond
+)
+
+// BuildPayloadArgs contains the provided parameters for building payload.
+type BuildPayloadArgs struct {
+	Parent       common.Hash    // The parent block to build payload on top
+	Timestamp    uint64         // The provided timestamp of generated payload
+	FeeRecipient common.Address // The provided recipient address for collecting transaction fee
+	Random       common.Hash    // The provided randomness value
+}
+
+// Payload wraps the built payload (block waiting for sealing). The empty version
+// of the payload is always available, while the full version is built in the
+// background and replaced whenever a more valuable one is built, until the
+// payload is resolved.
+type Payload struct {
+	empty    *types.Block
+	full     *types.Block
+	fullFees *big.Int
+	ready    chan struct{} // Closed when the first full version is available
+	stop     chan struct{} // Closed when the payload is resolved
+	lock     sync.Mutex
+}
+
+// newPayload creates a payload with the given empty block as the fallback.
+func newPayload(empty *types.Block) *Payload {
+	return &Payload{
+		empty: empty,
+		ready: make(chan struct{}),
+		stop:  make(chan struct{}),
+	}
+}
+
+// update replaces the full version of the payload if the given block pays more
+// fees to the fee recipient.
+func (payload *Payload) update(block *types.Block, fees *big.Int, elapsed time.Duration) {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	select {
+	case <-payload.stop:
+		return // reject stale update
+	default:
+	}
+	if payload.full == nil {
+		close(payload.ready)
+	} else if fees.Cmp(payload.fullFees) <= 0 {
+		return
+	}
+	payload.full, payload.fullFees = block, fees
+	log.Info("Updated payload", "number", block.NumberU64(), "hash", block.Hash(), "txs", len(block.Transactions()),
+		"gas", block.GasUsed(), "fees", new(big.Float).Quo(new(big.Float).SetInt(fees), big.NewFloat(1e18)),
+		"elapsed", common.PrettyDuration(elapsed))
+}
+
+// Value returns the priority fees paid to the fee recipient by the current best
+// version of the payload, without ending its refinement.
+func (payload *Payload) Value() *big.Int {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	if payload.full == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(payload.fullFees)
+}
+
+// Resolve ends the refinement of the payload and returns its best version along
+// with the priority fees paid to the fee recipient. If no full version is built
+// yet, it waits a bit for the first one before falling back to the empty block.
+func (payload *Payload) Resolve() (*types.Block, *big.Int) {
+	timeout := time.NewTimer(payloadResolveTimeout)
+	defer timeout.Stop()
+
+	select {
+	case <-payload.ready:
+	case <-timeout.C:
+	}
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	select {
+	case <-payload.stop:
+	default:
+		close(payload.stop)
+	}
+	if payload.full != nil {
+		return payload.full, new(big.Int).Set(payload.fullFees)
+	}
+	return payload.empty, new(big.Int)
+}
+
+// buildPayload builds the empty version of the payload synchronously and keeps
+// building more valuable full versions in the background every recommit
+// interval, until the payload is resolved or the build timeout is reached.
+func (w *worker) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
+	// Build the initial version with no transaction included. It should be fast
+	// enough to run. The empty payload can at least make sure there is something
+	// to deliver for not missing slot.
+	resCh, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, true)
+	if err != nil {
+		return nil, err
+	}
+	empty := <-resCh
+	if empty.err != nil {
+		return nil, empty.err
+	}
+	payload := newPayload(empty.block)
+
+	recommit := w.config.Recommit
+	if recommit < minRecommitInterval {
+		recommit = minRecommitInterval
+	}
+	go func() {
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		end := time.NewTimer(payloadBuildTimeout)
+		defer end.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				start := time.Now()
+				resCh, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, false)
+				if err != nil {
+					return
+				}
+				if res := <-resCh; res.err == nil {
+					payload.update(res.block, res.fees, time.Since(start))
+				} else {
+					log.Debug("Failed to build payload", "err", res.err)
+				}
+				timer.Reset(recommit)
+			case <-payload.stop:
+				return
+			case <-end.C:
+				return
+			}
+		}
+	}()
+	return payload, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestBuildPayload(t *testing.T) {
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		recipient = common.HexToAddress("0xdeadbeef")
+	)
+	w, b := newTestWorker(t, ethashChainConfig, ethash.NewFaker(), db, 0)
+	defer w.close()
+
+	args := &BuildPayloadArgs{
+		Parent:       b.chain.CurrentBlock().Hash(),
+		Timestamp:    uint64(time.Now().Unix()),
+		FeeRecipient: recipient,
+		Random:       common.Hash{0x01},
+	}
+	payload, err := w.buildPayload(args)
+	if err != nil {
+		t.Fatalf("Failed to build payload %v", err)
+	}
+	if len(payload.empty.Transactions()) != 0 {
+		t.Fatalf("Unexpected transactions in empty payload: %d", len(payload.empty.Transactions()))
+	}
+	// Add a transaction paying a priority fee and wait for the payload to be
+	// refined with it.
+	tip := big.NewInt(params.GWei)
+	tx := types.MustSignNewTx(testBankKey, types.LatestSigner(ethashChainConfig), &types.LegacyTx{
+		Nonce:    1,
+		To:       &testUserAddress,
+		Value:    big.NewInt(1000),
+		Gas:      params.TxGas,
+		GasPrice: new(big.Int).Add(big.NewInt(params.InitialBaseFee), tip),
+	})
+	if err := b.txPool.AddLocal(tx); err != nil {
+		t.Fatalf("Failed to add transaction %v", err)
+	}
+	for start := time.Now(); payload.Value().Sign() == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("Payload not refined")
+		}
+	}
+	block, value := payload.Resolve()
+	if len(block.Transactions()) != 2 {
+		t.Fatalf("Unexpected transaction count: have %d, want 2", len(block.Transactions()))
+	}
+	if block.Coinbase() != recipient {
+		t.Fatalf("Unexpected fee recipient: have %x, want %x", block.Coinbase(), recipient)
+	}
+	var want = new(big.Int)
+	for _, tx := range block.Transactions() {
+		tip, _ := tx.EffectiveGasTip(block.BaseFee())
+		want.Add(want, new(big.Int).Mul(tip, new(big.Int).SetUint64(params.TxGas)))
+	}
+	if want.Sign() == 0 || value.Cmp(want) != 0 {
+		t.Fatalf("Unexpected payload value: have %v, want %v", value, want)
+	}
+	// Resolved payloads are not refined any further
+	if another, _ := payload.Resolve(); another != block {
+		t.Fatal("Resolved payload changed")
+	}
+}
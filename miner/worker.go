@@ -167,11 +167,17 @@ type newWorkReq struct {
 	timestamp int64
 }
 
+// newPayloadResult represents a result struct corresponds to payload generation.
+type newPayloadResult struct {
+	err   error
+	block *types.Block
+	fees  *big.Int // Total priority fees paid to the fee recipient
+}
+
 // getWorkReq represents a request for getting a new sealing work with provided parameters.
 type getWorkReq struct {
 	params *generateParams
-	result chan *types.Block // non-blocking channel
-	err    chan error
+	result chan *newPayloadResult // non-blocking channel
 }
 
 // intervalAdjust represents a resubmitting interval adjustment.
@@ -190,7 +196,8 @@ type worker struct {
 	chain       *core.BlockChain
 
 	// Feeds
-	pendingLogsFeed event.Feed
+	pendingLogsFeed  event.Feed
+	pendingBlockFeed event.Feed
 
 	// Subscriptions
 	mux          *event.TypeMux
@@ -205,6 +212,7 @@ type worker struct {
 	newWorkCh          chan *newWorkReq
 	getWorkCh          chan *getWorkReq
 	taskCh             chan *task
+	pendingBlockCh     chan core.PendingBlockEvent // Latest pending block not yet delivered to the subscribers
 	resultCh           chan *types.Block
 	startCh            chan struct{}
 	exitCh             chan struct{}
@@ -217,6 +225,7 @@ type worker struct {
 	localUncles  map[common.Hash]*types.Block // A set of side blocks generated locally as the possible uncle blocks.
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
+	bundles      *bundlePool                  // A set of transaction bundles to include atomically at the top of the blocks.
 
 	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
 	coinbase common.Address
@@ -263,6 +272,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		localUncles:        make(map[common.Hash]*types.Block),
 		remoteUncles:       make(map[common.Hash]*types.Block),
 		unconfirmed:        newUnconfirmedBlocks(eth.BlockChain(), sealingLogAtDepth),
+		bundles:            newBundlePool(types.LatestSigner(chainConfig)),
 		pendingTasks:       make(map[common.Hash]*task),
 		txsCh:              make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:        make(chan core.ChainHeadEvent, chainHeadChanSize),
@@ -270,6 +280,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		newWorkCh:          make(chan *newWorkReq),
 		getWorkCh:          make(chan *getWorkReq),
 		taskCh:             make(chan *task),
+		pendingBlockCh:     make(chan core.PendingBlockEvent, 1),
 		resultCh:           make(chan *types.Block, resultQueueSize),
 		exitCh:             make(chan struct{}),
 		startCh:            make(chan struct{}, 1),
@@ -289,11 +300,12 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		recommit = minRecommitInterval
 	}
 
-	worker.wg.Add(4)
+	worker.wg.Add(5)
 	go worker.mainLoop()
 	go worker.newWorkLoop(recommit)
 	go worker.resultLoop()
 	go worker.taskLoop()
+	go worker.pendingBlockLoop()
 
 	// Submit first work to initialize pending state.
 	if init {
@@ -534,13 +546,11 @@ func (w *worker) mainLoop() {
 			w.commitWork(req.interrupt, req.noempty, req.timestamp)
 
 		case req := <-w.getWorkCh:
-			block, err := w.generateWork(req.params)
-			if err != nil {
-				req.err <- err
-				req.result <- nil
-			} else {
-				req.err <- nil
-				req.result <- block
+			block, fees, err := w.generateWork(req.params)
+			req.result <- &newPayloadResult{
+				err:   err,
+				block: block,
+				fees:  fees,
 			}
 		case ev := <-w.chainSideCh:
 			// Short circuit for duplicate side blocks
@@ -595,7 +605,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
+				txset := w.orderingPolicy().Order(w.current.header, w.current.signer, txs, nil)
 				tcount := w.current.tcount
 				w.commitTransactions(w.current, txset, nil)
 
@@ -816,11 +826,10 @@ func (w *worker) commitUncle(env *environment, uncle *types.Header) error {
 	return nil
 }
 
-// updateSnapshot updates pending snapshot block, receipts and state.
+// updateSnapshot updates pending snapshot block, receipts and state, and notifies
+// the subscribers of the new pending block.
 func (w *worker) updateSnapshot(env *environment) {
 	w.snapshotMu.Lock()
-	defer w.snapshotMu.Unlock()
-
 	w.snapshotBlock = types.NewBlock(
 		env.header,
 		env.txs,
@@ -830,6 +839,37 @@ func (w *worker) updateSnapshot(env *environment) {
 	)
 	w.snapshotReceipts = copyReceipts(env.receipts)
 	w.snapshotState = env.state.Copy()
+	block, receipts := w.snapshotBlock, w.snapshotReceipts
+	w.snapshotMu.Unlock()
+
+	// Hand the new version over to the notifier loop without blocking on slow
+	// subscribers, replacing any older version not delivered yet.
+	ev := core.PendingBlockEvent{Block: block, Receipts: receipts}
+	for {
+		select {
+		case w.pendingBlockCh <- ev:
+			return
+		default:
+		}
+		select {
+		case <-w.pendingBlockCh:
+		default:
+		}
+	}
+}
+
+// pendingBlockLoop is a standalone goroutine to deliver the new versions of the
+// pending block to the subscribers.
+func (w *worker) pendingBlockLoop() {
+	defer w.wg.Done()
+	for {
+		select {
+		case ev := <-w.pendingBlockCh:
+			w.pendingBlockFeed.Send(ev)
+		case <-w.exitCh:
+			return
+		}
+	}
 }
 
 func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*types.Log, error) {
@@ -846,7 +886,7 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *int32) error {
+func (w *worker) commitTransactions(env *environment, txs TransactionOrdering, interrupt *int32) error {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(gasLimit)
@@ -898,6 +938,14 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			txs.Pop()
 			continue
 		}
+		// Let the ordering policy veto the transaction, e.g. if the sender used
+		// up its quota in the block.
+		if txs.Veto(from, tx, env.txs, env.receipts) {
+			log.Trace("Skipping account vetoed by the ordering policy", "sender", from, "hash", tx.Hash())
+
+			txs.Pop()
+			continue
+		}
 		// Start executing the transaction
 		env.state.Prepare(tx.Hash(), env.tcount)
 
@@ -1055,46 +1103,121 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 }
 
 // fillTransactions retrieves the pending transactions from the txpool and fills them
-// into the given sealing block. The transaction selection and ordering strategy can
-// be customized with the plugin in the future.
+// into the given sealing block. The transaction selection and ordering strategy is
+// customized through the configured OrderingPolicy.
 func (w *worker) fillTransactions(interrupt *int32, env *environment) error {
-	// Split the pending transactions into locals and remotes
-	// Fill the block with all available pending transactions.
+	// Insert the includable bundles at the top of the block, then fill it with
+	// all available pending transactions, in the order of the configured policy.
+	w.commitBundles(env, interrupt)
+
 	pending := w.eth.TxPool().Pending(true)
-	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
-	for _, account := range w.eth.TxPool().Locals() {
-		if txs := remoteTxs[account]; len(txs) > 0 {
-			delete(remoteTxs, account)
-			localTxs[account] = txs
-		}
+	if len(pending) == 0 {
+		return nil
 	}
-	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, localTxs, env.header.BaseFee)
-		if err := w.commitTransactions(env, txs, interrupt); err != nil {
-			return err
+	txs := w.orderingPolicy().Order(env.header, env.signer, pending, w.eth.TxPool().Locals())
+	return w.commitTransactions(env, txs, interrupt)
+}
+
+// commitBundles inserts the bundles includable into the given sealing block,
+// skipping the ones with any transaction failing.
+func (w *worker) commitBundles(env *environment, interrupt *int32) {
+	bundles := w.bundles.includable(env.header.Number.Uint64(), env.header.Time)
+	if len(bundles) == 0 {
+		return
+	}
+	// Failed bundles are reverted through the state journal, which can't span
+	// the intermediate roots of the receipts before Byzantium.
+	if !w.chainConfig.IsByzantium(env.header.Number) {
+		log.Debug("Bundles skipped before Byzantium", "count", len(bundles))
+		return
+	}
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	for _, bundle := range bundles {
+		if interrupt != nil && atomic.LoadInt32(interrupt) != commitInterruptNone {
+			return
+		}
+		if err := w.commitBundle(env, bundle); err != nil {
+			log.Debug("Bundle skipped", "hash", bundle.Hash(), "err", err)
 		}
 	}
-	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, remoteTxs, env.header.BaseFee)
-		if err := w.commitTransactions(env, txs, interrupt); err != nil {
-			return err
+}
+
+// commitBundle applies all the transactions of a bundle onto the sealing block,
+// or reverts the block to its original state if any of them fails.
+func (w *worker) commitBundle(env *environment, bundle *Bundle) error {
+	// Retain the state journal across the bundle transactions, so that all of
+	// them can be reverted at once.
+	env.state.RetainJournal(true)
+	defer env.state.RetainJournal(false)
+
+	var (
+		snap     = env.state.Snapshot()
+		gasPool  = *env.gasPool
+		gasUsed  = env.header.GasUsed
+		tcount   = env.tcount
+		txs      = len(env.txs)
+		receipts = len(env.receipts)
+	)
+	for i, tx := range bundle.Txs {
+		env.state.Prepare(tx.Hash(), env.tcount)
+
+		_, err := w.commitTransaction(env, tx)
+		if err == nil && env.receipts[len(env.receipts)-1].Status != types.ReceiptStatusSuccessful {
+			err = errBundleTxReverted
+		}
+		if err != nil {
+			env.state.RevertToSnapshot(snap)
+			*env.gasPool = gasPool
+			env.header.GasUsed = gasUsed
+			env.tcount = tcount
+			env.txs, env.receipts = env.txs[:txs], env.receipts[:receipts]
+			return fmt.Errorf("transaction %d (%x): %w", i, tx.Hash(), err)
 		}
+		env.tcount++
 	}
 	return nil
 }
 
-// generateWork generates a sealing block based on the given parameters.
-func (w *worker) generateWork(params *generateParams) (*types.Block, error) {
+// addBundle validates a bundle and adds it to the bundle pool for inclusion
+// into its target block.
+func (w *worker) addBundle(bundle *Bundle) error {
+	head := w.chain.CurrentBlock()
+
+	var baseFee *big.Int
+	if number := new(big.Int).Add(head.Number(), common.Big1); w.chainConfig.IsLondon(number) {
+		baseFee = misc.CalcBaseFee(w.chainConfig, head.Header())
+	}
+	return w.bundles.add(bundle, head.NumberU64(), baseFee)
+}
+
+// orderingPolicy returns the configured transaction ordering policy, or the
+// default price and nonce based one.
+func (w *worker) orderingPolicy() OrderingPolicy {
+	if w.config.Ordering != nil {
+		return w.config.Ordering
+	}
+	return PriceAndNonceOrdering{}
+}
+
+// generateWork generates a sealing block based on the given parameters, along
+// with the priority fees it pays to the fee recipient.
+func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, error) {
 	work, err := w.prepareWork(params)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer work.discard()
 
 	if !params.noTxs {
 		w.fillTransactions(nil, work)
 	}
-	return w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts)
+	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, blockFees(block, work.receipts), nil
 }
 
 // commitWork generates several new sealing tasks based on the parent block
@@ -1180,11 +1303,8 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 // getSealingBlock generates the sealing block based on the given parameters.
 // The generation result will be passed back via the given channel no matter
 // the generation itself succeeds or not.
-func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (chan *types.Block, chan error, error) {
-	var (
-		resCh = make(chan *types.Block, 1)
-		errCh = make(chan error, 1)
-	)
+func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (chan *newPayloadResult, error) {
+	resCh := make(chan *newPayloadResult, 1)
 	req := &getWorkReq{
 		params: &generateParams{
 			timestamp:  timestamp,
@@ -1197,13 +1317,12 @@ func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase
 			noTxs:      noTxs,
 		},
 		result: resCh,
-		err:    errCh,
 	}
 	select {
 	case w.getWorkCh <- req:
-		return resCh, errCh, nil
+		return resCh, nil
 	case <-w.exitCh:
-		return nil, nil, errors.New("miner closed")
+		return nil, errors.New("miner closed")
 	}
 }
 
@@ -1232,12 +1351,17 @@ func (w *worker) postSideBlock(event core.ChainSideEvent) {
 	}
 }
 
-// totalFees computes total consumed miner fees in ETH. Block transactions and receipts have to have the same order.
-func totalFees(block *types.Block, receipts []*types.Receipt) *big.Float {
+// blockFees computes total consumed miner fees in wei. Block transactions and receipts have to have the same order.
+func blockFees(block *types.Block, receipts []*types.Receipt) *big.Int {
 	feesWei := new(big.Int)
 	for i, tx := range block.Transactions() {
 		minerFee, _ := tx.EffectiveGasTip(block.BaseFee())
 		feesWei.Add(feesWei, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), minerFee))
 	}
-	return new(big.Float).Quo(new(big.Float).SetInt(feesWei), new(big.Float).SetInt(big.NewInt(params.Ether)))
+	return feesWei
+}
+
+// totalFees computes total consumed miner fees in ETH. Block transactions and receipts have to have the same order.
+func totalFees(block *types.Block, receipts []*types.Receipt) *big.Float {
+	return new(big.Float).Quo(new(big.Float).SetInt(blockFees(block, receipts)), new(big.Float).SetInt(big.NewInt(params.Ether)))
 }
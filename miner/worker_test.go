@@ -322,6 +322,69 @@ func testEmptyWork(t *testing.T, chainConfig *params.ChainConfig, engine consens
 	}
 }
 
+// Tests that each new version of the pending block is delivered to the pending
+// block subscribers along with its receipts.
+func TestPendingBlockFeed(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	blocks := make(chan core.PendingBlockEvent, 4)
+	sub := w.pendingBlockFeed.Subscribe(blocks)
+	defer sub.Unsubscribe()
+
+	w.skipSealHook = func(task *task) bool { return true }
+	w.start() // Start mining!
+
+	timeout := time.NewTimer(3 * time.Second)
+	defer timeout.Stop()
+	for {
+		select {
+		case ev := <-blocks:
+			if len(ev.Block.Transactions()) != len(ev.Receipts) {
+				t.Fatalf("receipt count mismatch: have %d, want %d", len(ev.Receipts), len(ev.Block.Transactions()))
+			}
+			if len(ev.Receipts) > 0 {
+				if ev.Receipts[0].TxHash != ev.Block.Transactions()[0].Hash() {
+					t.Fatalf("receipt transaction mismatch: have %x, want %x", ev.Receipts[0].TxHash, ev.Block.Transactions()[0].Hash())
+				}
+				return
+			}
+		case <-timeout.C:
+			t.Fatal("pending block with transactions not delivered")
+		}
+	}
+}
+
+// Tests that a pending block subscriber not consuming the events doesn't stall
+// the worker.
+func TestPendingBlockFeedSlowSubscriber(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	sub := w.pendingBlockFeed.Subscribe(make(chan core.PendingBlockEvent))
+	defer sub.Unsubscribe()
+
+	var tasks int32
+	w.newTaskHook = func(task *task) { atomic.AddInt32(&tasks, 1) }
+	w.skipSealHook = func(task *task) bool { return true }
+	w.start() // Start mining!
+
+	// Both the empty and the full sealing tasks update the pending block
+	deadline := time.Now().Add(3 * time.Second)
+	for atomic.LoadInt32(&tasks) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("worker stalled by the pending block subscriber: %d tasks", atomic.LoadInt32(&tasks))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func TestStreamUncleBlock(t *testing.T) {
 	ethash := ethash.NewFaker()
 	defer ethash.Close()
@@ -638,9 +701,9 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 
 	// This API should work even when the automatic sealing is not enabled
 	for _, c := range cases {
-		resChan, errChan, _ := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, false)
-		block := <-resChan
-		err := <-errChan
+		resChan, _ := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, false)
+		res := <-resChan
+		block, err := res.block, res.err
 		if c.expectErr {
 			if err == nil {
 				t.Error("Expect error but get nil")
@@ -656,9 +719,9 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 	// This API should work even when the automatic sealing is enabled
 	w.start()
 	for _, c := range cases {
-		resChan, errChan, _ := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, false)
-		block := <-resChan
-		err := <-errChan
+		resChan, _ := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, false)
+		res := <-resChan
+		block, err := res.block, res.err
 		if c.expectErr {
 			if err == nil {
 				t.Error("Expect error but get nil")
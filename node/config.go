@@ -24,6 +24,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -191,6 +192,27 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLMaxCost is the maximum estimated cost of a GraphQL query. Queries
+	// above it are rejected before being executed. Zero means no limit.
+	GraphQLMaxCost uint64 `toml:",omitempty"`
+
+	// GraphQLFieldCosts overrides the cost of resolving individual GraphQL fields,
+	// keyed by "Type.field" (e.g. "Block.transactions"). Fields cost one by default.
+	GraphQLFieldCosts map[string]uint64 `toml:",omitempty"`
+
+	// GraphQLListSizes overrides the assumed number of items returned by GraphQL
+	// list fields, keyed by "Type.field". For the block ranges of "Query.blocks"
+	// and "Query.logs" it is the number of items per block in the range.
+	GraphQLListSizes map[string]uint64 `toml:",omitempty"`
+
+	// GraphQLTimeout is the maximum execution time of a GraphQL query or mutation.
+	// Zero means no limit.
+	GraphQLTimeout time.Duration `toml:",omitempty"`
+
+	// GraphQLTracing exposes the trace field of transactions, re-executing them
+	// with the struct logger or any custom tracer on request.
+	GraphQLTracing bool `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -203,6 +225,26 @@ type Config struct {
 
 	// JWTSecret is the hex-encoded jwt secret.
 	JWTSecret string `toml:",omitempty"`
+
+	// BatchRequestLimit is the maximum number of requests in a batch. Requests
+	// beyond the limit are answered with an error. Zero means no limit.
+	BatchRequestLimit int `toml:",omitempty"`
+
+	// BatchResponseMaxSize is the maximum number of bytes returned from a batched
+	// call. Once exceeded, the remaining calls of the batch are answered with an
+	// error. Zero means no limit.
+	BatchResponseMaxSize int `toml:",omitempty"`
+
+	// DBEngine is the database engine to use for newly created databases, either
+	// "leveldb" or "pebble". An empty value keeps the engine of pre-existing
+	// databases and defaults to leveldb for new ones. Opening an existing
+	// database with a different engine fails.
+	DBEngine string `toml:",omitempty"`
+
+	// RPCAccessControl configures method level access rules and per-client rate
+	// limits, enforced on the HTTP and WebSocket endpoints, the authenticated
+	// engine API ones included. It does not apply to IPC.
+	RPCAccessControl rpc.AccessConfig `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -22,14 +22,18 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -585,6 +589,47 @@ func (test rpcPrefixTest) check(t *testing.T, node *Node) {
 	}
 }
 
+// Tests that the access rules and rate limits of the RPC endpoints apply to the
+// authenticated endpoint as well.
+func TestAuthEndpointAccessControl(t *testing.T) {
+	secret := [32]byte{1}
+	secretFile := filepath.Join(t.TempDir(), "jwtsecret")
+	if err := os.WriteFile(secretFile, []byte(fmt.Sprintf("%#x", secret)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	node, err := New(&Config{
+		AuthAddr:         "127.0.0.1",
+		AuthPort:         0,
+		JWTSecret:        secretFile,
+		RPCAccessControl: rpc.AccessConfig{RateLimit: 0.001, RateBurst: 1},
+	})
+	if err != nil {
+		t.Fatalf("could not create a new node: %v", err)
+	}
+	defer node.Close()
+
+	node.RegisterAPIs([]rpc.API{{Namespace: "engine", Service: new(FullService), Authenticated: true}})
+	if err := node.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iat": time.Now().Unix()}).SignedString(secret[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + node.httpAuth.listenAddr()
+	for i, want := range []string{"", "rate limit exceeded"} {
+		resp := rpcRequest(t, url, "Authorization", "Bearer "+token)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("request %d: could not read response: %v", i, err)
+		}
+		if have := strings.Contains(string(body), "rate limit exceeded"); have != (want != "") {
+			t.Errorf("request %d: rate limited %v, want %v: %s", i, have, want != "", body)
+		}
+	}
+}
+
 func createNode(t *testing.T, httpPort, wsPort int) *Node {
 	conf := &Config{
 		HTTPHost: "127.0.0.1",
@@ -40,6 +40,7 @@ type httpConfig struct {
 	Vhosts             []string
 	prefix             string // path prefix on which to mount http handler
 	jwtSecret          []byte // optional JWT secret
+	rpcEndpointConfig
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
@@ -48,11 +49,20 @@ type wsConfig struct {
 	Modules   []string
 	prefix    string // path prefix on which to mount ws handler
 	jwtSecret []byte // optional JWT secret
+	rpcEndpointConfig
+}
+
+// rpcEndpointConfig holds the settings shared by all JSON-RPC transports.
+type rpcEndpointConfig struct {
+	batchItemLimit         int
+	batchResponseSizeLimit int
+	accessControl          *rpc.AccessControl // not applied to IPC
 }
 
 type rpcHandler struct {
 	http.Handler
 	server *rpc.Server
+	mux    http.Handler // handlers registered via Node.RegisterWSHandler, behind the same stack
 }
 
 type httpServer struct {
@@ -71,18 +81,20 @@ type httpServer struct {
 
 	// WebSocket handler things.
 	wsConfig  wsConfig
-	wsHandler atomic.Value // *rpcHandler
+	wsHandler atomic.Value  // *rpcHandler
+	wsMux     http.ServeMux // registered websocket handlers go here
 
 	// These are set by setListenAddr.
 	endpoint string
 	host     string
 	port     int
 
-	handlerNames map[string]string
+	handlerNames   map[string]string
+	wsHandlerNames map[string]string
 }
 
 func newHTTPServer(log log.Logger, timeouts rpc.HTTPTimeouts) *httpServer {
-	h := &httpServer{log: log, timeouts: timeouts, handlerNames: make(map[string]string)}
+	h := &httpServer{log: log, timeouts: timeouts, handlerNames: make(map[string]string), wsHandlerNames: make(map[string]string)}
 
 	h.httpHandler.Store((*rpcHandler)(nil))
 	h.wsHandler.Store((*rpcHandler)(nil))
@@ -151,6 +163,21 @@ func (h *httpServer) start() error {
 			url += h.wsConfig.prefix
 		}
 		h.log.Info("WebSocket enabled", "url", url)
+
+		// Log all websocket handlers mounted on server.
+		var paths []string
+		for path := range h.wsHandlerNames {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		logged := make(map[string]bool, len(paths))
+		for _, path := range paths {
+			name := h.wsHandlerNames[path]
+			if !logged[name] {
+				log.Info(name+" enabled", "url", "ws://"+listener.Addr().String()+path)
+				logged[name] = true
+			}
+		}
 	}
 	// if server is websocket only, return after logging
 	if !h.rpcAllowed() {
@@ -185,6 +212,12 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// check if ws request and serve if ws enabled
 	ws := h.wsHandler.Load().(*rpcHandler)
 	if ws != nil && isWebsocket(r) {
+		// Websocket handlers registered via Node.RegisterWSHandler take
+		// precedence over the RPC handler.
+		if _, pattern := h.wsMux.Handler(r); pattern != "" {
+			ws.mux.ServeHTTP(w, r)
+			return
+		}
 		if checkPath(r, h.wsConfig.prefix) {
 			ws.ServeHTTP(w, r)
 		}
@@ -281,6 +314,8 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	srv.SetAccessControl(config.accessControl)
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
@@ -312,6 +347,8 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	}
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	srv.SetAccessControl(config.accessControl)
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
@@ -319,6 +356,7 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	h.wsHandler.Store(&rpcHandler{
 		Handler: NewWSHandlerStack(srv.WebsocketHandler(config.Origins), config.jwtSecret),
 		server:  srv,
+		mux:     NewWSHandlerStack(newAccessHandler(config.accessControl, &h.wsMux), config.jwtSecret),
 	})
 	return nil
 }
@@ -365,7 +403,7 @@ func isWebsocket(r *http.Request) bool {
 func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string, jwtSecret []byte) http.Handler {
 	// Wrap the CORS-handler within a host-handler
 	handler := newCorsHandler(srv, cors)
-	handler = newVHostHandler(vhosts, handler)
+	handler = NewVHostHandler(vhosts, handler)
 	if len(jwtSecret) != 0 {
 		handler = newJWTHandler(jwtSecret, handler)
 	}
@@ -380,6 +418,21 @@ func NewWSHandlerStack(srv http.Handler, jwtSecret []byte) http.Handler {
 	return srv
 }
 
+// newAccessHandler rate limits the clients of handlers served next to the RPC
+// server with the access control of the endpoint.
+func newAccessHandler(ac *rpc.AccessControl, next http.Handler) http.Handler {
+	if ac == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ac.AllowRequest(r) {
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
@@ -403,7 +456,9 @@ type virtualHostHandler struct {
 	next   http.Handler
 }
 
-func newVHostHandler(vhosts []string, next http.Handler) http.Handler {
+// NewVHostHandler returns a handler only serving requests addressed to IPs or to
+// one of the given virtual hosts.
+func NewVHostHandler(vhosts []string, next http.Handler) http.Handler {
 	vhostMap := make(map[string]struct{})
 	for _, allowedHost := range vhosts {
 		vhostMap[strings.ToLower(allowedHost)] = struct{}{}
@@ -484,14 +539,15 @@ func newGzipHandler(next http.Handler) http.Handler {
 type ipcServer struct {
 	log      log.Logger
 	endpoint string
+	config   rpcEndpointConfig
 
 	mu       sync.Mutex
 	listener net.Listener
 	srv      *rpc.Server
 }
 
-func newIPCServer(log log.Logger, endpoint string) *ipcServer {
-	return &ipcServer{log: log, endpoint: endpoint}
+func newIPCServer(log log.Logger, endpoint string, config rpcEndpointConfig) *ipcServer {
+	return &ipcServer{log: log, endpoint: endpoint, config: config}
 }
 
 // Start starts the httpServer's http.Server
@@ -502,9 +558,12 @@ func (is *ipcServer) start(apis []rpc.API) error {
 	if is.listener != nil {
 		return nil // already running
 	}
-	listener, srv, err := rpc.StartIPCEndpoint(is.endpoint, apis)
+	srv := rpc.NewServer()
+	srv.SetBatchLimits(is.config.batchItemLimit, is.config.batchResponseSizeLimit)
+	listener, err := rpc.ServeIPCEndpoint(is.endpoint, apis, srv)
 	if err != nil {
 		is.log.Warn("IPC opening failed", "url", is.endpoint, "error", err)
+		srv.Stop()
 		return err
 	}
 	is.log.Info("IPC endpoint opened", "url", is.endpoint)
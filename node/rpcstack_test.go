@@ -231,6 +231,53 @@ func Test_checkPath(t *testing.T) {
 	}
 }
 
+// TestWSHandlerStack tests that websocket handlers registered next to the RPC
+// endpoint are subject to the same authentication and rate limits.
+func TestWSHandlerStack(t *testing.T) {
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	})
+	dial := func(url string, headers http.Header) int {
+		conn, resp, err := websocket.DefaultDialer.Dial(url, headers)
+		if err == nil {
+			conn.Close()
+		}
+		if resp == nil {
+			t.Fatalf("could not dial %s: %v", url, err)
+		}
+		return resp.StatusCode
+	}
+	// Check that the rate limit of the RPC endpoint covers the handlers too
+	ac, err := rpc.NewAccessControl(rpc.AccessConfig{RateLimit: 0.001, RateBurst: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := createAndStartServer(t, &httpConfig{}, true, &wsConfig{Origins: []string{"*"}, rpcEndpointConfig: rpcEndpointConfig{accessControl: ac}})
+	srv.wsMux.Handle("/custom", handler)
+	url := "ws://" + srv.listenAddr() + "/custom"
+
+	assert.Equal(t, http.StatusSwitchingProtocols, dial(url, nil))
+	assert.Equal(t, http.StatusTooManyRequests, dial(url, nil))
+	srv.stop()
+
+	// Check that the handlers require the authentication of the RPC endpoint
+	secret := [32]byte{1}
+	srv = createAndStartServer(t, &httpConfig{}, true, &wsConfig{Origins: []string{"*"}, jwtSecret: secret[:]})
+	srv.wsMux.Handle("/custom", handler)
+	url = "ws://" + srv.listenAddr() + "/custom"
+
+	assert.Equal(t, http.StatusForbidden, dial(url, nil))
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iat": time.Now().Unix()}).SignedString(secret[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusSwitchingProtocols, dial(url, http.Header{"Authorization": []string{"Bearer " + token}}))
+	srv.stop()
+}
+
 func createAndStartServer(t *testing.T, conf *httpConfig, ws bool, wsConf *wsConfig) *httpServer {
 	t.Helper()
 
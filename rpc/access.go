@@ -0,0 +1,201 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru"
+	"golang.org/x/time/rate"
+)
+
+// maxRateLimitedClients is the number of clients for which rate limiting state
+// is tracked. The least recently seen clients are evicted beyond that, which
+// resets their buckets.
+const maxRateLimitedClients = 10000
+
+// AccessConfig configures method level access control and per-client rate
+// limiting of an RPC server.
+//
+// Method patterns are either a full method name ("eth_call"), a namespace
+// wildcard ("debug_*") or "*" matching every method. A method matching any of
+// the denied patterns is rejected. If allowed patterns are configured, methods
+// not matching any of them are rejected as well.
+type AccessConfig struct {
+	AllowedMethods []string `toml:",omitempty"`
+	DeniedMethods  []string `toml:",omitempty"`
+
+	// RateLimit is the number of calls per second a single client may make,
+	// 0 disables rate limiting. Clients are identified by the subject of their
+	// authentication token if any, otherwise by their IP address. Every
+	// element of a batch counts as a separate call.
+	RateLimit float64 `toml:",omitempty"`
+
+	// RateBurst is the number of calls a client may make in a burst above the
+	// rate limit. It defaults to the rate limit rounded up.
+	RateBurst int `toml:",omitempty"`
+}
+
+// AccessControl enforces an AccessConfig. It is safe for concurrent use and may
+// be shared by multiple servers.
+type AccessControl struct {
+	allowed  []string
+	denied   []string
+	limit    rate.Limit
+	burst    int
+	limiters *lru.Cache // client key -> *rate.Limiter
+}
+
+// NewAccessControl validates the given config and creates the access control
+// enforcing it.
+func NewAccessControl(config AccessConfig) (*AccessControl, error) {
+	for _, pattern := range append(append([]string{}, config.AllowedMethods...), config.DeniedMethods...) {
+		if err := validateMethodPattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+	if config.RateLimit < 0 {
+		return nil, fmt.Errorf("invalid rate limit %v", config.RateLimit)
+	}
+	if config.RateBurst < 0 {
+		return nil, fmt.Errorf("invalid rate burst %d", config.RateBurst)
+	}
+	ac := &AccessControl{
+		allowed: config.AllowedMethods,
+		denied:  config.DeniedMethods,
+		limit:   rate.Limit(config.RateLimit),
+		burst:   config.RateBurst,
+	}
+	if config.RateLimit > 0 {
+		if ac.burst == 0 {
+			ac.burst = int(config.RateLimit)
+			if float64(ac.burst) < config.RateLimit {
+				ac.burst++
+			}
+		}
+		ac.limiters, _ = lru.New(maxRateLimitedClients)
+	}
+	return ac, nil
+}
+
+// validateMethodPattern checks that a method pattern is well formed.
+func validateMethodPattern(pattern string) error {
+	if pattern == "*" {
+		return nil
+	}
+	if strings.Count(pattern, "*") > 1 || (strings.Contains(pattern, "*") && !strings.HasSuffix(pattern, "_*")) {
+		return fmt.Errorf("invalid method pattern %q", pattern)
+	}
+	if !strings.Contains(pattern, serviceMethodSeparator) {
+		return fmt.Errorf("invalid method pattern %q, missing namespace", pattern)
+	}
+	return nil
+}
+
+// matchMethod reports whether the method matches any of the patterns.
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		switch {
+		case pattern == "*":
+			return true
+		case strings.HasSuffix(pattern, "*"):
+			if strings.HasPrefix(method, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		case pattern == method:
+			return true
+		}
+	}
+	return false
+}
+
+// allowMethod reports whether the access rules permit calling the method.
+func (ac *AccessControl) allowMethod(method string) bool {
+	if matchMethod(ac.denied, method) {
+		return false
+	}
+	return len(ac.allowed) == 0 || matchMethod(ac.allowed, method)
+}
+
+// allowCall reports whether the client identified by key may make another call
+// without exceeding its rate limit. Calls of unidentified clients are never
+// limited.
+func (ac *AccessControl) allowCall(key string) bool {
+	if ac.limiters == nil || key == "" {
+		return true
+	}
+	if l, ok := ac.limiters.Get(key); ok {
+		return l.(*rate.Limiter).Allow()
+	}
+	// The limiter may have been added concurrently, only keep the first one
+	limiter := rate.NewLimiter(ac.limit, ac.burst)
+	if ok, _ := ac.limiters.ContainsOrAdd(key, limiter); ok {
+		if l, ok := ac.limiters.Get(key); ok {
+			limiter = l.(*rate.Limiter)
+		}
+	}
+	return limiter.Allow()
+}
+
+// check verifies that the client is allowed to call the method, returning the
+// error to respond with otherwise.
+func (ac *AccessControl) check(key string, msg *jsonrpcMessage) error {
+	// Unsubscribing is always permitted, otherwise denying a subscription
+	// after the fact would leave clients unable to clean up.
+	if !msg.isUnsubscribe() && !ac.allowMethod(msg.Method) {
+		rpcDeniedGauge.Inc(1)
+		return &methodDeniedError{method: msg.Method}
+	}
+	if !ac.allowCall(key) {
+		rpcRateLimitedGauge.Inc(1)
+		return &rateLimitedError{}
+	}
+	return nil
+}
+
+// AllowRequest reports whether the client issuing an HTTP or WebSocket request
+// may make another call without exceeding its rate limit. It's meant for the
+// handlers served next to the RPC server, the method rules don't apply to them.
+func (ac *AccessControl) AllowRequest(r *http.Request) bool {
+	info := PeerInfo{Transport: "http", RemoteAddr: r.RemoteAddr}
+	info.HTTP.AuthSubject = authSubjectFromContext(r.Context())
+	if !ac.allowCall(clientKey(info)) {
+		rpcRateLimitedGauge.Inc(1)
+		return false
+	}
+	return true
+}
+
+// clientKey returns the key identifying the client for rate limiting purposes.
+// Authenticated clients are identified by their subject, others by their IP.
+// Local connections (in-process, IPC) have no key.
+func clientKey(info PeerInfo) string {
+	if info.HTTP.AuthSubject != "" {
+		return "sub:" + info.HTTP.AuthSubject
+	}
+	if info.Transport != "http" && info.Transport != "ws" {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(info.RemoteAddr)
+	if err != nil {
+		return info.RemoteAddr
+	}
+	return host
+}
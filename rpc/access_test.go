@@ -0,0 +1,150 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessConfigValidation(t *testing.T) {
+	tests := []struct {
+		config AccessConfig
+		valid  bool
+	}{
+		{AccessConfig{AllowedMethods: []string{"eth_call", "debug_*", "*"}}, true},
+		{AccessConfig{DeniedMethods: []string{"eth_*"}, RateLimit: 0.5}, true},
+		{AccessConfig{DeniedMethods: []string{"eth"}}, false},
+		{AccessConfig{DeniedMethods: []string{"eth*"}}, false},
+		{AccessConfig{AllowedMethods: []string{"*_call"}}, false},
+		{AccessConfig{RateLimit: -1}, false},
+		{AccessConfig{RateLimit: 1, RateBurst: -1}, false},
+	}
+	for i, test := range tests {
+		_, err := NewAccessControl(test.config)
+		if test.valid && err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+}
+
+func TestAccessControlMethods(t *testing.T) {
+	ac, err := NewAccessControl(AccessConfig{
+		AllowedMethods: []string{"test_*", "nftest_*"},
+		DeniedMethods:  []string{"test_echo", "nftest_subscribe"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer()
+	defer server.Stop()
+	server.SetAccessControl(ac)
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Allowed method.
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("allowed method failed: %v", err)
+	}
+	// Denied method, even though its namespace is allowed.
+	checkError(t, client.Call(nil, "test_echo", "x", 1), errcodeMethodDenied)
+	// Method not matching the allow list.
+	checkError(t, client.Call(nil, "rpc_modules"), errcodeMethodDenied)
+
+	// Batch items are checked individually.
+	batch := []BatchElem{
+		{Method: "test_noArgsRets", Result: new(interface{})},
+		{Method: "test_echo", Args: []interface{}{"x", 1, &echoArgs{"y"}}, Result: new(echoResult)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch[0].Error != nil {
+		t.Fatalf("batch elem 0: unexpected error %v", batch[0].Error)
+	}
+	checkError(t, batch[1].Error, errcodeMethodDenied)
+
+	// Subscriptions go through the same checks.
+	_, err = client.Subscribe(context.Background(), "nftest", make(chan int), "someSubscription", 1, 1)
+	checkError(t, err, errcodeMethodDenied)
+}
+
+func TestAccessControlRateLimit(t *testing.T) {
+	ac, err := NewAccessControl(AccessConfig{RateLimit: 0.001, RateBurst: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer()
+	defer server.Stop()
+	server.SetAccessControl(ac)
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+	client, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// The burst allowance is consumed by the first calls, across batch items.
+	batch := make([]BatchElem, 3)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "test_noArgsRets", Result: new(interface{})}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for i := range batch[:2] {
+		if batch[i].Error != nil {
+			t.Fatalf("batch elem %d: unexpected error %v", i, batch[i].Error)
+		}
+	}
+	checkError(t, batch[2].Error, errcodeRateLimited)
+	checkError(t, client.Call(nil, "test_noArgsRets"), errcodeRateLimited)
+
+	// Authenticated clients are limited separately.
+	authed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r.WithContext(WithAuthSubject(r.Context(), "alice")))
+	})
+	authsrv := httptest.NewServer(authed)
+	defer authsrv.Close()
+	authClient, err := DialHTTP(authsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer authClient.Close()
+	if err := authClient.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("authenticated client limited: %v", err)
+	}
+}
+
+func checkError(t *testing.T, err error, code int) {
+	t.Helper()
+
+	rpcErr, ok := err.(Error)
+	if !ok {
+		t.Fatalf("expected rpc error with code %d, got %v", code, err)
+	}
+	if rpcErr.ErrorCode() != code {
+		t.Fatalf("wrong error code %d, want %d (%v)", rpcErr.ErrorCode(), code, err)
+	}
+}
@@ -144,6 +144,97 @@ func TestClientBatchRequest(t *testing.T) {
 	}
 }
 
+func TestClientBatchRequestLimit(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetBatchLimits(2, 100000)
+	client := DialInProc(server)
+	defer client.Close()
+
+	batch := []BatchElem{
+		{Method: "test_echo", Args: []interface{}{"hello", 1, &echoArgs{"world"}}, Result: new(echoResult)},
+		{Method: "test_echo", Args: []interface{}{"hello", 2, &echoArgs{"world"}}, Result: new(echoResult)},
+		{Method: "test_echo", Args: []interface{}{"hello", 3, &echoArgs{"world"}}, Result: new(echoResult)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	for i := range batch[:2] {
+		if batch[i].Error != nil {
+			t.Fatalf("batch elem %d: unexpected error %v", i, batch[i].Error)
+		}
+	}
+	re, ok := batch[2].Error.(Error)
+	if !ok {
+		t.Fatalf("batch elem 2: wrong error %v", batch[2].Error)
+	}
+	if re.ErrorCode() != -32600 || re.Error() != errMsgBatchTooLarge {
+		t.Fatalf("batch elem 2: wrong error %v (code %d)", re, re.ErrorCode())
+	}
+}
+
+func TestClientBatchResponseSizeLimit(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.RegisterName("large", largeRespService{length: 1000})
+	server.SetBatchLimits(100, 2500)
+	client := DialInProc(server)
+	defer client.Close()
+
+	batch := make([]BatchElem, 5)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "large_largeResp", Result: new(string)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	for i := range batch {
+		if i < 2 {
+			if batch[i].Error != nil {
+				t.Fatalf("batch elem %d: unexpected error %v", i, batch[i].Error)
+			}
+			continue
+		}
+		re, ok := batch[i].Error.(Error)
+		if !ok {
+			t.Fatalf("batch elem %d: wrong error %v", i, batch[i].Error)
+		}
+		if re.ErrorCode() != errcodeResponseTooLarge || re.Error() != errMsgResponseTooLarge {
+			t.Fatalf("batch elem %d: wrong error %v (code %d)", i, re, re.ErrorCode())
+		}
+	}
+}
+
+func TestClientBatchErrorSizeLimit(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.RegisterName("large", largeRespService{length: 1000})
+	server.SetBatchLimits(100, 2500)
+	client := DialInProc(server)
+	defer client.Close()
+
+	batch := make([]BatchElem, 5)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "large_largeError", Result: new(string)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	for i := range batch {
+		re, ok := batch[i].Error.(Error)
+		if !ok {
+			t.Fatalf("batch elem %d: wrong error %v", i, batch[i].Error)
+		}
+		want := "large error"
+		if i >= 2 {
+			want = errMsgResponseTooLarge
+		}
+		if re.Error() != want {
+			t.Fatalf("batch elem %d: wrong error %v, want %q", i, re, want)
+		}
+	}
+}
+
 func TestClientNotify(t *testing.T) {
 	server := newTestServer()
 	defer server.Stop()
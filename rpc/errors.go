@@ -54,9 +54,22 @@ var (
 	_ Error = new(invalidRequestError)
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
+	_ Error = new(responseTooLargeError)
+	_ Error = new(methodDeniedError)
+	_ Error = new(rateLimitedError)
 )
 
-const defaultErrorCode = -32000
+const (
+	defaultErrorCode        = -32000
+	errcodeResponseTooLarge = -32003
+	errcodeMethodDenied     = -32004
+	errcodeRateLimited      = -32005
+)
+
+const (
+	errMsgBatchTooLarge    = "batch too large"
+	errMsgResponseTooLarge = "response too large"
+)
 
 type methodNotFoundError struct{ method string }
 
@@ -101,3 +114,26 @@ type invalidParamsError struct{ message string }
 func (e *invalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *invalidParamsError) Error() string { return e.message }
+
+// the aggregate size of the responses to a batch exceeds the configured limit
+type responseTooLargeError struct{ message string }
+
+func (e *responseTooLargeError) ErrorCode() int { return errcodeResponseTooLarge }
+
+func (e *responseTooLargeError) Error() string { return e.message }
+
+// the method is not permitted by the server's access rules
+type methodDeniedError struct{ method string }
+
+func (e *methodDeniedError) ErrorCode() int { return errcodeMethodDenied }
+
+func (e *methodDeniedError) Error() string {
+	return fmt.Sprintf("the method %s is not allowed", e.method)
+}
+
+// the client exceeded its call rate limit
+type rateLimitedError struct{}
+
+func (e *rateLimitedError) ErrorCode() int { return errcodeRateLimited }
+
+func (e *rateLimitedError) Error() string { return "rate limit exceeded" }
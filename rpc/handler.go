@@ -61,17 +61,32 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	batchLimits    batchLimits
+	access         *AccessControl // method access rules and rate limits, may be nil
+	clientKey      string         // identifies the remote end for rate limiting
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
 }
 
+// handlerConfig holds the server settings applied to every connection handler.
+type handlerConfig struct {
+	batchLimits batchLimits
+	access      *AccessControl
+}
+
+// batchLimits configures the limits enforced on incoming batch requests.
+type batchLimits struct {
+	itemLimit         int // maximum number of items in a batch, 0 for no limit
+	responseSizeLimit int // maximum aggregate size of the responses in bytes, 0 for no limit
+}
+
 type callProc struct {
 	ctx       context.Context
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, cfg handlerConfig) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -82,12 +97,17 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		rootCtx:        rootCtx,
 		cancelRoot:     cancelRoot,
 		allowSubscribe: true,
+		batchLimits:    cfg.batchLimits,
+		access:         cfg.access,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
 	}
+	if h.access != nil {
+		h.clientKey = clientKey(PeerInfoFromContext(connCtx))
+	}
 	h.unsubscribeCb = newCallback(reflect.Value{}, reflect.ValueOf(h.unsubscribe))
 	return h
 }
@@ -102,6 +122,12 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		return
 	}
 
+	// Items beyond the batch size limit are not executed at all, they only get
+	// an error response.
+	var overflow []*jsonrpcMessage
+	if limit := h.batchLimits.itemLimit; limit > 0 && len(msgs) > limit {
+		msgs, overflow = msgs[:limit], msgs[limit:]
+	}
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
 	for _, msg := range msgs {
@@ -109,15 +135,41 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 			calls = append(calls, msg)
 		}
 	}
-	if len(calls) == 0 {
+	if len(calls) == 0 && len(overflow) == 0 {
 		return
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
+		var (
+			answers  = make([]*jsonrpcMessage, 0, len(calls)+len(overflow))
+			limit    = h.batchLimits.responseSizeLimit
+			respSize int
+			tooLarge bool
+		)
 		for _, msg := range calls {
-			if answer := h.handleCallMsg(cp, msg); answer != nil {
-				answers = append(answers, answer)
+			// Once the response size limit is hit, the remaining calls are
+			// rejected without being executed.
+			if tooLarge {
+				if msg.hasValidID() {
+					answers = append(answers, msg.errorResponse(&responseTooLargeError{errMsgResponseTooLarge}))
+				}
+				continue
+			}
+			answer := h.handleCallMsg(cp, msg)
+			if answer == nil {
+				continue
+			}
+			if limit > 0 {
+				if respSize += answer.size(); respSize > limit {
+					answer = msg.errorResponse(&responseTooLargeError{errMsgResponseTooLarge})
+					tooLarge = true
+				}
+			}
+			answers = append(answers, answer)
+		}
+		for _, msg := range overflow {
+			if msg.hasValidID() {
+				answers = append(answers, msg.errorResponse(&invalidRequestError{errMsgBatchTooLarge}))
 			}
 		}
 		h.addSubscriptions(cp.notifiers)
@@ -317,6 +369,11 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if h.access != nil {
+		if err := h.access.check(h.clientKey, msg); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -91,6 +91,13 @@ func (msg *jsonrpcMessage) String() string {
 	return string(b)
 }
 
+// size returns the length of the JSON encoding of the message, accounting for
+// both its result and error payloads.
+func (msg *jsonrpcMessage) size() int {
+	b, _ := json.Marshal(msg)
+	return len(b)
+}
+
 func (msg *jsonrpcMessage) errorResponse(err error) *jsonrpcMessage {
 	resp := errorMessage(err)
 	resp.ID = msg.ID
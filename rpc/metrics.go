@@ -27,6 +27,8 @@ var (
 	successfulRequestGauge = metrics.NewRegisteredGauge("rpc/success", nil)
 	failedRequestGauge     = metrics.NewRegisteredGauge("rpc/failure", nil)
 	rpcServingTimer        = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+	rpcDeniedGauge         = metrics.NewRegisteredGauge("rpc/denied", nil)
+	rpcRateLimitedGauge    = metrics.NewRegisteredGauge("rpc/ratelimited", nil)
 )
 
 func newRPCServingTimer(method string, valid bool) metrics.Timer {
@@ -47,6 +47,29 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+
+	config handlerConfig
+}
+
+// SetBatchLimits sets limits applied to batch requests. There are two limits: 'itemLimit'
+// is the maximum number of items in a batch. 'maxResponseSize' is the maximum number of
+// response bytes across all requests in a batch. A zero value disables the respective
+// limit.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetBatchLimits(itemLimit, maxResponseSize int) {
+	s.config.batchLimits = batchLimits{itemLimit: itemLimit, responseSizeLimit: maxResponseSize}
+}
+
+// SetAccessControl installs method level access rules and per-client rate limits
+// on the server. The same AccessControl may be shared by several servers, in which
+// case clients are rate limited across all of them. Passing nil removes any rules.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetAccessControl(ac *AccessControl) {
+	s.config.access = ac
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -84,7 +107,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, s.config)
 	<-codec.closed()
 	c.Close()
 }
@@ -98,7 +121,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.config)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -168,11 +191,31 @@ type PeerInfo struct {
 		UserAgent string
 		Origin    string
 		Host      string
+
+		// AuthSubject is the subject of the client's authentication token, if the
+		// request was authenticated. See WithAuthSubject.
+		AuthSubject string
 	}
 }
 
 type peerInfoContextKey struct{}
 
+type authSubjectContextKey struct{}
+
+// WithAuthSubject returns a copy of the HTTP request context carrying the identity of
+// an authenticated client, e.g. the subject of its JWT token. HTTP handlers wrapping
+// the server use this to pass the identity on, which is then reported in PeerInfo and
+// used to identify the client for rate limiting.
+func WithAuthSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, authSubjectContextKey{}, subject)
+}
+
+// authSubjectFromContext returns the subject set by WithAuthSubject, if any.
+func authSubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(authSubjectContextKey{}).(string)
+	return subject
+}
+
 // PeerInfoFromContext returns information about the client's network connection.
 // Use this with the context passed to RPC method handler functions.
 //
@@ -208,3 +208,13 @@ type largeRespService struct {
 func (x largeRespService) LargeResp() string {
 	return strings.Repeat("x", x.length)
 }
+
+func (x largeRespService) LargeError() error {
+	return largeError(strings.Repeat("x", x.length))
+}
+
+// largeError is an error carrying arbitrary-size data.
+type largeError string
+
+func (e largeError) Error() string          { return "large error" }
+func (e largeError) ErrorData() interface{} { return string(e) }
@@ -88,6 +88,11 @@ type Database struct {
 	childrenSize  common.StorageSize // Storage size of the external children tracking
 	preimagesSize common.StorageSize // Storage size of the preimages cache
 
+	path *pathDB // State layers of the path-based scheme, nil for the hash-based one
+
+	guard     func(common.Hash) // Callback notified before any trie node is persisted
+	guardLock sync.RWMutex      // Lock serializing node persistence and stale node deletion
+
 	lock sync.RWMutex
 }
 
@@ -266,6 +271,9 @@ type Config struct {
 	Cache     int    // Memory allowance (MB) to use for caching trie nodes in memory
 	Journal   string // Journal of clean cache to survive node restarts
 	Preimages bool   // Flag whether the preimage of trie key is recorded
+
+	Scheme       string // Scheme for storing trie nodes (rawdb.HashScheme or rawdb.PathScheme), hash if empty
+	StateHistory uint64 // Number of reverse diffs kept by the path-based scheme, 0 for all
 }
 
 // NewDatabase creates a new trie database to store ephemeral trie content before
@@ -297,6 +305,9 @@ func NewDatabaseWithConfig(diskdb ethdb.KeyValueStore, config *Config) *Database
 	if config == nil || config.Preimages { // TODO(karalabe): Flip to default off in the future
 		db.preimages = make(map[common.Hash][]byte)
 	}
+	if config != nil && config.Scheme == rawdb.PathScheme {
+		db.path = newPathDB(diskdb, config.StateHistory)
+	}
 	return db
 }
 
@@ -579,6 +590,9 @@ func (db *Database) dereference(child common.Hash, parent common.Hash) {
 // Note, this method is a non-synchronized mutator. It is unsafe to call this
 // concurrently with other mutators.
 func (db *Database) Cap(limit common.StorageSize) error {
+	if db.path != nil {
+		return db.capPath(limit)
+	}
 	// Create a database batch to flush persistent data out. It is important that
 	// outside code doesn't see an inconsistent state (referenced data removed from
 	// memory cache during commit but not yet in persistent storage). This is ensured
@@ -586,6 +600,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	nodes, storage, start := len(db.dirties), db.dirtiesSize, time.Now()
 	batch := db.diskdb.NewBatch()
 
+	db.guardLock.RLock()
+	defer db.guardLock.RUnlock()
+
 	// db.dirtiesSize only contains the useful data in the cache, but when reporting
 	// the total memory consumption, the maintenance metadata is also needed to be
 	// counted.
@@ -613,6 +630,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	for size > limit && oldest != (common.Hash{}) {
 		// Fetch the oldest referenced node and push into the batch
 		node := db.dirties[oldest]
+		if db.guard != nil {
+			db.guard(oldest)
+		}
 		rawdb.WriteTrieNode(batch, oldest, node.rlp())
 
 		// If we exceeded the ideal batch size, commit and reset
@@ -682,6 +702,10 @@ func (db *Database) Cap(limit common.StorageSize) error {
 // Note, this method is a non-synchronized mutator. It is unsafe to call this
 // concurrently with other mutators.
 func (db *Database) Commit(node common.Hash, report bool, callback func(common.Hash)) error {
+	// The path-based scheme persists whole state layers instead
+	if db.path != nil {
+		return db.commitPath(node, report)
+	}
 	// Create a database batch to flush persistent data out. It is important that
 	// outside code doesn't see an inconsistent state (referenced data removed from
 	// memory cache during commit but not yet in persistent storage). This is ensured
@@ -689,6 +713,9 @@ func (db *Database) Commit(node common.Hash, report bool, callback func(common.H
 	start := time.Now()
 	batch := db.diskdb.NewBatch()
 
+	db.guardLock.RLock()
+	defer db.guardLock.RUnlock()
+
 	// Move all of the accumulated preimages into a write batch
 	if db.preimages != nil {
 		rawdb.WritePreimages(batch, db.preimages)
@@ -758,6 +785,9 @@ func (db *Database) commit(hash common.Hash, batch ethdb.Batch, uncacher *cleane
 		return err
 	}
 	// If we've reached an optimal batch size, commit and start over
+	if db.guard != nil {
+		db.guard(hash)
+	}
 	rawdb.WriteTrieNode(batch, hash, node.rlp())
 	if callback != nil {
 		callback(hash)
@@ -823,6 +853,68 @@ func (c *cleaner) Delete(key []byte) error {
 	panic("not implemented")
 }
 
+// Pin references the given root from the meta-root if it's held in memory,
+// protecting it from garbage collection until it's dereferenced. It reports
+// whether the root was pinned, the ones not held in memory need no unpinning.
+func (db *Database) Pin(root common.Hash) bool {
+	if db.path != nil {
+		return false
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if _, ok := db.dirties[root]; !ok {
+		return false
+	}
+	db.reference(root, common.Hash{})
+	return true
+}
+
+// SetGuard installs a callback which is notified about every trie node right
+// before it is persisted into the disk database, or removes it if nil. It's
+// used by the online state pruner to learn about the nodes resurrected while
+// the stale ones are being deleted.
+func (db *Database) SetGuard(guard func(common.Hash)) {
+	db.guardLock.Lock()
+	defer db.guardLock.Unlock()
+
+	db.guard = guard
+}
+
+// DeleteStale removes the given trie nodes from the disk database and the clean
+// cache. The liveness of every node is checked again whilst node persistence is
+// blocked, so that nodes written concurrently by a commit are never deleted.
+//
+// Note, this method is only supported by the hash-based scheme.
+func (db *Database) DeleteStale(hashes []common.Hash, live func(common.Hash) bool) error {
+	if db.path != nil {
+		return errors.New("stale node deletion is not supported by the path-based scheme")
+	}
+	db.guardLock.Lock()
+	defer db.guardLock.Unlock()
+
+	var (
+		batch   = db.diskdb.NewBatch()
+		deleted []common.Hash
+	)
+	for _, hash := range hashes {
+		if live(hash) {
+			continue
+		}
+		rawdb.DeleteTrieNode(batch, hash)
+		deleted = append(deleted, hash)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if db.cleans != nil {
+		for _, hash := range deleted {
+			db.cleans.Del(hash[:])
+		}
+	}
+	return nil
+}
+
 // Size returns the current storage size of the memory cache in front of the
 // persistent database layer.
 func (db *Database) Size() (common.StorageSize, common.StorageSize) {
@@ -834,7 +926,13 @@ func (db *Database) Size() (common.StorageSize, common.StorageSize) {
 	// counted.
 	var metadataSize = common.StorageSize((len(db.dirties) - 1) * cachedNodeSize)
 	var metarootRefs = common.StorageSize(len(db.dirties[common.Hash{}].children) * (common.HashLength + 2))
-	return db.dirtiesSize + db.childrenSize + metadataSize - metarootRefs, db.preimagesSize
+	var layersSize common.StorageSize
+	if db.path != nil {
+		db.path.lock.RLock()
+		layersSize = common.StorageSize(db.path.size)
+		db.path.lock.RUnlock()
+	}
+	return db.dirtiesSize + db.childrenSize + metadataSize - metarootRefs + layersSize, db.preimagesSize
 }
 
 // saveCache saves clean state cache to given directory path
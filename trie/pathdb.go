@@ -0,0 +1,584 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// maxDiffLayers is the maximum number of state layers kept in memory on top of
+// the persisted state by the path-based scheme, matching the number of recent
+// states the blockchain keeps available.
+const maxDiffLayers = 128
+
+var (
+	// errStateUnknown is returned if the state layer of the requested root is
+	// not known by the path-based scheme.
+	errStateUnknown = errors.New("unknown state layer")
+
+	// errStateUnrecoverable is returned if the persisted state cannot be
+	// reverted to the requested root.
+	errStateUnrecoverable = errors.New("state is not recoverable")
+)
+
+// memoryNode is a trie node held in a state layer of the path-based scheme.
+type memoryNode struct {
+	hash common.Hash // Node hash, empty for deleted nodes
+	blob []byte      // Encoded trie node, nil for deleted nodes
+}
+
+// diffLayer is the set of trie nodes changed by a state transition, keyed by
+// the owner of the trie (zero for the account trie) and the node path.
+type diffLayer struct {
+	root   common.Hash                            // Root hash of the state after the transition
+	parent common.Hash                            // Root hash of the state before the transition
+	id     uint64                                 // Sequential id of the state, one above its parent
+	nodes  map[common.Hash]map[string]*memoryNode // Changed trie nodes, nil blobs mark deletions
+	size   uint64                                 // Approximate memory used by the layer
+}
+
+// pathDB maintains the trie node layers of the path-based scheme: a single
+// persisted state, updated in place, plus a bounded number of recent states
+// kept in memory as diffs on top of it. Whenever a layer is persisted, the
+// overwritten nodes are saved as a reverse diff, allowing to roll back the
+// persisted state later on.
+type pathDB struct {
+	diskdb  ethdb.KeyValueStore // Persistent storage of the trie nodes
+	history uint64              // Number of reverse diffs to retain, 0 for all
+
+	diskRoot common.Hash                // Root hash of the persisted state
+	diskID   uint64                     // Sequential id of the persisted state
+	layers   map[common.Hash]*diffLayer // In-memory state layers, keyed by root
+	head     common.Hash                // Root of the most recently added layer
+	size     uint64                     // Approximate memory used by all layers
+
+	lock sync.RWMutex
+}
+
+// newPathDB creates the layer tree on top of the persisted state, restoring
+// the in-memory layers saved at the last shutdown if they are still valid.
+func newPathDB(diskdb ethdb.KeyValueStore, history uint64) *pathDB {
+	p := &pathDB{
+		diskdb:   diskdb,
+		history:  history,
+		diskRoot: emptyRoot,
+		diskID:   rawdb.ReadPersistentStateID(diskdb),
+		layers:   make(map[common.Hash]*diffLayer),
+	}
+	if blob := rawdb.ReadAccountTrieNode(diskdb, nil); len(blob) > 0 {
+		p.diskRoot = crypto.Keccak256Hash(blob)
+	}
+	p.head = p.diskRoot
+	if err := p.loadJournal(); err != nil {
+		log.Warn("Failed to load trie journal", "err", err)
+		p.layers, p.size, p.head = make(map[common.Hash]*diffLayer), 0, p.diskRoot
+	}
+	return p
+}
+
+// readPathNode retrieves the persisted trie node at the given location.
+func readPathNode(db ethdb.KeyValueReader, owner common.Hash, path []byte) []byte {
+	if owner == (common.Hash{}) {
+		return rawdb.ReadAccountTrieNode(db, path)
+	}
+	return rawdb.ReadStorageTrieNode(db, owner, path)
+}
+
+// writePathNode persists the trie node at the given location, deleting the
+// persisted one if the blob is empty.
+func writePathNode(db ethdb.KeyValueWriter, owner common.Hash, path []byte, blob []byte) {
+	switch {
+	case owner == (common.Hash{}) && len(blob) == 0:
+		rawdb.DeleteAccountTrieNode(db, path)
+	case owner == (common.Hash{}):
+		rawdb.WriteAccountTrieNode(db, path, blob)
+	case len(blob) == 0:
+		rawdb.DeleteStorageTrieNode(db, owner, path)
+	default:
+		rawdb.WriteStorageTrieNode(db, owner, path, blob)
+	}
+}
+
+// node retrieves the trie node with the given hash at the given location from
+// any of the state layers. As nodes are verified against their hash, it does
+// not matter which state the node is requested for. The returned flag reports
+// whether the node was loaded from disk.
+//
+// Note, this method assumes that the lock is held!
+func (p *pathDB) node(owner common.Hash, path []byte, hash common.Hash) ([]byte, bool) {
+	for _, layer := range p.layers {
+		if n, ok := layer.nodes[owner][string(path)]; ok && n.hash == hash {
+			return n.blob, false
+		}
+	}
+	blob := readPathNode(p.diskdb, owner, path)
+	if len(blob) == 0 || crypto.Keccak256Hash(blob) != hash {
+		return nil, false
+	}
+	return blob, true
+}
+
+// lookup retrieves the trie node at the given location in the state with the
+// given root, or nil if the state doesn't have a node there.
+//
+// Note, this method assumes that the lock is held!
+func (p *pathDB) lookup(root common.Hash, owner common.Hash, path []byte) ([]byte, error) {
+	for root != p.diskRoot {
+		layer := p.layers[root]
+		if layer == nil {
+			return nil, fmt.Errorf("%w: %x", errStateUnknown, root)
+		}
+		if n, ok := layer.nodes[owner][string(path)]; ok {
+			return n.blob, nil
+		}
+		root = layer.parent
+	}
+	return readPathNode(p.diskdb, owner, path), nil
+}
+
+// known reports whether the state with the given root is available.
+//
+// Note, this method assumes that the lock is held!
+func (p *pathDB) known(root common.Hash) bool {
+	return root == p.diskRoot || p.layers[root] != nil
+}
+
+// nextID returns the id of a state built on top of the given parent.
+//
+// Note, this method assumes that the lock is held!
+func (p *pathDB) nextID(parent common.Hash) (uint64, error) {
+	if parent == p.diskRoot {
+		return p.diskID + 1, nil
+	}
+	if layer := p.layers[parent]; layer != nil {
+		return layer.id + 1, nil
+	}
+	return 0, fmt.Errorf("%w: %x", errStateUnknown, parent)
+}
+
+// add inserts a new layer into the tree, persisting the bottom-most layers of
+// its chain if there are too many of them.
+func (p *pathDB) add(layer *diffLayer) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.layers[layer.root] = layer
+	p.size += layer.size
+	p.head = layer.root
+
+	return p.cap(layer.root, maxDiffLayers)
+}
+
+// cap persists the bottom-most layers of the chain ending in the given root,
+// until at most the given number of layers remain in memory. Any layers which
+// are not built on top of the new persisted state are discarded.
+//
+// Note, this method assumes that the lock is held!
+func (p *pathDB) cap(root common.Hash, layers int) error {
+	var chain []*diffLayer
+	for root != p.diskRoot {
+		layer := p.layers[root]
+		if layer == nil {
+			return fmt.Errorf("%w: %x", errStateUnknown, root)
+		}
+		chain = append(chain, layer)
+		root = layer.parent
+	}
+	if len(chain) <= layers {
+		return nil
+	}
+	for len(chain) > layers {
+		if err := p.flush(chain[len(chain)-1]); err != nil {
+			return err
+		}
+		chain = chain[:len(chain)-1]
+	}
+	p.prune()
+	return nil
+}
+
+// flush persists the layer directly on top of the persisted state, saving the
+// overwritten nodes as a reverse diff.
+//
+// Note, this method assumes that the lock is held!
+func (p *pathDB) flush(layer *diffLayer) error {
+	if layer.parent != p.diskRoot || layer.id != p.diskID+1 {
+		return fmt.Errorf("layer %x (id %d) is not on top of the persisted state %x (id %d)", layer.root, layer.id, p.diskRoot, p.diskID)
+	}
+	var (
+		start = time.Now()
+		batch = p.diskdb.NewBatch()
+		diff  = &reverseDiff{Parent: p.diskRoot, Root: layer.root}
+	)
+	for owner, subset := range layer.nodes {
+		for path, n := range subset {
+			diff.States = append(diff.States, reverseDiffState{
+				Owner: owner,
+				Path:  []byte(path),
+				Prev:  readPathNode(p.diskdb, owner, []byte(path)),
+			})
+			writePathNode(batch, owner, []byte(path), n.blob)
+		}
+	}
+	enc, err := rlp.EncodeToBytes(diff)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteReverseDiff(batch, layer.id, enc)
+	if p.diskID == 0 {
+		rawdb.WriteReverseDiffLookup(batch, p.diskRoot, 0)
+	}
+	rawdb.WriteReverseDiffLookup(batch, layer.root, layer.id)
+	rawdb.WritePersistentStateID(batch, layer.id)
+
+	// Drop the reverse diffs beyond the retention limit, making the
+	// corresponding states unrecoverable.
+	if p.history > 0 && layer.id > p.history {
+		for id := layer.id - p.history; id > 0; id-- {
+			blob := rawdb.ReadReverseDiff(p.diskdb, id)
+			if len(blob) == 0 {
+				break
+			}
+			rawdb.DeleteReverseDiff(batch, id)
+
+			var old reverseDiff
+			if err := rlp.DecodeBytes(blob, &old); err == nil {
+				if lookup := rawdb.ReadReverseDiffLookup(p.diskdb, old.Parent); lookup != nil && *lookup == id-1 {
+					rawdb.DeleteReverseDiffLookup(batch, old.Parent)
+				}
+			}
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	p.diskRoot, p.diskID = layer.root, layer.id
+	delete(p.layers, layer.root)
+	p.size -= layer.size
+
+	log.Debug("Persisted trie layer", "root", layer.root, "id", layer.id, "nodes", len(diff.States), "size", common.StorageSize(layer.size), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// prune discards all layers which are not built on top of the persisted state.
+//
+// Note, this method assumes that the lock is held!
+func (p *pathDB) prune() {
+	valid := map[common.Hash]bool{p.diskRoot: true}
+
+	var check func(root common.Hash) bool
+	check = func(root common.Hash) bool {
+		if ok, known := valid[root]; known {
+			return ok
+		}
+		layer := p.layers[root]
+		valid[root] = layer != nil && check(layer.parent)
+		return valid[root]
+	}
+	for root, layer := range p.layers {
+		if !check(root) {
+			delete(p.layers, root)
+			p.size -= layer.size
+		}
+	}
+	if !p.known(p.head) {
+		p.head = p.diskRoot
+	}
+}
+
+// recoverable reports whether the persisted state can be reverted to the
+// state with the given root.
+//
+// Note, this method assumes that the lock is held!
+func (p *pathDB) recoverable(root common.Hash) bool {
+	id := rawdb.ReadReverseDiffLookup(p.diskdb, root)
+	if id == nil || *id >= p.diskID {
+		return false
+	}
+	// Reverse diffs are pruned from the oldest, so the whole range is
+	// available if the first one is.
+	return len(rawdb.ReadReverseDiff(p.diskdb, *id+1)) != 0
+}
+
+// recover reverts the persisted state to the one with the given root by
+// applying reverse diffs, discarding all in-memory layers.
+//
+// Note, this method assumes that the lock is held!
+func (p *pathDB) recover(root common.Hash) error {
+	if !p.recoverable(root) {
+		return fmt.Errorf("%w: %x", errStateUnrecoverable, root)
+	}
+	var (
+		start  = time.Now()
+		target = *rawdb.ReadReverseDiffLookup(p.diskdb, root)
+	)
+	p.layers, p.size = make(map[common.Hash]*diffLayer), 0
+
+	for p.diskID > target {
+		var diff reverseDiff
+		if err := rlp.DecodeBytes(rawdb.ReadReverseDiff(p.diskdb, p.diskID), &diff); err != nil {
+			return fmt.Errorf("invalid reverse diff %d: %v", p.diskID, err)
+		}
+		if diff.Root != p.diskRoot {
+			return fmt.Errorf("reverse diff %d root mismatch: have %x, want %x", p.diskID, diff.Root, p.diskRoot)
+		}
+		batch := p.diskdb.NewBatch()
+		for _, state := range diff.States {
+			writePathNode(batch, state.Owner, state.Path, state.Prev)
+		}
+		rawdb.DeleteReverseDiff(batch, p.diskID)
+		rawdb.DeleteReverseDiffLookup(batch, diff.Root)
+		rawdb.WritePersistentStateID(batch, p.diskID-1)
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		p.diskRoot, p.diskID = diff.Parent, p.diskID-1
+	}
+	p.head = p.diskRoot
+
+	log.Info("Reverted persisted state", "root", root, "id", p.diskID, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// Scheme returns the scheme used to store trie nodes in the database.
+func (db *Database) Scheme() string {
+	if db.path != nil {
+		return rawdb.PathScheme
+	}
+	return rawdb.HashScheme
+}
+
+// Initialized reports whether the state of the genesis with the given root has
+// been persisted. As the path-based scheme only keeps a single state around,
+// any persisted state counts for it.
+func (db *Database) Initialized(genesisRoot common.Hash) bool {
+	if db.path == nil {
+		return genesisRoot == emptyRoot || rawdb.HasTrieNode(db.diskdb, genesisRoot)
+	}
+	db.path.lock.RLock()
+	defer db.path.lock.RUnlock()
+
+	return db.path.diskRoot != emptyRoot || db.path.diskID != 0 || len(db.path.layers) > 0
+}
+
+// readNode retrieves the trie node with the given hash, located at the given
+// path of the trie with the given owner. Only the path-based scheme makes use
+// of the location.
+func (db *Database) readNode(owner common.Hash, path []byte, hash common.Hash) node {
+	if db.path == nil {
+		return db.node(hash)
+	}
+	blob := db.readPathBlob(owner, path, hash)
+	if blob == nil {
+		return nil
+	}
+	return mustDecodeNode(hash[:], blob)
+}
+
+// readBlob retrieves the encoded trie node with the given hash, located at the
+// given path of the trie with the given owner. Only the path-based scheme makes
+// use of the location.
+func (db *Database) readBlob(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	if db.path == nil {
+		return db.Node(hash)
+	}
+	if blob := db.readPathBlob(owner, path, hash); blob != nil {
+		return blob, nil
+	}
+	return nil, errors.New("not found")
+}
+
+// readPathBlob retrieves an encoded trie node from the caches, the in-memory
+// layers or the persisted state of the path-based scheme.
+func (db *Database) readPathBlob(owner common.Hash, path []byte, hash common.Hash) []byte {
+	// Nodes are content addressed, so the hash keyed caches are always valid
+	if db.cleans != nil {
+		if enc := db.cleans.Get(nil, hash[:]); enc != nil {
+			memcacheCleanHitMeter.Mark(1)
+			memcacheCleanReadMeter.Mark(int64(len(enc)))
+			return enc
+		}
+	}
+	db.lock.RLock()
+	dirty := db.dirties[hash]
+	db.lock.RUnlock()
+
+	if dirty != nil {
+		memcacheDirtyHitMeter.Mark(1)
+		memcacheDirtyReadMeter.Mark(int64(dirty.size))
+		return dirty.rlp()
+	}
+	memcacheDirtyMissMeter.Mark(1)
+
+	db.path.lock.RLock()
+	blob, disk := db.path.node(owner, path, hash)
+	db.path.lock.RUnlock()
+
+	if disk && db.cleans != nil {
+		db.cleans.Set(hash[:], blob)
+		memcacheCleanMissMeter.Mark(1)
+		memcacheCleanWriteMeter.Mark(int64(len(blob)))
+	}
+	return blob
+}
+
+// Update creates a new state layer out of the trie nodes committed into the
+// memory database for the state with the given root, built on top of the one
+// with the given parent root. The nodes are moved out of the memory database.
+// The oldest layers are persisted once there are too many of them.
+//
+// It is a no-op for the hash-based scheme, where tries are tracked by
+// reference counting instead.
+//
+// Note, this method is a non-synchronized mutator. It is unsafe to call this
+// concurrently with other mutators.
+func (db *Database) Update(root common.Hash, parent common.Hash) error {
+	if db.path == nil || root == parent {
+		return nil
+	}
+	db.path.lock.RLock()
+	known := db.path.known(root)
+	db.path.lock.RUnlock()
+
+	if !known {
+		layer, err := db.buildLayer(root, parent)
+		if err != nil {
+			return err
+		}
+		if err := db.path.add(layer); err != nil {
+			return err
+		}
+	}
+	// The committed nodes are available from the layer now, drop them from
+	// the dirty cache.
+	db.lock.Lock()
+	db.reference(root, common.Hash{})
+	db.dereference(root, common.Hash{})
+	db.lock.Unlock()
+
+	return nil
+}
+
+// commitPath persists all the layers up to the state with the given root. If
+// the state has no layer yet, it is created on top of the persisted state.
+func (db *Database) commitPath(root common.Hash, report bool) error {
+	start := time.Now()
+	if db.preimages != nil {
+		batch := db.diskdb.NewBatch()
+		rawdb.WritePreimages(batch, db.preimages)
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		db.lock.Lock()
+		db.preimages, db.preimagesSize = make(map[common.Hash][]byte), 0
+		db.lock.Unlock()
+	}
+	db.path.lock.RLock()
+	known, parent := db.path.known(root), db.path.diskRoot
+	db.path.lock.RUnlock()
+
+	if !known {
+		if err := db.Update(root, parent); err != nil {
+			return err
+		}
+	}
+	db.path.lock.Lock()
+	defer db.path.lock.Unlock()
+
+	if err := db.path.cap(root, 0); err != nil {
+		log.Error("Failed to persist trie layers", "err", err)
+		return err
+	}
+	logger := log.Info
+	if !report {
+		logger = log.Debug
+	}
+	logger("Persisted trie layers to disk", "root", root, "id", db.path.diskID, "livelayers", len(db.path.layers),
+		"livesize", common.StorageSize(db.path.size), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// capPath persists the oldest layers of the most recently updated chain until
+// the memory used by the layers goes below the given threshold.
+func (db *Database) capPath(limit common.StorageSize) error {
+	db.path.lock.Lock()
+	defer db.path.lock.Unlock()
+
+	var chain []common.Hash
+	for root := db.path.head; root != db.path.diskRoot; {
+		layer := db.path.layers[root]
+		if layer == nil {
+			return fmt.Errorf("%w: %x", errStateUnknown, root)
+		}
+		chain = append(chain, root)
+		root = layer.parent
+	}
+	for i := len(chain) - 1; i >= 0 && common.StorageSize(db.path.size) > limit; i-- {
+		if err := db.path.flush(db.path.layers[chain[i]]); err != nil {
+			return err
+		}
+	}
+	db.path.prune()
+	return nil
+}
+
+// Recoverable reports whether the persisted state can be reverted to the state
+// with the given root. It's always false for the hash-based scheme.
+func (db *Database) Recoverable(root common.Hash) bool {
+	if db.path == nil {
+		return false
+	}
+	db.path.lock.RLock()
+	defer db.path.lock.RUnlock()
+
+	return db.path.recoverable(root)
+}
+
+// Recover reverts the persisted state to the state with the given root, using
+// the reverse diffs saved whenever a state layer was persisted. All in-memory
+// layers are discarded.
+func (db *Database) Recover(root common.Hash) error {
+	if db.path == nil {
+		return errors.New("state recovery requires the path-based scheme")
+	}
+	db.path.lock.Lock()
+	defer db.path.lock.Unlock()
+
+	return db.path.recover(root)
+}
+
+// Journal saves the in-memory layers up to the state with the given root, so
+// they can be restored after a restart. It is a no-op for the hash-based scheme.
+func (db *Database) Journal(root common.Hash) error {
+	if db.path == nil {
+		return nil
+	}
+	db.path.lock.RLock()
+	defer db.path.lock.RUnlock()
+
+	return db.path.journal(root)
+}
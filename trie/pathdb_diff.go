@@ -0,0 +1,281 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// layerBuilder assembles the state layer of a state transition in the
+// path-based scheme. Starting from the new root, it walks the nodes committed
+// into the memory database and compares each of them with the node at the same
+// location in the parent state, which yields both the changed nodes and the
+// ones which are not part of the new state anymore.
+//
+// The walk relies on the fact that a trie node's location is determined by the
+// keys beneath it: any node which is not committed anew is still at the same
+// location as in the parent state, so the walk can stop there. Subtries of the
+// parent state which are gone are only deleted once the walk is done, since
+// parts of them may still be reachable in the new state through other nodes.
+type layerBuilder struct {
+	db     *Database
+	parent common.Hash // Root of the parent state
+
+	nodes   map[common.Hash]map[string]*memoryNode // Nodes of the new state
+	kept    map[common.Hash]map[string]struct{}    // Unchanged nodes of the new state
+	stale   []staleNode                            // Subtries of the parent state to delete
+	deleted map[common.Hash]map[string]struct{}    // Nodes of the parent state to delete
+	created map[common.Hash]struct{}               // Accounts with a leaf in the new state
+	removed map[common.Hash]struct{}               // Accounts with a leaf removed from the parent state
+}
+
+// staleNode is the root of a subtrie of the parent state which is gone.
+type staleNode struct {
+	owner common.Hash
+	path  []byte
+	hash  common.Hash
+}
+
+// buildLayer creates the state layer for the transition from the parent state
+// to the one with the given root, whose nodes are in the dirty cache.
+func (db *Database) buildLayer(root common.Hash, parent common.Hash) (*diffLayer, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	db.path.lock.RLock()
+	defer db.path.lock.RUnlock()
+
+	id, err := db.path.nextID(parent)
+	if err != nil {
+		return nil, err
+	}
+	b := &layerBuilder{
+		db:      db,
+		parent:  parent,
+		nodes:   make(map[common.Hash]map[string]*memoryNode),
+		kept:    make(map[common.Hash]map[string]struct{}),
+		deleted: make(map[common.Hash]map[string]struct{}),
+		created: make(map[common.Hash]struct{}),
+		removed: make(map[common.Hash]struct{}),
+	}
+	if root == emptyRoot {
+		b.stale = append(b.stale, staleNode{path: nil, hash: parent})
+	} else if err := b.update(common.Hash{}, nil, root, parent); err != nil {
+		return nil, err
+	}
+	for _, n := range b.stale {
+		if err := b.delete(n.owner, n.path, n.hash); err != nil {
+			return nil, err
+		}
+	}
+	// Accounts removed from the trie take their storage along. Accounts which
+	// merely moved have been recreated at their new location.
+	for account := range b.removed {
+		if _, ok := b.created[account]; ok {
+			continue
+		}
+		if err := b.delete(account, nil, common.Hash{}); err != nil {
+			return nil, err
+		}
+	}
+	layer := &diffLayer{
+		root:   root,
+		parent: parent,
+		id:     id,
+		nodes:  b.nodes,
+	}
+	for owner, subset := range b.deleted {
+		if layer.nodes[owner] == nil {
+			layer.nodes[owner] = make(map[string]*memoryNode)
+		}
+		for path := range subset {
+			if _, ok := layer.nodes[owner][path]; !ok {
+				layer.nodes[owner][path] = new(memoryNode)
+			}
+		}
+	}
+	for _, subset := range layer.nodes {
+		for path, n := range subset {
+			layer.size += uint64(common.HashLength + len(path) + len(n.blob))
+		}
+		layer.size += common.HashLength
+	}
+	return layer, nil
+}
+
+// lookup retrieves the node at the given location in the parent state. If the
+// hash of the node is known, the clean cache is consulted first.
+func (b *layerBuilder) lookup(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	if hash != (common.Hash{}) && b.db.cleans != nil {
+		if enc := b.db.cleans.Get(nil, hash[:]); enc != nil {
+			return enc, nil
+		}
+	}
+	return b.db.path.lookup(b.parent, owner, path)
+}
+
+// update adds the committed node with the given hash at the given location to
+// the layer, recursing into the changed children and deleting the subtries of
+// the parent state which are gone. The hash of the node at the same location in
+// the parent state is passed along if known.
+func (b *layerBuilder) update(owner common.Hash, path []byte, hash common.Hash, prevHash common.Hash) error {
+	if hash == prevHash {
+		b.keep(owner, path)
+		return nil
+	}
+	prevBlob, err := b.lookup(owner, path, prevHash)
+	if err != nil {
+		return err
+	}
+	if prevHash == (common.Hash{}) && len(prevBlob) > 0 && crypto.Keccak256Hash(prevBlob) == hash {
+		b.keep(owner, path)
+		return nil
+	}
+	cached := b.db.dirties[hash]
+	if cached == nil {
+		return fmt.Errorf("missing committed trie node %x (owner %x, path %x)", hash, owner, path)
+	}
+	var (
+		n    = cached.obj(hash)
+		prev node
+	)
+	if len(prevBlob) > 0 {
+		prev = mustDecodeNode(nil, prevBlob)
+	}
+	if b.nodes[owner] == nil {
+		b.nodes[owner] = make(map[string]*memoryNode)
+	}
+	b.nodes[owner][string(path)] = &memoryNode{hash: hash, blob: cached.rlp()}
+
+	// Account leaves carry the root of the account's storage trie
+	if owner == (common.Hash{}) {
+		if account, _, ok := accountLeaf(prev, path); ok {
+			b.removed[account] = struct{}{}
+		}
+		if account, value, ok := accountLeaf(n, path); ok {
+			b.created[account] = struct{}{}
+
+			var acc types.StateAccount
+			if err := rlp.DecodeBytes(value, &acc); err == nil {
+				if acc.Root == emptyRoot {
+					err = b.delete(account, nil, common.Hash{})
+				} else {
+					err = b.update(account, nil, acc.Root, common.Hash{})
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	// Descend into the changed children, then drop the ones which are gone
+	prevChildren := make(map[string]common.Hash)
+	forHashChildren(prev, path, func(path []byte, hash common.Hash) {
+		prevChildren[string(path)] = hash
+	})
+	forHashChildren(n, path, func(path []byte, hash common.Hash) {
+		if err == nil {
+			err = b.update(owner, path, hash, prevChildren[string(path)])
+		}
+		delete(prevChildren, string(path))
+	})
+	if err != nil {
+		return err
+	}
+	for path, hash := range prevChildren {
+		b.stale = append(b.stale, staleNode{owner: owner, path: []byte(path), hash: hash})
+	}
+	return nil
+}
+
+// keep marks the node at the given location as unchanged in the new state.
+func (b *layerBuilder) keep(owner common.Hash, path []byte) {
+	if b.kept[owner] == nil {
+		b.kept[owner] = make(map[string]struct{})
+	}
+	b.kept[owner][string(path)] = struct{}{}
+}
+
+// delete removes the node at the given location of the parent state from the
+// layer, along with its whole subtrie. The parts of the subtrie which are still
+// present in the new state are left alone.
+func (b *layerBuilder) delete(owner common.Hash, path []byte, hash common.Hash) error {
+	if _, ok := b.kept[owner][string(path)]; ok {
+		return nil
+	}
+	if _, ok := b.nodes[owner][string(path)]; ok {
+		return nil
+	}
+	blob, err := b.lookup(owner, path, hash)
+	if err != nil || len(blob) == 0 {
+		return err
+	}
+	if b.deleted[owner] == nil {
+		b.deleted[owner] = make(map[string]struct{})
+	}
+	b.deleted[owner][string(path)] = struct{}{}
+
+	n := mustDecodeNode(nil, blob)
+	if owner == (common.Hash{}) {
+		if account, _, ok := accountLeaf(n, path); ok {
+			b.removed[account] = struct{}{}
+		}
+	}
+	forHashChildren(n, path, func(path []byte, hash common.Hash) {
+		if err == nil {
+			err = b.delete(owner, path, hash)
+		}
+	})
+	return err
+}
+
+// forHashChildren invokes the callback for all the children of the node at the
+// given path which are stored on their own, i.e. referenced by hash, along with
+// their paths.
+func forHashChildren(n node, path []byte, onChild func(path []byte, hash common.Hash)) {
+	switch n := n.(type) {
+	case *shortNode:
+		forHashChildren(n.Val, concat(path, n.Key...), onChild)
+	case *fullNode:
+		for i := 0; i < 16; i++ {
+			if n.Children[i] != nil {
+				forHashChildren(n.Children[i], concat(path, byte(i)), onChild)
+			}
+		}
+	case hashNode:
+		onChild(path, common.BytesToHash(n))
+	}
+}
+
+// accountLeaf returns the account hash and the value if the node at the given
+// path is a leaf of the account trie.
+func accountLeaf(n node, path []byte) (common.Hash, []byte, bool) {
+	short, ok := n.(*shortNode)
+	if !ok {
+		return common.Hash{}, nil, false
+	}
+	value, ok := short.Val.(valueNode)
+	if !ok || len(path)+len(short.Key) != 2*common.HashLength+1 {
+		return common.Hash{}, nil, false
+	}
+	return common.BytesToHash(hexToKeybytes(concat(path, short.Key...))), value, true
+}
@@ -0,0 +1,161 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// journalVersion ensures that an incompatible journal is detected and discarded.
+const journalVersion uint64 = 0
+
+// reverseDiff is the set of trie nodes overwritten when persisting a state
+// layer of the path-based scheme. Applying it onto the persisted state reverts
+// it to the parent state.
+type reverseDiff struct {
+	Parent common.Hash        // Root of the state the diff reverts to
+	Root   common.Hash        // Root of the state the diff reverts from
+	States []reverseDiffState // Nodes to restore, empty ones are deleted
+}
+
+// reverseDiffState is a single trie node of a reverse diff.
+type reverseDiffState struct {
+	Owner common.Hash
+	Path  []byte
+	Prev  []byte
+}
+
+// journalNode is a single trie node of a journalled layer, an empty blob marks
+// a deleted node.
+type journalNode struct {
+	Owner common.Hash
+	Path  []byte
+	Blob  []byte
+}
+
+// journalLayer is a state layer as saved in the journal.
+type journalLayer struct {
+	Root   common.Hash
+	Parent common.Hash
+	ID     uint64
+	Nodes  []journalNode
+}
+
+// trieJournal is the set of in-memory layers saved at shutdown, along with the
+// persisted state they were built on.
+type trieJournal struct {
+	Version  uint64
+	DiskRoot common.Hash
+	DiskID   uint64
+	Layers   []journalLayer // Ordered from the bottom-most layer
+}
+
+// journal saves the chain of layers ending in the given root into the database.
+//
+// Note, this method assumes that the lock is held!
+func (p *pathDB) journal(root common.Hash) error {
+	var chain []*diffLayer
+	for root != p.diskRoot {
+		layer := p.layers[root]
+		if layer == nil {
+			return fmt.Errorf("%w: %x", errStateUnknown, root)
+		}
+		chain = append(chain, layer)
+		root = layer.parent
+	}
+	journal := trieJournal{
+		Version:  journalVersion,
+		DiskRoot: p.diskRoot,
+		DiskID:   p.diskID,
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		layer := journalLayer{
+			Root:   chain[i].root,
+			Parent: chain[i].parent,
+			ID:     chain[i].id,
+		}
+		for owner, subset := range chain[i].nodes {
+			for path, n := range subset {
+				layer.Nodes = append(layer.Nodes, journalNode{Owner: owner, Path: []byte(path), Blob: n.blob})
+			}
+		}
+		journal.Layers = append(journal.Layers, layer)
+	}
+	enc, err := rlp.EncodeToBytes(journal)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteTrieJournal(p.diskdb, enc)
+	log.Info("Journalled trie layers", "root", p.diskRoot, "id", p.diskID, "layers", len(chain), "size", common.StorageSize(len(enc)))
+	return nil
+}
+
+// loadJournal restores the in-memory layers from the journal, provided that it
+// was saved on top of the current persisted state.
+func (p *pathDB) loadJournal() error {
+	blob := rawdb.ReadTrieJournal(p.diskdb)
+	if len(blob) == 0 {
+		return nil
+	}
+	var journal trieJournal
+	if err := rlp.DecodeBytes(blob, &journal); err != nil {
+		return err
+	}
+	if journal.Version != journalVersion {
+		return fmt.Errorf("journal version mismatch: have %d, want %d", journal.Version, journalVersion)
+	}
+	if journal.DiskRoot != p.diskRoot || journal.DiskID != p.diskID {
+		log.Info("Discarded stale trie journal", "root", journal.DiskRoot, "id", journal.DiskID, "disk", p.diskRoot, "diskid", p.diskID)
+		return nil
+	}
+	parent, id := p.diskRoot, p.diskID
+	for _, entry := range journal.Layers {
+		if entry.Parent != parent || entry.ID != id+1 {
+			return fmt.Errorf("broken journal layer %x: parent %x, id %d", entry.Root, entry.Parent, entry.ID)
+		}
+		layer := &diffLayer{
+			root:   entry.Root,
+			parent: entry.Parent,
+			id:     entry.ID,
+			nodes:  make(map[common.Hash]map[string]*memoryNode),
+		}
+		for _, n := range entry.Nodes {
+			if layer.nodes[n.Owner] == nil {
+				layer.nodes[n.Owner] = make(map[string]*memoryNode)
+				layer.size += common.HashLength
+			}
+			mn := new(memoryNode)
+			if len(n.Blob) > 0 {
+				mn.hash, mn.blob = crypto.Keccak256Hash(n.Blob), n.Blob
+			}
+			layer.nodes[n.Owner][string(n.Path)] = mn
+			layer.size += uint64(common.HashLength + len(n.Path) + len(n.Blob))
+		}
+		p.layers[layer.root] = layer
+		p.size += layer.size
+		parent, id = layer.root, layer.id
+	}
+	p.head = parent
+	log.Info("Loaded trie journal", "root", p.diskRoot, "id", p.diskID, "layers", len(journal.Layers))
+	return nil
+}
@@ -0,0 +1,520 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// testAccount is the expected content of an account in the path scheme tests.
+type testAccount struct {
+	nonce   uint64
+	storage map[common.Hash][]byte
+}
+
+type testState map[common.Hash]*testAccount
+
+func (s testState) copy() testState {
+	cpy := make(testState)
+	for hash, acc := range s {
+		storage := make(map[common.Hash][]byte)
+		for slot, value := range acc.storage {
+			storage[slot] = value
+		}
+		cpy[hash] = &testAccount{nonce: acc.nonce, storage: storage}
+	}
+	return cpy
+}
+
+// pathTester commits a chain of random state transitions into a trie database
+// using the path-based scheme, tracking the expected content of every state.
+type pathTester struct {
+	rand   *rand.Rand
+	diskdb ethdb.Database
+	db     *Database
+
+	roots  []common.Hash
+	states []testState
+}
+
+func newPathTester(seed int64, history uint64) *pathTester {
+	diskdb := rawdb.NewMemoryDatabase()
+	return &pathTester{
+		rand:   rand.New(rand.NewSource(seed)),
+		diskdb: diskdb,
+		db:     NewDatabaseWithConfig(diskdb, &Config{Scheme: rawdb.PathScheme, StateHistory: history}),
+		roots:  []common.Hash{emptyRoot},
+		states: []testState{make(testState)},
+	}
+}
+
+func (t *pathTester) randBytes(n int) []byte {
+	b := make([]byte, n)
+	t.rand.Read(b)
+	return b
+}
+
+// sortHashes sorts the hashes in ascending order, so that the random mutations
+// are reproducible.
+func sortHashes(hashes []common.Hash) []common.Hash {
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+	return hashes
+}
+
+// mutate creates a random successor of the given state, returning it along
+// with the accounts whose storage has to be rebuilt from scratch.
+func (t *pathTester) mutate(state testState) (testState, map[common.Hash]bool) {
+	var (
+		next  = state.copy()
+		wiped = make(map[common.Hash]bool)
+		keys  []common.Hash
+	)
+	for hash := range next {
+		keys = append(keys, hash)
+	}
+	sortHashes(keys)
+	// Create a few new accounts
+	for i := 0; i < 1+t.rand.Intn(3); i++ {
+		acc := &testAccount{storage: make(map[common.Hash][]byte)}
+		for j := 0; j < t.rand.Intn(6); j++ {
+			acc.storage[common.BytesToHash(t.randBytes(32))] = t.randBytes(1 + t.rand.Intn(32))
+		}
+		next[common.BytesToHash(t.randBytes(32))] = acc
+	}
+	if len(keys) == 0 {
+		return next, wiped
+	}
+	// Modify some existing ones
+	for i := 0; i < 2+t.rand.Intn(3); i++ {
+		acc := next[keys[t.rand.Intn(len(keys))]]
+		if acc == nil {
+			continue
+		}
+		acc.nonce++
+
+		var slots []common.Hash
+		for slot := range acc.storage {
+			slots = append(slots, slot)
+		}
+		for _, slot := range sortHashes(slots) {
+			switch t.rand.Intn(4) {
+			case 0:
+				delete(acc.storage, slot)
+			case 1:
+				acc.storage[slot] = t.randBytes(1 + t.rand.Intn(32))
+			}
+		}
+		for j := 0; j < t.rand.Intn(3); j++ {
+			acc.storage[common.BytesToHash(t.randBytes(32))] = t.randBytes(1 + t.rand.Intn(32))
+		}
+	}
+	// Delete an account and recreate another one with fresh storage
+	if t.rand.Intn(2) == 0 {
+		delete(next, keys[t.rand.Intn(len(keys))])
+	}
+	if t.rand.Intn(2) == 0 {
+		hash := keys[t.rand.Intn(len(keys))]
+		acc := &testAccount{storage: make(map[common.Hash][]byte)}
+		for j := 0; j < t.rand.Intn(4); j++ {
+			acc.storage[common.BytesToHash(t.randBytes(32))] = t.randBytes(1 + t.rand.Intn(32))
+		}
+		next[hash], wiped[hash] = acc, true
+	}
+	return next, wiped
+}
+
+// commit applies the transition from the parent state into the tries and
+// creates the state layer for it.
+func (t *pathTester) commit(parent int, state testState, wiped map[common.Hash]bool) (common.Hash, error) {
+	var (
+		prevRoot  = t.roots[parent]
+		prevState = t.states[parent]
+	)
+	tr, err := New(common.Hash{}, prevRoot, t.db)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	for hash := range prevState {
+		if _, ok := state[hash]; !ok {
+			if err := tr.TryDelete(hash[:]); err != nil {
+				return common.Hash{}, err
+			}
+		}
+	}
+	for hash, acc := range state {
+		storageRoot := emptyRoot
+		if prev, ok := prevState[hash]; ok && !wiped[hash] {
+			blob, err := tr.TryGet(hash[:])
+			if err != nil {
+				return common.Hash{}, err
+			}
+			var stored types.StateAccount
+			if err := rlp.DecodeBytes(blob, &stored); err != nil {
+				return common.Hash{}, err
+			}
+			if prev.nonce == acc.nonce {
+				continue // unchanged
+			}
+			storageRoot = stored.Root
+		}
+		st, err := New(hash, storageRoot, t.db)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		var prevStorage map[common.Hash][]byte
+		if prev, ok := prevState[hash]; ok && !wiped[hash] {
+			prevStorage = prev.storage
+		}
+		for slot := range prevStorage {
+			if _, ok := acc.storage[slot]; !ok {
+				if err := st.TryDelete(slot[:]); err != nil {
+					return common.Hash{}, err
+				}
+			}
+		}
+		for slot, value := range acc.storage {
+			if err := st.TryUpdate(slot[:], value); err != nil {
+				return common.Hash{}, err
+			}
+		}
+		if storageRoot, _, err = st.Commit(nil); err != nil {
+			return common.Hash{}, err
+		}
+		err = tr.TryUpdateAccount(hash[:], &types.StateAccount{
+			Nonce:    acc.nonce,
+			Balance:  big.NewInt(1),
+			Root:     storageRoot,
+			CodeHash: crypto.Keccak256(nil),
+		})
+		if err != nil {
+			return common.Hash{}, err
+		}
+	}
+	root, _, err := tr.Commit(func(_ [][]byte, _ []byte, leaf []byte, parent common.Hash) error {
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(leaf, &acc); err != nil {
+			return nil
+		}
+		if acc.Root != emptyRoot {
+			t.db.Reference(acc.Root, parent)
+		}
+		return nil
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := t.db.Update(root, prevRoot); err != nil {
+		return common.Hash{}, err
+	}
+	return root, nil
+}
+
+// extend adds the given number of random states on top of the last one.
+func (t *pathTester) extend(n int) error {
+	for i := 0; i < n; i++ {
+		state, wiped := t.mutate(t.states[len(t.states)-1])
+		root, err := t.commit(len(t.states)-1, state, wiped)
+		if err != nil {
+			return err
+		}
+		t.roots = append(t.roots, root)
+		t.states = append(t.states, state)
+	}
+	return nil
+}
+
+// checkState verifies that the state with the given root is fully available
+// and matches the expected content.
+func checkState(db *Database, root common.Hash, state testState) error {
+	tr, err := New(common.Hash{}, root, db)
+	if err != nil {
+		return err
+	}
+	var (
+		accounts int
+		it       = NewIterator(tr.NodeIterator(nil))
+	)
+	for it.Next() {
+		accounts++
+		hash := common.BytesToHash(it.Key)
+		exp, ok := state[hash]
+		if !ok {
+			return fmt.Errorf("unexpected account %x", hash)
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+			return err
+		}
+		if acc.Nonce != exp.nonce {
+			return fmt.Errorf("account %x nonce mismatch: have %d, want %d", hash, acc.Nonce, exp.nonce)
+		}
+		st, err := New(hash, acc.Root, db)
+		if err != nil {
+			return err
+		}
+		slots, sit := 0, NewIterator(st.NodeIterator(nil))
+		for sit.Next() {
+			slots++
+			if want := exp.storage[common.BytesToHash(sit.Key)]; !bytes.Equal(sit.Value, want) {
+				return fmt.Errorf("account %x slot %x mismatch: have %x, want %x", hash, sit.Key, sit.Value, want)
+			}
+		}
+		if sit.Err != nil {
+			return sit.Err
+		}
+		if slots != len(exp.storage) {
+			return fmt.Errorf("account %x slot count mismatch: have %d, want %d", hash, slots, len(exp.storage))
+		}
+	}
+	if it.Err != nil {
+		return it.Err
+	}
+	if accounts != len(state) {
+		return fmt.Errorf("account count mismatch: have %d, want %d", accounts, len(state))
+	}
+	return nil
+}
+
+// checkDisk verifies that the persisted trie nodes are exactly the nodes of
+// the state with the given root, i.e. that no stale nodes are left behind.
+func checkDisk(db *Database, diskdb ethdb.Database, root common.Hash) error {
+	want := make(map[string]bool)
+	collect := func(owner common.Hash, root common.Hash) ([]common.Hash, error) {
+		tr, err := New(owner, root, db)
+		if err != nil {
+			return nil, err
+		}
+		var storages []common.Hash
+		for it := tr.NodeIterator(nil); it.Next(true); {
+			if it.Hash() != (common.Hash{}) {
+				if owner == (common.Hash{}) {
+					want[string(append([]byte("A"), it.Path()...))] = true
+				} else {
+					want[string(append(append([]byte("O"), owner[:]...), it.Path()...))] = true
+				}
+			}
+			if it.Leaf() && owner == (common.Hash{}) {
+				var acc types.StateAccount
+				if err := rlp.DecodeBytes(it.LeafBlob(), &acc); err != nil {
+					return nil, err
+				}
+				if acc.Root != emptyRoot {
+					storages = append(storages, common.BytesToHash(it.LeafKey()), acc.Root)
+				}
+			}
+		}
+		return storages, nil
+	}
+	storages, err := collect(common.Hash{}, root)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(storages); i += 2 {
+		if _, err := collect(storages[i], storages[i+1]); err != nil {
+			return err
+		}
+	}
+	have := make(map[string]bool)
+	for _, prefix := range []string{"A", "O"} {
+		it := diskdb.NewIterator([]byte(prefix), nil)
+		for it.Next() {
+			have[string(it.Key())] = true
+		}
+		it.Release()
+	}
+	for key := range want {
+		if !have[key] {
+			return fmt.Errorf("missing persisted node %x", key)
+		}
+	}
+	for key := range have {
+		if !want[key] {
+			return fmt.Errorf("stale persisted node %x", key)
+		}
+	}
+	return nil
+}
+
+func TestPathSchemeStateLayers(t *testing.T) {
+	tester := newPathTester(1, 0)
+	if err := tester.extend(maxDiffLayers + 40); err != nil {
+		t.Fatalf("failed to create states: %v", err)
+	}
+	head := len(tester.roots) - 1
+
+	// All in-memory layers and the persisted state must be available
+	for i := head; i >= head-maxDiffLayers; i-- {
+		if err := checkState(tester.db, tester.roots[i], tester.states[i]); err != nil {
+			t.Fatalf("state %d: %v", i, err)
+		}
+	}
+	// Older states must be gone
+	if _, err := New(common.Hash{}, tester.roots[head-maxDiffLayers-1], tester.db); err == nil {
+		t.Fatalf("state %d available beyond the in-memory layers", head-maxDiffLayers-1)
+	}
+	// Persisting the head must leave no stale nodes on disk
+	if err := tester.db.Commit(tester.roots[head], false, nil); err != nil {
+		t.Fatalf("failed to commit head: %v", err)
+	}
+	if err := checkState(tester.db, tester.roots[head], tester.states[head]); err != nil {
+		t.Fatalf("head state: %v", err)
+	}
+	if err := checkDisk(tester.db, tester.diskdb, tester.roots[head]); err != nil {
+		t.Fatalf("head disk: %v", err)
+	}
+	// Revert the persisted state to an older one
+	target := 10
+	if !tester.db.Recoverable(tester.roots[target]) {
+		t.Fatalf("state %d not recoverable", target)
+	}
+	if err := tester.db.Recover(tester.roots[target]); err != nil {
+		t.Fatalf("failed to recover state %d: %v", target, err)
+	}
+	if err := checkState(tester.db, tester.roots[target], tester.states[target]); err != nil {
+		t.Fatalf("recovered state: %v", err)
+	}
+	if err := checkDisk(tester.db, tester.diskdb, tester.roots[target]); err != nil {
+		t.Fatalf("recovered disk: %v", err)
+	}
+	if tester.db.Recoverable(tester.roots[head]) {
+		t.Fatal("reverted state still recoverable")
+	}
+	// The chain can continue from the recovered state
+	tester.roots, tester.states = tester.roots[:target+1], tester.states[:target+1]
+	if err := tester.extend(5); err != nil {
+		t.Fatalf("failed to extend recovered state: %v", err)
+	}
+	last := len(tester.roots) - 1
+	if err := tester.db.Commit(tester.roots[last], false, nil); err != nil {
+		t.Fatalf("failed to commit head: %v", err)
+	}
+	if err := checkDisk(tester.db, tester.diskdb, tester.roots[last]); err != nil {
+		t.Fatalf("extended disk: %v", err)
+	}
+}
+
+func TestPathSchemeStateHistory(t *testing.T) {
+	tester := newPathTester(2, 10)
+	if err := tester.extend(30); err != nil {
+		t.Fatalf("failed to create states: %v", err)
+	}
+	head := len(tester.roots) - 1
+	if err := tester.db.Commit(tester.roots[head], false, nil); err != nil {
+		t.Fatalf("failed to commit head: %v", err)
+	}
+	for i := 0; i < head; i++ {
+		if want := i >= head-10; tester.db.Recoverable(tester.roots[i]) != want {
+			t.Errorf("state %d: recoverable mismatch, want %v", i, want)
+		}
+	}
+	if err := tester.db.Recover(tester.roots[head-11]); err == nil {
+		t.Fatal("recovered state beyond history")
+	}
+	if err := tester.db.Recover(tester.roots[head-10]); err != nil {
+		t.Fatalf("failed to recover state: %v", err)
+	}
+	if err := checkDisk(tester.db, tester.diskdb, tester.roots[head-10]); err != nil {
+		t.Fatalf("recovered disk: %v", err)
+	}
+}
+
+func TestPathSchemeJournal(t *testing.T) {
+	tester := newPathTester(3, 0)
+	if err := tester.extend(20); err != nil {
+		t.Fatalf("failed to create states: %v", err)
+	}
+	if err := tester.db.Commit(tester.roots[5], false, nil); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	head := len(tester.roots) - 1
+	if err := tester.db.Journal(tester.roots[head]); err != nil {
+		t.Fatalf("failed to journal layers: %v", err)
+	}
+	// Reopen the database, all the journalled layers must be restored
+	db := NewDatabaseWithConfig(tester.diskdb, &Config{Scheme: rawdb.PathScheme})
+	for i := 5; i <= head; i++ {
+		if err := checkState(db, tester.roots[i], tester.states[i]); err != nil {
+			t.Fatalf("state %d: %v", i, err)
+		}
+	}
+	// The journal must be ignored once the persisted state changed
+	if err := db.Commit(tester.roots[head], false, nil); err != nil {
+		t.Fatalf("failed to commit head: %v", err)
+	}
+	db = NewDatabaseWithConfig(tester.diskdb, &Config{Scheme: rawdb.PathScheme})
+	if _, err := New(common.Hash{}, tester.roots[head-1], db); err == nil {
+		t.Fatal("stale journal layers restored")
+	}
+	if err := checkState(db, tester.roots[head], tester.states[head]); err != nil {
+		t.Fatalf("head state: %v", err)
+	}
+}
+
+func TestPathSchemeForks(t *testing.T) {
+	tester := newPathTester(4, 0)
+	if err := tester.extend(10); err != nil {
+		t.Fatalf("failed to create states: %v", err)
+	}
+	// Create a side chain forking off state 5
+	var (
+		sideRoots  []common.Hash
+		sideStates []testState
+		parent     = 5
+	)
+	for i := 0; i < 3; i++ {
+		state, wiped := tester.mutate(tester.states[parent])
+		root, err := tester.commit(parent, state, wiped)
+		if err != nil {
+			t.Fatalf("failed to create side state: %v", err)
+		}
+		tester.roots = append(tester.roots, root)
+		tester.states = append(tester.states, state)
+		sideRoots, sideStates = append(sideRoots, root), append(sideStates, state)
+		parent = len(tester.roots) - 1
+	}
+	for i, root := range sideRoots {
+		if err := checkState(tester.db, root, sideStates[i]); err != nil {
+			t.Fatalf("side state %d: %v", i, err)
+		}
+	}
+	// Persisting the main chain beyond the fork point discards the side chain
+	if err := tester.db.Commit(tester.roots[7], false, nil); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	for _, root := range sideRoots {
+		if _, err := New(common.Hash{}, root, tester.db); err == nil {
+			t.Fatal("side state available after persisting the main chain")
+		}
+	}
+	for i := 7; i <= 10; i++ {
+		if err := checkState(tester.db, tester.roots[i], tester.states[i]); err != nil {
+			t.Fatalf("state %d: %v", i, err)
+		}
+	}
+}